@@ -1,5 +1,5 @@
 module github.com/Data-Corruption/lmdb-go
 
-go 1.17
+go 1.18
 
 require golang.org/x/net v0.0.0-20210415231046-e915ea6b2b7d
@@ -0,0 +1,92 @@
+package lmdb
+
+// batchOpKind is the kind of a batchOp.
+type batchOpKind uint8
+
+const (
+	batchPut batchOpKind = iota
+	batchPutNX
+	batchDel
+)
+
+// batchOp is one operation recorded by a Batch.
+type batchOp struct {
+	kind     batchOpKind
+	dbi      DBI
+	key, val []byte
+}
+
+// Batch accumulates write operations and applies them in a single write
+// transaction with Execute.  The zero value is an empty Batch ready to use.
+//
+// A Batch retains the slices passed to its methods, which must not be modified
+// until Execute returns.  A Batch is not safe for concurrent use.
+type Batch struct {
+	ops []batchOp
+}
+
+// Put records storing val at key in dbi, overwriting any existing value.
+func (b *Batch) Put(dbi DBI, key, val []byte) {
+	b.ops = append(b.ops, batchOp{batchPut, dbi, key, val})
+}
+
+// PutNX records storing val at key in dbi unless key already exists, in which
+// case the operation is skipped.
+func (b *Batch) PutNX(dbi DBI, key, val []byte) {
+	b.ops = append(b.ops, batchOp{batchPutNX, dbi, key, val})
+}
+
+// Del records deleting key from dbi.  In a DupSort database a non-nil val
+// deletes only that value of key, see Txn.Del.
+func (b *Batch) Del(dbi DBI, key, val []byte) {
+	b.ops = append(b.ops, batchOp{batchDel, dbi, key, val})
+}
+
+// Len returns the number of operations recorded in b.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Reset removes all operations from b, retaining its storage.
+func (b *Batch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// Execute applies the operations of b in order within one call to env.Update.
+// The transaction runs in a new goroutine locked to its thread, so Execute may
+// be called from any goroutine whether or not it is locked to its thread.  Like
+// any write it blocks while another write transaction is open and must not be
+// called from within an Update on the same Env.
+//
+// If an operation fails the transaction is aborted and its error returned,
+// none of the operations are applied.  Deleting a key that does not exist
+// fails with NotFound.  Execute does not modify b and a Batch may be executed
+// more than once.
+func (b *Batch) Execute(env *Env) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- env.Update(b.apply)
+	}()
+	return <-errc
+}
+
+func (b *Batch) apply(txn *Txn) error {
+	for _, op := range b.ops {
+		var err error
+		switch op.kind {
+		case batchPut:
+			err = txn.Put(op.dbi, op.key, op.val, 0)
+		case batchPutNX:
+			err = txn.Put(op.dbi, op.key, op.val, NoOverwrite)
+			if IsErrno(err, KeyExist) {
+				err = nil
+			}
+		case batchDel:
+			err = txn.Del(op.dbi, op.key, op.val)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package lmdb
+
+import "testing"
+
+func TestBatch(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	db, err := openRoot(env, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *Txn) error {
+		return txn.Put(db, []byte("old"), []byte("1"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b Batch
+	b.Put(db, []byte("a"), []byte("2"))
+	b.PutNX(db, []byte("old"), []byte("ignored"))
+	b.PutNX(db, []byte("b"), []byte("3"))
+	b.Put(db, []byte("c"), []byte("4"))
+	b.Del(db, []byte("c"), nil)
+	if b.Len() != 5 {
+		t.Errorf("len: %d", b.Len())
+	}
+	if err := b.Execute(env); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"old": "1", "a": "2", "b": "3"}
+	err = env.View(func(txn *Txn) error {
+		for k, v := range want {
+			got, err := txn.Get(db, []byte(k))
+			if err != nil {
+				return err
+			}
+			if string(got) != v {
+				t.Errorf("%s: %q (!= %q)", k, got, v)
+			}
+		}
+		_, err := txn.Get(db, []byte("c"))
+		if !IsNotFound(err) {
+			t.Errorf("deleted key: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// a failing operation aborts the whole batch
+	b.Reset()
+	b.Put(db, []byte("d"), []byte("5"))
+	b.Del(db, []byte("missing"), nil)
+	if err := b.Execute(env); !IsNotFound(err) {
+		t.Errorf("execute: %v", err)
+	}
+	err = env.View(func(txn *Txn) error {
+		_, err := txn.Get(db, []byte("d"))
+		return err
+	})
+	if !IsNotFound(err) {
+		t.Errorf("aborted put: %v", err)
+	}
+}
@@ -271,6 +271,123 @@ func BenchmarkTxn_Get_raw_ro(b *testing.B) {
 	}
 }
 
+// BenchmarkTxn_Get_1000 reads 1000 keys with individual calls to Get inside
+// one View, for comparison with BenchmarkTxn_BulkGet_1000.
+func BenchmarkTxn_Get_1000(b *testing.B) {
+	benchmarkTxnGet1000(b, func(txn *Txn, dbi DBI, keys [][]byte) error {
+		for _, k := range keys {
+			_, err := txn.Get(dbi, k)
+			if err != nil && !IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BenchmarkTxn_BulkGet_1000 reads the keys of BenchmarkTxn_Get_1000 with one
+// call to BulkGet.
+func BenchmarkTxn_BulkGet_1000(b *testing.B) {
+	benchmarkTxnGet1000(b, func(txn *Txn, dbi DBI, keys [][]byte) error {
+		_, err := txn.BulkGet(dbi, keys)
+		return err
+	})
+}
+
+func BenchmarkTxn_GetUnsafe_1000(b *testing.B) {
+	benchmarkTxnGet1000(b, func(txn *Txn, dbi DBI, keys [][]byte) error {
+		for _, k := range keys {
+			if _, err := txn.GetUnsafe(dbi, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func benchmarkTxnGet1000(b *testing.B, get func(txn *Txn, dbi DBI, keys [][]byte) error) {
+	initRandSource(b)
+	env := setup(b)
+	defer clean(env, b)
+
+	dbi := openBenchDBI(b, env)
+
+	rc := newRandSourceCursor()
+	ps, err := populateBenchmarkDB(env, dbi, &rc)
+	if err != nil {
+		b.Errorf("populate db: %v", err)
+		return
+	}
+	keys := make([][]byte, 1000)
+	for i := range keys {
+		// ps alternates keys and values
+		keys[i] = ps[2*rand.Intn(len(ps)/2)]
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = env.View(func(txn *Txn) error {
+			return get(txn, dbi, keys)
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEnv_Update_1000 writes 1000 keys with one Update each, for
+// comparison with BenchmarkBatch_Execute_1000.
+func BenchmarkEnv_Update_1000(b *testing.B) {
+	benchmarkPut1000(b, func(env *Env, dbi DBI, ps [][]byte) error {
+		for i := 0; i < len(ps); i += 2 {
+			err := env.Update(func(txn *Txn) error {
+				return txn.Put(dbi, ps[i], ps[i+1], 0)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BenchmarkBatch_Execute_1000 writes the keys of BenchmarkEnv_Update_1000 with
+// one Batch.
+func BenchmarkBatch_Execute_1000(b *testing.B) {
+	benchmarkPut1000(b, func(env *Env, dbi DBI, ps [][]byte) error {
+		var batch Batch
+		for i := 0; i < len(ps); i += 2 {
+			batch.Put(dbi, ps[i], ps[i+1])
+		}
+		return batch.Execute(env)
+	})
+}
+
+func benchmarkPut1000(b *testing.B, put func(env *Env, dbi DBI, ps [][]byte) error) {
+	initRandSource(b)
+	env := setup(b)
+	defer clean(env, b)
+
+	dbi := openBenchDBI(b, env)
+	bMust(b, env.SetMapSize(benchDBMapSize), "setting map size")
+
+	rc := newRandSourceCursor()
+	// ps alternates keys and values
+	ps := make([][]byte, 0, 2000)
+	for i := 0; i < 1000; i++ {
+		ps = append(ps, makeBenchDBKey(&rc), makeBenchDBVal(&rc))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := put(env, dbi, ps); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkGet_1_alloc_rw_copy(b *testing.B) {
 	env := setup(b)
 	defer clean(env, b)
@@ -1747,3 +1864,64 @@ type testRecordBytes [2][]byte
 
 func (r testRecordBytes) Key() []byte  { return r[0] }
 func (r testRecordBytes) Data() []byte { return r[1] }
+
+func benchmarkCursorIterate(b *testing.B, iterate func(cur *Cursor) (int, error)) {
+	env := setup(b)
+	defer clean(env, b)
+
+	dbi := openBenchDBI(b, env)
+	records := testRecordSetSized(benchmarkScanDBSize)
+	if !populateDBI(b, env, dbi, records) {
+		return
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := env.View(func(txn *Txn) (err error) {
+			txn.RawRead = true
+			cur, err := txn.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+
+			n, err := iterate(cur)
+			if err == nil && n != records.Len() {
+				b.Errorf("iterated %d items (!= %d)", n, records.Len())
+			}
+			return err
+		})
+		if err != nil {
+			b.Error(err)
+			return
+		}
+	}
+}
+
+func BenchmarkCursor_Range(b *testing.B) {
+	benchmarkCursorIterate(b, func(cur *Cursor) (int, error) {
+		n := 0
+		next := cur.Range(nil, nil)
+		for _, _, ok := next(); ok; _, _, ok = next() {
+			n++
+		}
+		return n, nil
+	})
+}
+
+func BenchmarkCursor_Get_Next(b *testing.B) {
+	benchmarkCursorIterate(b, func(cur *Cursor) (int, error) {
+		n := 0
+		for {
+			_, _, err := cur.Get(nil, nil, Next)
+			if IsNotFound(err) {
+				return n, nil
+			}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+	})
+}
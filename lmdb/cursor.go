@@ -8,7 +8,9 @@ package lmdb
 */
 import "C"
 import (
+	"bytes"
 	"runtime"
+	"syscall"
 	"unsafe"
 )
 
@@ -183,6 +185,52 @@ func (c *Cursor) Get(setkey, setval []byte, op uint) (key, val []byte, err error
 	return key, val, nil
 }
 
+// Prev moves the cursor to the previous item, or to the last item of an
+// unpositioned cursor, and returns it. At the beginning of the database the
+// returned error satisfies IsNotFound.  Prev is equivalent to calling Get with
+// the Prev op.
+func (c *Cursor) Prev() (key, val []byte, err error) {
+	return c.Get(nil, nil, Prev)
+}
+
+// PrevDup moves the cursor to the previous value of the current key in a
+// DupSort database and returns it. At the first value of the key the returned
+// error satisfies IsNotFound.  PrevDup is equivalent to calling Get with the
+// PrevDup op.
+func (c *Cursor) PrevDup() (key, val []byte, err error) {
+	return c.Get(nil, nil, PrevDup)
+}
+
+// NextNoDup moves the cursor to the first value of the next key, or to the
+// first item of an unpositioned cursor, and returns it. In a DupSort database
+// the remaining values of the current key are skipped, so NextNoDup visits
+// each key once.  At the end of the database the returned error satisfies
+// IsNotFound.  NextNoDup is equivalent to calling Get with the NextNoDup op.
+func (c *Cursor) NextNoDup() (key, val []byte, err error) {
+	return c.Get(nil, nil, NextNoDup)
+}
+
+// PrevNoDup moves the cursor to the last value of the previous key, or to the
+// last item of an unpositioned cursor, and returns it.  At the beginning of
+// the database the returned error satisfies IsNotFound.  PrevNoDup is
+// equivalent to calling Get with the PrevNoDup op.
+func (c *Cursor) PrevNoDup() (key, val []byte, err error) {
+	return c.Get(nil, nil, PrevNoDup)
+}
+
+// GetCurrent returns the item at the cursor position without moving the
+// cursor, for example to read where a SetRange landed.  If the cursor is not
+// positioned, because it was just opened or its last positioning failed, the
+// returned error satisfies IsNotFound, where LMDB itself fails with EINVAL.
+// GetCurrent is equivalent to calling Get with the GetCurrent op.
+func (c *Cursor) GetCurrent() (key, val []byte, err error) {
+	key, val, err = c.Get(nil, nil, GetCurrent)
+	if IsErrnoSys(err, syscall.EINVAL) {
+		return nil, nil, &OpError{Op: "mdb_cursor_get", Errno: NotFound}
+	}
+	return key, val, err
+}
+
 // getVal0 retrieves items from the database without using given key or value
 // data for reference (Next, First, Last, etc).
 //
@@ -247,6 +295,31 @@ func (c *Cursor) Put(key, val []byte, flags uint) error {
 	return operrno("mdb_cursor_put", ret)
 }
 
+// PutCurrent replaces the value of the item at the cursor position, which
+// stays where it is, so values can be rewritten while iterating.  PutCurrent
+// is Put with the Current flag and the key of the current item, which LMDB
+// requires be passed again.  On an unpositioned cursor it fails with EINVAL.
+//
+// In a DupSort database val must sort into the same place among the values
+// of the key as the value it replaces, and in a DupFixed database it must
+// also have the same size.  A value of a different size in other databases
+// is stored by deleting the old item and inserting the new one.
+//
+// See mdb_cursor_put and MDB_CURRENT.
+func (c *Cursor) PutCurrent(val []byte) error {
+	err := c.getVal0(GetCurrent)
+	if err != nil {
+		*c.txn.key = C.MDB_val{}
+		*c.txn.val = C.MDB_val{}
+		return err
+	}
+	// a copy, the page holding the key may be rewritten by the put
+	key := getBytesCopy(c.txn.key)
+	*c.txn.key = C.MDB_val{}
+	*c.txn.val = C.MDB_val{}
+	return c.Put(key, val, Current)
+}
+
 // PutReserve returns a []byte of length n that can be written to, potentially
 // avoiding a memcopy.  The returned byte slice is only valid in txn's thread,
 // before it has terminated.
@@ -274,7 +347,8 @@ func (c *Cursor) PutReserve(key []byte, n int, flags uint) ([]byte, error) {
 
 // PutMulti stores a set of contiguous items with stride size under key.
 // PutMulti panics if len(page) is not a multiple of stride.  The cursor's
-// database must be DupFixed and DupSort.
+// database must be DupFixed and DupSort.  Storing many values in one PutMulti
+// call is much faster than storing them individually with Put.
 //
 // See mdb_cursor_put.
 func (c *Cursor) PutMulti(key []byte, page []byte, stride int, flags uint) error {
@@ -314,3 +388,119 @@ func (c *Cursor) Count() (uint64, error) {
 	}
 	return uint64(_size), nil
 }
+
+// GetAll returns copies of every value stored under key, in the sort order of
+// the database, and leaves the cursor at the last of them.  The values are
+// the duplicates of key in a DupSort database and the single value of key
+// otherwise.  If key does not exist GetAll returns an empty, non-nil slice.
+func (c *Cursor) GetAll(key []byte) ([][]byte, error) {
+	var err error
+	if len(key) == 0 {
+		err = c.getVal0(Set)
+	} else {
+		err = c.getVal1(key, Set)
+	}
+	vals := [][]byte{}
+	for err == nil {
+		vals = append(vals, getBytesCopy(c.txn.val))
+		err = c.getVal0(NextDup)
+	}
+	*c.txn.key = C.MDB_val{}
+	*c.txn.val = C.MDB_val{}
+	if !IsNotFound(err) {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// Range returns a pull iterator over the items of the cursor's database with
+// keys in the half-open interval [start, end).  An empty start begins at the
+// first item and an empty end continues through the last one.  Keys are
+// compared to end byte-wise, which does not match the order of databases
+// opened with ReverseKey or IntegerKey, so such databases should be iterated
+// with an empty end.
+//
+// Each call of the returned function moves the cursor to the next item and
+// returns it with ok set, until the range is exhausted or an error occurs,
+// after which ok is false.  In a DupSort database every duplicate is visited.
+//
+//	next := cur.Range(start, end)
+//	for k, v, ok := next(); ok; k, v, ok = next() {
+//		process(k, v)
+//	}
+//
+// The iterator does not allocate.  Regardless of c.Txn().RawRead the returned
+// slices reference readonly memory owned by LMDB, which is invalidated by the
+// next call of the iterator, any other use of the cursor, and the end of the
+// transaction.
+func (c *Cursor) Range(start, end []byte) func() (key, val []byte, ok bool) {
+	started, done := false, false
+	return func() (key, val []byte, ok bool) {
+		if done {
+			return nil, nil, false
+		}
+		var err error
+		switch {
+		case started:
+			err = c.getVal0(Next)
+		case len(start) == 0:
+			err = c.getVal0(First)
+		default:
+			err = c.getVal1(start, SetRange)
+		}
+		started = true
+		if err == nil {
+			key, val = getBytes(c.txn.key), getBytes(c.txn.val)
+		}
+		*c.txn.key = C.MDB_val{}
+		*c.txn.val = C.MDB_val{}
+		if err != nil || (len(end) > 0 && bytes.Compare(key, end) >= 0) {
+			done = true
+			return nil, nil, false
+		}
+		return key, val, true
+	}
+}
+
+// DeleteRange deletes every item with a key in the half-open interval
+// [start, end) and returns the number of items deleted.  An empty start begins
+// at the first item and an empty end continues through the last one.  Keys are
+// compared to end byte-wise, as in Range.  A range with start >= end deletes
+// nothing.  In a DupSort database every duplicate of a matching key is
+// deleted and counted.
+//
+// Deleting through the cursor avoids the tree search a separate Txn.Del call
+// performs for each key.  On error the items deleted so far remain deleted in
+// the transaction.
+//
+// See mdb_cursor_del.
+func (c *Cursor) DeleteRange(start, end []byte) (int, error) {
+	if len(start) > 0 && len(end) > 0 && bytes.Compare(start, end) >= 0 {
+		return 0, nil
+	}
+	var err error
+	if len(start) == 0 {
+		err = c.getVal0(First)
+	} else {
+		err = c.getVal1(start, SetRange)
+	}
+	n := 0
+	for err == nil {
+		if len(end) > 0 && bytes.Compare(getBytes(c.txn.key), end) >= 0 {
+			break
+		}
+		if err = c.Del(0); err != nil {
+			break
+		}
+		n++
+		// after mdb_cursor_del, MDB_NEXT returns the item that followed
+		// the deleted one
+		err = c.getVal0(Next)
+	}
+	*c.txn.key = C.MDB_val{}
+	*c.txn.val = C.MDB_val{}
+	if IsNotFound(err) {
+		err = nil
+	}
+	return n, err
+}
@@ -2,10 +2,14 @@ package lmdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"os"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
+	"syscall"
 	"testing"
 )
 
@@ -107,6 +111,44 @@ func TestCursor_Close(t *testing.T) {
 	}
 }
 
+func TestCursor_finalizer(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	db, err := openRoot(env, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, err := env.BeginTxn(nil, Readonly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txn.Abort()
+
+	// unreachable cursors are closed by their finalizer, explicitly closed
+	// ones must not be closed again
+	for i := 0; i < 100; i++ {
+		cur, err := txn.OpenCursor(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 0 {
+			cur.Close()
+		}
+	}
+	runtime.GC()
+	runtime.GC()
+
+	cur, err := txn.OpenCursor(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+	if _, _, err := cur.Get(nil, nil, First); !IsNotFound(err) {
+		t.Errorf("get: %v", err)
+	}
+}
+
 func TestCursor_bytesBuffer(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -479,6 +521,69 @@ func TestCursor_Get_reverse(t *testing.T) {
 	}
 }
 
+func TestCursor_Prev(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err := txn.OpenDBI("testdb", Create|DupSort)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 10; i++ {
+			if err = txn.Put(dbi, []byte(fmt.Sprintf("key%d", i)), []byte("val"), 0); err != nil {
+				return err
+			}
+		}
+		for _, v := range []string{"a", "b", "c"} {
+			if err = txn.Put(dbi, []byte("key9"), []byte(v), 0); err != nil {
+				return err
+			}
+		}
+
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		k, v, err := cur.Get(nil, nil, Last)
+		if err != nil {
+			return err
+		}
+		var vals []string
+		for ; err == nil; k, v, err = cur.PrevDup() {
+			if string(k) != "key9" {
+				t.Errorf("PrevDup key: %q", k)
+			}
+			vals = append(vals, string(v))
+		}
+		if !IsNotFound(err) {
+			return err
+		}
+		if got := strings.Join(vals, ","); got != "val,c,b,a" {
+			t.Errorf("PrevDup values: %s", got)
+		}
+
+		var keys []string
+		for k, _, err = cur.Get(nil, nil, Last); err == nil; k, _, err = cur.Prev() {
+			if len(keys) == 0 || keys[len(keys)-1] != string(k) {
+				keys = append(keys, string(k))
+			}
+		}
+		if !IsNotFound(err) {
+			return err
+		}
+		if !sort.IsSorted(sort.Reverse(sort.StringSlice(keys))) || len(keys) != 10 {
+			t.Errorf("Prev keys: %q", keys)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 func TestCursor_PutMulti(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -546,6 +651,63 @@ func TestCursor_PutMulti(t *testing.T) {
 	}
 }
 
+func TestCursor_PutMulti_many(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	const n = 1000
+	const stride = 8
+	page := make([]byte, n*stride)
+	for i := 0; i < n; i++ {
+		binary.BigEndian.PutUint64(page[i*stride:], uint64(n-i))
+	}
+
+	var dbi DBI
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err = txn.OpenDBI("multi", Create|DupSort|DupFixed)
+		if err != nil {
+			return err
+		}
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		return cur.PutMulti([]byte("k"), page, stride, 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.View(func(txn *Txn) (err error) {
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		count, i := 0, uint64(1)
+		_, v, err := cur.Get([]byte("k"), nil, Set)
+		for ; err == nil; _, v, err = cur.Get(nil, nil, NextDup) {
+			if got := binary.BigEndian.Uint64(v); got != i {
+				return fmt.Errorf("value %d: %d (!= %d)", count, got, i)
+			}
+			count++
+			i++
+		}
+		if !IsNotFound(err) {
+			return err
+		}
+		if count != n {
+			return fmt.Errorf("read %d values (!= %d)", count, n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 func TestCursor_Put(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -973,3 +1135,449 @@ func BenchmarkCursor_Renew(b *testing.B) {
 		return nil
 	})
 }
+
+func TestCursor_GetAll(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err := txn.OpenDBI("dup", Create|DupSort)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 50; i++ {
+			err = txn.Put(dbi, []byte("key"), []byte(fmt.Sprintf("val%02d", i)), 0)
+			if err != nil {
+				return err
+			}
+		}
+		if err = txn.Put(dbi, []byte("other"), []byte("x"), 0); err != nil {
+			return err
+		}
+		plain, err := txn.OpenDBI("plain", Create)
+		if err != nil {
+			return err
+		}
+		if err = txn.Put(plain, []byte("key"), []byte("single"), 0); err != nil {
+			return err
+		}
+
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		vals, err := cur.GetAll([]byte("key"))
+		if err != nil {
+			return err
+		}
+		if len(vals) != 50 {
+			t.Errorf("values: %d (!= 50)", len(vals))
+		}
+		for i, v := range vals {
+			if want := fmt.Sprintf("val%02d", i); string(v) != want {
+				t.Errorf("value %d: %q (!= %q)", i, v, want)
+			}
+		}
+		vals, err = cur.GetAll([]byte("missing"))
+		if err != nil || vals == nil || len(vals) != 0 {
+			t.Errorf("missing key: %q %v", vals, err)
+		}
+		if _, err = cur.GetAll(nil); err == nil {
+			t.Errorf("empty key: no error")
+		}
+
+		pcur, err := txn.OpenCursor(plain)
+		if err != nil {
+			return err
+		}
+		defer pcur.Close()
+		vals, err = pcur.GetAll([]byte("key"))
+		if err != nil || len(vals) != 1 || string(vals[0]) != "single" {
+			t.Errorf("non-dup database: %q %v", vals, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCursor_Range(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	dbi, err := openDBI(env, "testdb", Create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"a", "b", "c", "d", "e"}
+	err = env.Update(func(txn *Txn) error {
+		for _, k := range keys {
+			if err := txn.Put(dbi, []byte(k), []byte("v"+k), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		start, end string
+		want       []string
+	}{
+		{"", "", keys},
+		{"b", "d", []string{"b", "c"}},
+		{"bb", "", []string{"c", "d", "e"}},
+		{"", "b", []string{"a"}},
+		{"c", "c", nil},
+		{"d", "b", nil},
+		{"f", "", nil},
+	} {
+		err = env.View(func(txn *Txn) error {
+			cur, err := txn.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+			var got []string
+			next := cur.Range([]byte(test.start), []byte(test.end))
+			for k, v, ok := next(); ok; k, v, ok = next() {
+				if string(v) != "v"+string(k) {
+					t.Errorf("value of %q: %q", k, v)
+				}
+				got = append(got, string(k))
+			}
+			if _, _, ok := next(); ok {
+				t.Errorf("iterator resumed after exhaustion")
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("range [%q, %q): %q (!= %q)", test.start, test.end, got, test.want)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCursor_DeleteRange(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	dbi, err := openDBI(env, "testdb", Create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(i int) []byte {
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, uint64(i))
+		return k
+	}
+	const n = 10000
+	err = env.Update(func(txn *Txn) error {
+		for i := 0; i < n; i++ {
+			if err := txn.Put(dbi, key(i), []byte("v"), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.Update(func(txn *Txn) error {
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		// empty ranges
+		for _, r := range [][2]int{{10, 10}, {20, 10}} {
+			deleted, err := cur.DeleteRange(key(r[0]), key(r[1]))
+			if err != nil || deleted != 0 {
+				t.Errorf("empty range %v: %d %v", r, deleted, err)
+			}
+		}
+
+		deleted, err := cur.DeleteRange(key(2500), key(7500))
+		if err != nil {
+			return err
+		}
+		if deleted != 5000 {
+			t.Errorf("deleted: %d (!= 5000)", deleted)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.View(func(txn *Txn) error {
+		stat, err := txn.Stat(dbi)
+		if err != nil {
+			return err
+		}
+		if stat.Entries != 5000 {
+			t.Errorf("remaining: %d (!= 5000)", stat.Entries)
+		}
+		for _, i := range []int{2499, 7500} {
+			if _, err := txn.Get(dbi, key(i)); err != nil {
+				t.Errorf("boundary key %d: %v", i, err)
+			}
+		}
+		for _, i := range []int{2500, 5000, 7499} {
+			if _, err := txn.Get(dbi, key(i)); !IsNotFound(err) {
+				t.Errorf("deleted key %d: %v", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an unbounded range deletes everything that is left
+	err = env.Update(func(txn *Txn) error {
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		deleted, err := cur.DeleteRange(nil, nil)
+		if deleted != 5000 {
+			t.Errorf("deleted: %d (!= 5000)", deleted)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCursor_DeleteRange_dupSort(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	dbi, err := openDBI(env, "testdb", Create|DupSort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *Txn) error {
+		for _, k := range []string{"a", "b", "c"} {
+			for _, v := range []string{"1", "2", "3"} {
+				if err := txn.Put(dbi, []byte(k), []byte(v), 0); err != nil {
+					return err
+				}
+			}
+		}
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		deleted, err := cur.DeleteRange([]byte("b"), []byte("c"))
+		if deleted != 3 {
+			t.Errorf("deleted: %d (!= 3)", deleted)
+		}
+		if err != nil {
+			return err
+		}
+		stat, err := txn.Stat(dbi)
+		if err != nil {
+			return err
+		}
+		if stat.Entries != 6 {
+			t.Errorf("remaining: %d (!= 6)", stat.Entries)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCursor_GetCurrent(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err := txn.OpenDBI("testdb", Create)
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"a", "c", "e"} {
+			if err = txn.Put(dbi, []byte(k), []byte("v"+k), 0); err != nil {
+				return err
+			}
+		}
+
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		if _, _, err := cur.GetCurrent(); !IsNotFound(err) {
+			t.Errorf("unpositioned cursor: %v", err)
+		}
+		for _, tc := range []struct{ seek, want string }{{"b", "c"}, {"c", "c"}, {"d", "e"}} {
+			if _, _, err := cur.Get([]byte(tc.seek), nil, SetRange); err != nil {
+				return err
+			}
+			k, v, err := cur.GetCurrent()
+			if err != nil {
+				return err
+			}
+			if string(k) != tc.want || string(v) != "v"+tc.want {
+				t.Errorf("seek %q: landed on %q=%q (!= %q)", tc.seek, k, v, tc.want)
+			}
+			// the cursor did not move
+			if k, _, err := cur.Get(nil, nil, Next); tc.want == "c" && (err != nil || string(k) != "e") {
+				t.Errorf("next after %q: %q %v", tc.want, k, err)
+			}
+		}
+		if _, _, err := cur.Get([]byte("f"), nil, SetRange); !IsNotFound(err) {
+			t.Errorf("seek past the end: %v", err)
+		}
+		if _, _, err := cur.GetCurrent(); !IsNotFound(err) {
+			t.Errorf("after failed seek: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCursor_PutCurrent(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	var dbi DBI
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err = txn.OpenDBI("testdb", Create)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 100; i++ {
+			if err = txn.Put(dbi, Uint64Key(uint64(i)), Uint64Key(uint64(i)), 0); err != nil {
+				return err
+			}
+		}
+
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		if err := cur.PutCurrent([]byte("x")); !IsErrnoSys(err, syscall.EINVAL) {
+			t.Errorf("unpositioned cursor: %v", err)
+		}
+		n := 0
+		_, v, err := cur.Get(nil, nil, First)
+		for ; err == nil; _, v, err = cur.Get(nil, nil, Next) {
+			if err := cur.PutCurrent(Uint64Key(2 * ParseUint64Key(v))); err != nil {
+				return err
+			}
+			n++
+		}
+		if !IsNotFound(err) {
+			return err
+		}
+		if n != 100 {
+			t.Errorf("visited %d items", n)
+		}
+
+		// a value of another size is stored too
+		if _, _, err := cur.Get(Uint64Key(7), nil, Set); err != nil {
+			return err
+		}
+		if err := cur.PutCurrent([]byte("longer than eight bytes")); err != nil {
+			return err
+		}
+		if k, v, err := cur.GetCurrent(); err != nil || ParseUint64Key(k) != 7 || string(v) != "longer than eight bytes" {
+			t.Errorf("resized value: %x %q %v", k, v, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.View(func(txn *Txn) error {
+		for i := 0; i < 100; i++ {
+			v, err := txn.Get(dbi, Uint64Key(uint64(i)))
+			if err != nil {
+				return err
+			}
+			if i != 7 && ParseUint64Key(v) != uint64(2*i) {
+				t.Errorf("%d: %d", i, ParseUint64Key(v))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCursor_NextNoDup(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err := txn.OpenDBI("testdb", Create|DupSort)
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"a", "b", "c"} {
+			for i := 0; i < 5; i++ {
+				if err = txn.Put(dbi, []byte(k), []byte(fmt.Sprint(i)), 0); err != nil {
+					return err
+				}
+			}
+		}
+		if stat, err := txn.Stat(dbi); err != nil || stat.Entries != 15 {
+			t.Errorf("entries: %v %v", stat, err)
+		}
+
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		var items []string
+		k, v, err := cur.NextNoDup()
+		for ; err == nil; k, v, err = cur.NextNoDup() {
+			items = append(items, string(k)+"="+string(v))
+		}
+		if !IsNotFound(err) {
+			return err
+		}
+		if got := strings.Join(items, ","); got != "a=0,b=0,c=0" {
+			t.Errorf("NextNoDup: %s", got)
+		}
+
+		items = nil
+		for k, v, err = cur.Get(nil, nil, Last); err == nil; k, v, err = cur.PrevNoDup() {
+			items = append(items, string(k)+"="+string(v))
+		}
+		if !IsNotFound(err) {
+			return err
+		}
+		if got := strings.Join(items, ","); got != "c=4,b=4,a=4" {
+			t.Errorf("PrevNoDup: %s", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -9,10 +9,14 @@ package lmdb
 import "C"
 
 import (
+	"bytes"
 	"errors"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -70,6 +74,14 @@ type Env struct {
 
 	ckey *C.MDB_val
 	cval *C.MDB_val
+
+	// rootMu guards the root DBI cached by OpenRoot.
+	rootMu   sync.Mutex
+	root     DBI
+	rootOpen bool
+
+	// maxDBs is the limit last set by SetMaxDBs, LMDB has no getter for it.
+	maxDBs int
 }
 
 // NewEnv allocates and initializes a new Env.
@@ -102,6 +114,10 @@ func (env *Env) Open(path string, flags uint, mode os.FileMode) error {
 var errNotOpen = errors.New("enivornment is not open")
 var errNegSize = errors.New("negative size")
 
+// ErrReadersActive is returned by Env.GrowMap when read transactions of the
+// process do not finish before the timeout.
+var ErrReadersActive = errors.New("read transactions still active")
+
 // FD returns the open file descriptor (or Windows file handle) for the given
 // environment.  An error is returned if the environment has not been
 // successfully Opened (where C API just retruns an invalid handle).
@@ -163,11 +179,11 @@ func (env *Env) ReaderCheck() (int, error) {
 }
 
 func (env *Env) close() bool {
+	env.closeLock.Lock()
 	if env._env == nil {
+		env.closeLock.Unlock()
 		return false
 	}
-
-	env.closeLock.Lock()
 	C.mdb_env_close(env._env)
 	env._env = nil
 	env.closeLock.Unlock()
@@ -180,15 +196,19 @@ func (env *Env) close() bool {
 }
 
 // Close shuts down the environment, releases the memory map, and clears the
-// finalizer on env.
+// finalizer on env.  Close is idempotent, closing a closed environment does
+// nothing and returns nil.
+//
+// mdb_env_close reports no errors, so Close currently always returns nil.  The
+// error result is kept for future checks.  All transactions must be
+// terminated before Close is called, LMDB does not check this.
 //
 // See mdb_env_close.
 func (env *Env) Close() error {
 	if env.close() {
 		runtime.SetFinalizer(env, nil)
-		return nil
 	}
-	return errors.New("environment is already closed")
+	return nil
 }
 
 // CopyFD copies env to the the file descriptor fd.
@@ -239,7 +259,8 @@ type Stat struct {
 	Entries       uint64 // Number of data items
 }
 
-// Stat returns statistics about the environment.
+// Stat returns statistics about the environment.  The B-tree statistics are
+// those of the unnamed root database, use Txn.Stat for a named database.
 //
 // See mdb_env_stat.
 func (env *Env) Stat() (*Stat, error) {
@@ -261,14 +282,16 @@ func (env *Env) Stat() (*Stat, error) {
 //
 // See MDB_envinfo.
 type EnvInfo struct {
-	MapSize    int64 // Size of the data memory map
-	LastPNO    int64 // ID of the last used page
-	LastTxnID  int64 // ID of the last committed transaction
-	MaxReaders uint  // maximum number of threads for the environment
-	NumReaders uint  // maximum number of threads used in the environment
+	MapAddr    uintptr // Address of the memory map if FixedMap is set, otherwise zero
+	MapSize    int64   // Size of the data memory map
+	LastPNO    int64   // ID of the last used page
+	LastTxnID  int64   // ID of the last committed transaction
+	MaxReaders uint    // maximum number of threads for the environment
+	NumReaders uint    // number of reader slots in use in the environment
 }
 
-// Info returns information about the environment.
+// Info returns information about the environment.  Info may be called
+// concurrently with transactions in other goroutines.
 //
 // See mdb_env_info.
 func (env *Env) Info() (*EnvInfo, error) {
@@ -278,6 +301,7 @@ func (env *Env) Info() (*EnvInfo, error) {
 		return nil, operrno("mdb_env_info", ret)
 	}
 	info := EnvInfo{
+		MapAddr:    uintptr(_info.me_mapaddr),
 		MapSize:    int64(_info.me_mapsize),
 		LastPNO:    int64(_info.me_last_pgno),
 		LastTxnID:  int64(_info.me_last_txnid),
@@ -288,7 +312,11 @@ func (env *Env) Info() (*EnvInfo, error) {
 }
 
 // Sync flushes buffers to disk.  If force is true a synchronous flush occurs
-// and ignores any NoSync or MapAsync flag on the environment.
+// and ignores any NoSync or MapAsync flag on the environment.  If force is
+// false and the environment has the NoSync flag set the flush is omitted and
+// Sync returns nil without writing anything, with MapAsync the flush is
+// asynchronous.  Applications using NoSync should call Sync(true)
+// periodically to bound the amount of data lost on a system crash.
 //
 // See mdb_env_sync.
 func (env *Env) Sync(force bool) error {
@@ -340,7 +368,15 @@ func (env *Env) Path() (string, error) {
 	return C.GoString(cpath), nil
 }
 
-// SetMapSize sets the size of the environment memory map.
+// SetMapSize sets the size of the environment memory map.  The size should be
+// a multiple of the OS page size.  Before Open a size of zero keeps the
+// default, or the size recorded in an existing environment if that is larger.
+//
+// SetMapSize may be called on an open environment, but only when no
+// transaction of the process is active.  A size of zero then adopts the size
+// last recorded in the environment, which another process may have grown,
+// see AdoptMapSize.  A nonzero size smaller than the data in use is raised to
+// fit it.
 //
 // See mdb_env_set_mapsize.
 func (env *Env) SetMapSize(size int64) error {
@@ -351,6 +387,89 @@ func (env *Env) SetMapSize(size int64) error {
 	return operrno("mdb_env_set_mapsize", ret)
 }
 
+// AdoptMapSize sets the size of the memory map of an open environment to the
+// size last recorded in the environment.  It is the recovery for a MapResized
+// error, which a transaction returns when another process grew the map beyond
+// the size of the map of env.  Like SetMapSize it must only be called when no
+// transaction of the process is active.
+//
+// See mdb_env_set_mapsize.
+func (env *Env) AdoptMapSize() error {
+	return env.SetMapSize(0)
+}
+
+// maxMapResizeRetries is the number of times RetryOnMapResize retries fn.
+const maxMapResizeRetries = 3
+
+// RetryOnMapResize calls fn, and if it fails with a MapResized error adopts
+// the new map size with AdoptMapSize and calls fn again, a few times at most
+// in case other processes keep growing the map.  It returns the last error
+// of fn, or the error of AdoptMapSize.
+//
+// fn would typically run one View or Update on env and must not leave a
+// transaction active when it returns, nor may any other goroutine of the
+// process have one active when the map size is adopted.
+func RetryOnMapResize(env *Env, fn func() error) error {
+	err := fn()
+	for i := 0; i < maxMapResizeRetries && IsMapResized(err); i++ {
+		if err := env.AdoptMapSize(); err != nil {
+			return err
+		}
+		err = fn()
+	}
+	return err
+}
+
+// GrowMap sets the size of the memory map of an open environment once no read
+// transaction of the process is active, waiting up to timeout for active
+// readers to finish by polling the reader table.  If readers remain after
+// timeout GrowMap returns ErrReadersActive without resizing.
+//
+// LMDB requires that no transaction of the process is active when the map is
+// resized.  GrowMap can only observe read transactions, so the caller must
+// ensure no write transaction is active and must prevent new transactions
+// from beginning until GrowMap returns.  Readers in other processes do not
+// block GrowMap.
+//
+// See mdb_env_set_mapsize.
+func (env *Env) GrowMap(newSize int64, timeout time.Duration) error {
+	const pollInterval = time.Millisecond
+	deadline := time.Now().Add(timeout)
+	for {
+		n, err := env.activeReaders()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return env.SetMapSize(newSize)
+		}
+		if !time.Now().Before(deadline) {
+			return ErrReadersActive
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// activeReaders returns the number of reader table slots held by read
+// transactions of the process.
+func (env *Env) activeReaders() (int, error) {
+	pid := os.Getpid()
+	n := 0
+	err := env.ReaderList(func(line string) error {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil
+		}
+		// the header line and other processes are skipped, as are slots
+		// retained by reset transactions, which have no txnid
+		if p, err := strconv.Atoi(fields[0]); err == nil && p == pid && fields[2] != "-" {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
 // SetMaxReaders sets the maximum number of reader slots in the environment.
 //
 // See mdb_env_set_maxreaders.
@@ -389,9 +508,23 @@ func (env *Env) SetMaxDBs(size int) error {
 		return errNegSize
 	}
 	ret := C.mdb_env_set_maxdbs(env._env, C.MDB_dbi(size))
+	if ret == success {
+		env.maxDBs = size
+	}
 	return operrno("mdb_env_set_maxdbs", ret)
 }
 
+// MaxDBs returns the maximum number of named databases set by SetMaxDBs, zero
+// if it was never called.  Opening more databases fails with DBsFull.  LMDB
+// offers no getter, the value is the one recorded by SetMaxDBs.  MaxDBs
+// returns an error once env is closed.
+func (env *Env) MaxDBs() (int, error) {
+	if env._env == nil {
+		return 0, errNotOpen
+	}
+	return env.maxDBs, nil
+}
+
 // BeginTxn is an unsafe, low-level method to initialize a new transaction on
 // env.  The Txn returned by BeginTxn is unmanaged and must be terminated by
 // calling either its Abort or Commit methods to ensure that its resources are
@@ -422,6 +555,22 @@ func (env *Env) BeginTxn(parent *Txn, flags uint) (*Txn, error) {
 	return txn, err
 }
 
+// BeginUpdate begins an unmanaged write transaction, it is shorthand for
+// BeginTxn(nil, 0).  The caller must call runtime.LockOSThread before
+// BeginUpdate and keep the goroutine locked until the Txn is committed or
+// aborted, see BeginTxn.  Update remains the preferred way to write.
+func (env *Env) BeginUpdate() (*Txn, error) {
+	return env.BeginTxn(nil, 0)
+}
+
+// BeginView begins an unmanaged readonly transaction, it is shorthand for
+// BeginTxn(nil, Readonly).  The Txn must be terminated by calling Abort or
+// Commit.  No thread locking is needed because Open always sets NoTLS.  View
+// remains the preferred way to read.
+func (env *Env) BeginView() (*Txn, error) {
+	return env.BeginTxn(nil, Readonly)
+}
+
 // RunTxn creates a new Txn and calls fn with it as an argument.  Run commits
 // the transaction if fn returns nil otherwise the transaction is aborted.
 // Because RunTxn terminates the transaction goroutines should not retain
@@ -437,6 +586,89 @@ func (env *Env) RunTxn(flags uint, fn TxnOp) error {
 	return env.run(false, flags, fn)
 }
 
+// OpenRoot opens the root database in its own transaction and caches the
+// handle, later calls return the cached handle without a transaction and
+// ignore flags.  A readonly transaction is used if flags is zero, otherwise a
+// write transaction is committed, so OpenRoot must not be called from within
+// an Update on the same goroutine.
+//
+// See Txn.OpenRoot.
+func (env *Env) OpenRoot(flags uint) (DBI, error) {
+	env.rootMu.Lock()
+	defer env.rootMu.Unlock()
+	if env.rootOpen {
+		return env.root, nil
+	}
+	op := func(txn *Txn) (err error) {
+		env.root, err = txn.OpenRoot(flags)
+		return err
+	}
+	var err error
+	if flags == 0 {
+		err = env.View(op)
+	} else {
+		err = env.Update(op)
+	}
+	if err != nil {
+		return 0, err
+	}
+	env.rootOpen = true
+	return env.root, nil
+}
+
+// IterFunc is called by Env.ForEachDBI with the name and handle of each named
+// database.  Returning an error stops the iteration.
+type IterFunc func(name string, dbi DBI) error
+
+// ErrNoNamedDBs is returned by Env.ForEachDBI when the root database does not
+// name any database.
+var ErrNoNamedDBs = errors.New("no named databases")
+
+// ForEachDBI calls fn with every named database of the environment, in key
+// order, opening each handle in txn.  Keys of the root database which do not
+// name a database are skipped, like lmdb_dump does.  The first error returned
+// by fn is returned.  Handles opened in txn are only valid after it ends if
+// it commits, see Txn.OpenDBI.
+func (env *Env) ForEachDBI(txn *Txn, fn IterFunc) error {
+	root, err := txn.OpenRoot(0)
+	if err != nil {
+		return err
+	}
+	cur, err := txn.OpenCursor(root)
+	if err != nil {
+		return err
+	}
+	defer cur.Close()
+
+	found := false
+	k, _, err := cur.Get(nil, nil, First)
+	for ; err == nil; k, _, err = cur.Get(nil, nil, Next) {
+		// names are C strings
+		if bytes.IndexByte(k, 0) >= 0 {
+			continue
+		}
+		name := string(k)
+		dbi, err := txn.OpenDBI(name, 0)
+		if IsErrno(err, Incompatible) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if err := fn(name, dbi); err != nil {
+			return err
+		}
+	}
+	if !IsNotFound(err) {
+		return err
+	}
+	if !found {
+		return ErrNoNamedDBs
+	}
+	return nil
+}
+
 // View creates a readonly transaction with a consistent view of the
 // environment and passes it to fn.  View terminates its transaction after fn
 // returns.  Any error encountered by View is returned.
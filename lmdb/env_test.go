@@ -6,9 +6,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"syscall"
 	"testing"
+	"time"
 )
 
 func TestEnv_Path_notOpen(t *testing.T) {
@@ -94,6 +96,24 @@ func TestEnv_Open(t *testing.T) {
 	}
 }
 
+func TestEnv_Close(t *testing.T) {
+	env := setup(t)
+	path, err := env.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	for i := 0; i < 2; i++ {
+		if err := env.Close(); err != nil {
+			t.Errorf("close %d: %v", i, err)
+		}
+	}
+	if _, err := env.Path(); err == nil {
+		t.Errorf("path of closed environment: no error")
+	}
+}
+
 func TestEnv_FD(t *testing.T) {
 	env, err := NewEnv()
 	if err != nil {
@@ -221,6 +241,58 @@ func TestEnv_SetMaxReader(t *testing.T) {
 	}
 }
 
+func TestEnv_MaxDBs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-env-maxdbs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	env, err := NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := env.MaxDBs(); err != nil || n != 0 {
+		t.Errorf("default MaxDBs: %d, %v", n, err)
+	}
+	if err := env.SetMaxDBs(3); err != nil {
+		t.Fatal(err)
+	}
+	err = env.Open(dir, 0, 0644)
+	if err != nil {
+		env.Close()
+		t.Fatal(err)
+	}
+
+	// a failed SetMaxDBs leaves the limit unchanged
+	if err := env.SetMaxDBs(10); !IsErrnoSys(err, syscall.EINVAL) {
+		t.Errorf("SetMaxDBs after open: %v", err)
+	}
+	if n, err := env.MaxDBs(); err != nil || n != 3 {
+		t.Errorf("MaxDBs: %d, %v", n, err)
+	}
+	err = env.Update(func(txn *Txn) error {
+		for i := 0; i < 3; i++ {
+			if _, err := txn.CreateDBI(fmt.Sprintf("db%d", i)); err != nil {
+				return err
+			}
+		}
+		_, err := txn.CreateDBI("db3")
+		if !IsErrno(err, DBsFull) {
+			t.Errorf("fourth database: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	env.Close()
+	if _, err := env.MaxDBs(); err == nil {
+		t.Errorf("MaxDBs on a closed environment")
+	}
+}
+
 func TestEnv_SetMapSize(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -246,6 +318,242 @@ func TestEnv_SetMapSize(t *testing.T) {
 	}
 }
 
+func TestEnv_SetMapSize_zero(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := info.MapSize
+
+	// zero keeps the recorded size of an open environment
+	if err := env.SetMapSize(0); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := env.Info(); info.MapSize != size {
+		t.Errorf("mapsize after zero: %v (!= %v)", info.MapSize, size)
+	}
+
+	// a write records the larger size, as another process growing the map
+	// would, and shrinking the map without a write leaves it recorded
+	if err := env.SetMapSize(2 * size); err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		return txn.Put(dbi, []byte("k"), []byte("v"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.SetMapSize(size); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := env.Info(); info.MapSize != size {
+		t.Errorf("mapsize after shrink: %v (!= %v)", info.MapSize, size)
+	}
+	if err := env.AdoptMapSize(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := env.Info(); info.MapSize != 2*size {
+		t.Errorf("adopted mapsize: %v (!= %v)", info.MapSize, 2*size)
+	}
+}
+
+func TestRetryOnMapResize(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	resized := &OpError{Op: "mdb_txn_begin", Errno: MapResized}
+	calls := 0
+	err := RetryOnMapResize(env, func() error {
+		if calls++; calls == 1 {
+			return resized
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("retry: %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = RetryOnMapResize(env, func() error {
+		calls++
+		return resized
+	})
+	if !IsMapResized(err) || calls != maxMapResizeRetries+1 {
+		t.Errorf("persistent resize: %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = RetryOnMapResize(env, func() error {
+		calls++
+		return &OpError{Op: "mdb_put", Errno: MapFull}
+	})
+	if !IsMapFull(err) || calls != 1 {
+		t.Errorf("other error: %v after %d calls", err, calls)
+	}
+}
+
+func TestEnv_Stat(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	stat, err := env.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// LMDB uses the OS page size for new environments
+	if stat.PSize != uint(os.Getpagesize()) {
+		t.Errorf("page size: %d (!= %d)", stat.PSize, os.Getpagesize())
+	}
+	if stat.Entries != 0 {
+		t.Errorf("entries of an empty environment: %d", stat.Entries)
+	}
+
+	err = env.Update(func(txn *Txn) (err error) {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		return txn.Put(dbi, []byte("k"), []byte("v"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err = env.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Entries != 1 || stat.Depth != 1 || stat.LeafPages != 1 {
+		t.Errorf("stat: %+v", stat)
+	}
+}
+
+func TestEnv_Info(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		return txn.Put(dbi, []byte("k"), []byte("v"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.LastTxnID <= 0 {
+		t.Errorf("last txn id: %d", info.LastTxnID)
+	}
+	if info.MapSize <= 0 || info.MaxReaders == 0 {
+		t.Errorf("info: %+v", info)
+	}
+	if info.MapAddr != 0 {
+		t.Errorf("map address without FixedMap: %#x", info.MapAddr)
+	}
+}
+
+func TestEnv_GrowMap(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSize := info.MapSize * 2
+
+	ready := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- env.View(func(txn *Txn) error {
+			close(ready)
+			<-release
+			return nil
+		})
+	}()
+	<-ready
+
+	// an active reader that outlives the timeout prevents the resize
+	if err := env.GrowMap(newSize, 20*time.Millisecond); err != ErrReadersActive {
+		t.Errorf("grow with active reader: %v", err)
+	}
+	if info, _ := env.Info(); info.MapSize == newSize {
+		t.Errorf("map resized while a reader was active")
+	}
+
+	// GrowMap waits for the reader to finish
+	const hold = 50 * time.Millisecond
+	time.AfterFunc(hold, func() { close(release) })
+	start := time.Now()
+	if err := env.GrowMap(newSize, 5*time.Second); err != nil {
+		t.Fatalf("grow: %v", err)
+	}
+	if d := time.Since(start); d < hold/2 {
+		t.Errorf("grow returned after %v, before the reader finished", d)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := env.Info(); info.MapSize != newSize {
+		t.Errorf("mapsize: %d (!= %d)", info.MapSize, newSize)
+	}
+}
+
+func TestEnv_OpenRoot(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	dbi, err := env.OpenRoot(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *Txn) error {
+		return txn.Put(dbi, []byte("k"), []byte("v"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.View(func(txn *Txn) error {
+		root, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		if root != dbi {
+			t.Errorf("dbi: %d (!= %d)", dbi, root)
+		}
+		v, err := txn.Get(dbi, []byte("k"))
+		if err == nil && string(v) != "v" {
+			t.Errorf("value: %q", v)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cached handle is returned without opening a transaction
+	if n := testing.AllocsPerRun(10, func() {
+		if again, err := env.OpenRoot(0); err != nil || again != dbi {
+			t.Fatalf("cached: %d %v", again, err)
+		}
+	}); n != 0 {
+		t.Errorf("cached OpenRoot allocated %v times", n)
+	}
+}
+
 func TestEnv_ReaderList(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -507,6 +815,54 @@ func TestEnv_Sync(t *testing.T) {
 	}
 }
 
+func TestEnv_Sync_noSync(t *testing.T) {
+	env := setupFlags(t, NoSync)
+	defer clean(env, t)
+
+	path, err := env.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	datafile := filepath.Join(path, "data.mdb")
+
+	// push the data file modification time into the past so the update is
+	// detectable regardless of filesystem timestamp granularity.
+	past := time.Now().Add(-time.Hour)
+	err = os.Chtimes(datafile, past, past)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.Update(func(txn *Txn) (err error) {
+		db, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		return txn.Put(db, []byte("k0"), []byte("v0"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a non-forced sync is a no-op on a NoSync environment but must not fail.
+	err = env.Sync(false)
+	if err != nil {
+		t.Errorf("sync(false): %v", err)
+	}
+	err = env.Sync(true)
+	if err != nil {
+		t.Errorf("sync(true): %v", err)
+	}
+
+	info, err := os.Stat(datafile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().After(past) {
+		t.Errorf("data file modification time not updated: %v", info.ModTime())
+	}
+}
+
 func setup(t T) *Env {
 	return setupFlags(t, 0)
 }
@@ -582,6 +938,65 @@ func TestEnv_MaxKeySize_nil(t *testing.T) {
 	t.Logf("mdb_env_get_maxkeysize: %d", n)
 }
 
+func TestEnv_BeginUpdate(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	db, err := openRoot(env, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	txn, err := env.BeginUpdate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = txn.Put(db, []byte("k"), []byte("v"), 0); err != nil {
+		txn.Abort()
+		t.Fatal(err)
+	}
+	view, err := env.BeginView()
+	if err != nil {
+		txn.Abort()
+		t.Fatal(err)
+	}
+	if _, err := view.Get(db, []byte("k")); !IsNotFound(err) {
+		t.Errorf("uncommitted write visible: %v", err)
+	}
+	view.Abort()
+	if err = txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	view, err = env.BeginView()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Abort()
+	if v, err := view.Get(db, []byte("k")); err != nil || string(v) != "v" {
+		t.Errorf("committed write: %q %v", v, err)
+	}
+
+	txn, err = env.BeginUpdate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = txn.Put(db, []byte("k2"), []byte("v"), 0); err != nil {
+		t.Error(err)
+	}
+	txn.Abort()
+	err = env.View(func(txn *Txn) error {
+		_, err := txn.Get(db, []byte("k2"))
+		return err
+	})
+	if !IsNotFound(err) {
+		t.Errorf("aborted write: %v", err)
+	}
+}
+
 func TestEnv_CloseDBI(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -612,3 +1027,54 @@ func TestEnv_CloseDBI(t *testing.T) {
 		t.Errorf("unexpected entries: %d (not %d)", stat.Entries, numdb)
 	}
 }
+
+func TestEnv_ForEachDBI(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	want := []string{"a", "b", "c", "d", "e"}
+	err := env.Update(func(txn *Txn) error {
+		if err := env.ForEachDBI(txn, func(string, DBI) error { return nil }); err != ErrNoNamedDBs {
+			t.Errorf("empty environment: %v", err)
+		}
+		for _, name := range want {
+			if _, err := txn.CreateDBI(name); err != nil {
+				return err
+			}
+		}
+		// a plain key in the root database is not a database
+		root, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		return txn.Put(root, []byte("plain"), []byte("v"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	err = env.View(func(txn *Txn) error {
+		return env.ForEachDBI(txn, func(name string, dbi DBI) error {
+			if _, err := txn.Stat(dbi); err != nil {
+				return err
+			}
+			names = append(names, name)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("databases: %q", names)
+	}
+
+	errStop := fmt.Errorf("stop")
+	err = env.View(func(txn *Txn) error {
+		return env.ForEachDBI(txn, func(string, DBI) error { return errStop })
+	})
+	if err != errStop {
+		t.Errorf("callback error: %v", err)
+	}
+}
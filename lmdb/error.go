@@ -23,6 +23,12 @@ func (err *OpError) Error() string {
 	return err.Op + ": " + err.Errno.Error()
 }
 
+// Unwrap returns err.Errno so that errors.Is and errors.As can match the error
+// code, for example errors.Is(err, NotFound).
+func (err *OpError) Unwrap() error {
+	return err.Errno
+}
+
 // The most common error codes do not need to be handled explicity.  Errors can
 // be checked through helper functions IsNotFound, IsMapFull, etc, Otherwise
 // they should be checked using the IsErrno function instead of direct
@@ -100,12 +106,53 @@ func IsMapFull(err error) bool {
 	return IsErrno(err, MapFull)
 }
 
+// IsVersionMismatch returns true if the environment was written by a version
+// of LMDB with an incompatible data format.  The data must be migrated, for
+// example by dumping it with the matching version of mdb_dump, before this
+// version can open it.
+func IsVersionMismatch(err error) bool {
+	return IsErrno(err, VersionMismatch)
+}
+
+// IsInvalid returns true if the file opened is not an LMDB environment.
+// Either the path is wrong or the header of the data file is corrupted.
+func IsInvalid(err error) bool {
+	return IsErrno(err, Invalid)
+}
+
 // IsMapResized returns true if the environment has grown too large for the
 // current map after being resized by another process.
 func IsMapResized(err error) bool {
 	return IsErrno(err, MapResized)
 }
 
+// IsCorrupted returns true if LMDB found a page or meta page with invalid
+// contents.  This usually indicates that the data file is corrupted on disk,
+// and the environment should not be written until it has been verified or
+// restored from a backup.
+func IsCorrupted(err error) bool {
+	return IsErrno(err, Corrupted)
+}
+
+// IsPanic returns true if the environment encountered a fatal error inside
+// the LMDB library and must be closed.  No further transactions can succeed
+// until it is reopened.
+func IsPanic(err error) bool {
+	return IsErrno(err, Panic)
+}
+
+// IsBadDBI returns true if a database handle was used that is not valid, for
+// example because it was closed or changed unexpectedly.
+func IsBadDBI(err error) bool {
+	return IsErrno(err, BadDBI)
+}
+
+// IsBadTxn returns true if a transaction was used that cannot be, for example
+// because it had an earlier fatal error or was used by a child transaction.
+func IsBadTxn(err error) bool {
+	return IsErrno(err, BadTxn)
+}
+
 // IsErrno returns true if err's errno is the given errno.
 func IsErrno(err error, errno Errno) bool {
 	return IsErrnoFn(err, func(err error) bool { return err == errno })
@@ -1,7 +1,12 @@
 package lmdb
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"syscall"
 	"testing"
 )
@@ -60,3 +65,64 @@ func TestIsErrno(t *testing.T) {
 		t.Errorf("expected match: %v", operr)
 	}
 }
+
+func TestOpError_Unwrap(t *testing.T) {
+	err := fmt.Errorf("context: %w", &OpError{"mdb_get", NotFound})
+	if !errors.Is(err, NotFound) {
+		t.Errorf("errors.Is(%v, NotFound) = false", err)
+	}
+	var errno Errno
+	if !errors.As(err, &errno) || errno != NotFound {
+		t.Errorf("errors.As: %v", errno)
+	}
+}
+
+func TestErrnoPredicates(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		fn    func(error) bool
+		errno Errno
+	}{
+		{"IsCorrupted", IsCorrupted, Corrupted},
+		{"IsPanic", IsPanic, Panic},
+		{"IsBadDBI", IsBadDBI, BadDBI},
+		{"IsBadTxn", IsBadTxn, BadTxn},
+		{"IsVersionMismatch", IsVersionMismatch, VersionMismatch},
+		{"IsInvalid", IsInvalid, Invalid},
+	} {
+		if !test.fn(test.errno) {
+			t.Errorf("%s(%v) = false", test.name, test.errno)
+		}
+		if !test.fn(&OpError{"testop", test.errno}) {
+			t.Errorf("%s(OpError{%v}) = false", test.name, test.errno)
+		}
+		if test.fn(nil) || test.fn(NotFound) || test.fn(syscall.EINVAL) {
+			t.Errorf("%s matched an unrelated error", test.name)
+		}
+	}
+}
+
+func TestIsInvalid_open(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mdb_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	garbage := bytes.Repeat([]byte("not lmdb"), 2048)
+	if err := ioutil.WriteFile(filepath.Join(dir, "data.mdb"), garbage, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	err = env.Open(dir, 0, 0644)
+	if !IsInvalid(err) {
+		t.Errorf("open: %v", err)
+	}
+	if IsVersionMismatch(err) {
+		t.Errorf("open matched IsVersionMismatch: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package lmdb
+
+import "encoding/binary"
+
+// Uint64Key returns the 8-byte big-endian encoding of n.  Keys encoded by
+// Uint64Key sort in numeric order under the default key comparison, which
+// makes them suitable for timestamps and sequential IDs scanned by range.
+//
+// Uint64Key is unrelated to the IntegerKey flag, which compares keys as
+// native-endian integers.
+func Uint64Key(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
+
+// ParseUint64Key decodes a key encoded by Uint64Key.  It panics if b is shorter
+// than 8 bytes and ignores any bytes after the first 8.
+func ParseUint64Key(b []byte) uint64 {
+	return binary.BigEndian.Uint64(b)
+}
+
+// IntKey returns an 8-byte encoding of n that sorts in numeric order under the
+// default key comparison, negative numbers included.  Plain two's complement
+// would sort negative numbers after positive ones, so IntKey flips the sign bit
+// before encoding n big-endian.
+func IntKey(n int64) []byte {
+	return Uint64Key(uint64(n) ^ 1<<63)
+}
+
+// ParseIntKey decodes a key encoded by IntKey.  Like ParseUint64Key it panics
+// if b is shorter than 8 bytes.
+func ParseIntKey(b []byte) int64 {
+	return int64(ParseUint64Key(b) ^ 1<<63)
+}
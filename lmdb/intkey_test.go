@@ -0,0 +1,83 @@
+package lmdb
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestIntKey(t *testing.T) {
+	for _, n := range []int64{math.MinInt64, -1, 0, 1, math.MaxInt64} {
+		if got := ParseIntKey(IntKey(n)); got != n {
+			t.Errorf("IntKey(%d) round trip: %d", n, got)
+		}
+	}
+	for _, n := range []uint64{0, 1, math.MaxUint64} {
+		if got := ParseUint64Key(Uint64Key(n)); got != n {
+			t.Errorf("Uint64Key(%d) round trip: %d", n, got)
+		}
+	}
+}
+
+func TestIntKey_order(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	nums := []int64{math.MinInt64, -1 << 40, -300, -1, 0, 1, 255, 256, 1 << 40, math.MaxInt64}
+	for i := 0; i < 100; i++ {
+		nums = append(nums, rand.Int63()-rand.Int63())
+	}
+	shuffled := append([]int64(nil), nums...)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+
+	err := env.Update(func(txn *Txn) (err error) {
+		sdbi, err := txn.OpenDBI("signed", Create)
+		if err != nil {
+			return err
+		}
+		udbi, err := txn.OpenDBI("unsigned", Create)
+		if err != nil {
+			return err
+		}
+		for _, n := range shuffled {
+			if err = txn.Put(sdbi, IntKey(n), nil, 0); err != nil {
+				return err
+			}
+			if err = txn.Put(udbi, Uint64Key(uint64(n)), nil, 0); err != nil {
+				return err
+			}
+		}
+
+		var got []int64
+		s := NewScanner(txn, sdbi)
+		defer s.Close()
+		for s.Scan() {
+			got = append(got, ParseIntKey(s.Key()))
+		}
+		if err := s.Err(); err != nil {
+			return err
+		}
+		if len(got) != len(nums) || !sort.SliceIsSorted(got, func(i, j int) bool { return got[i] < got[j] }) {
+			t.Errorf("signed keys out of order: %v", got)
+		}
+
+		var ugot []uint64
+		s = NewScanner(txn, udbi)
+		defer s.Close()
+		for s.Scan() {
+			ugot = append(ugot, ParseUint64Key(s.Key()))
+		}
+		if err := s.Err(); err != nil {
+			return err
+		}
+		if len(ugot) != len(nums) || !sort.SliceIsSorted(ugot, func(i, j int) bool { return ugot[i] < ugot[j] }) {
+			t.Errorf("unsigned keys out of order: %v", ugot)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
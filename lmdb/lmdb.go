@@ -105,6 +105,26 @@ uses a finalizer to abort unreachable Txn objects.  But of course, applications
 must still be careful not to leak unterminated Txn objects in a way such that
 they fail get garbage collected.
 
+# Iteration
+
+A Scanner walks a database inside a transaction without the cursor
+boilerplate, in the style of bufio.Scanner.  SeekTo and SetFilter are
+optional.
+
+	err := env.View(func(txn *lmdb.Txn) error {
+		s := lmdb.NewScanner(txn, dbi)
+		defer s.Close()
+		s.SeekTo([]byte("user:"))
+		s.SetFilter(func(k, v []byte) bool { return len(v) > 0 })
+		for s.Scan() {
+			if !bytes.HasPrefix(s.Key(), []byte("user:")) {
+				break
+			}
+			fmt.Printf("%s=%s\n", s.Key(), s.Value())
+		}
+		return s.Err()
+	})
+
 # Caveats
 
 Write transactions (those created without the Readonly flag) must be created in
@@ -153,6 +173,17 @@ func VersionString() string {
 	return C.GoString(verstr)
 }
 
+// VersionTriple returns the major, minor, and patch version numbers of the
+// LMDB C library for programmatic comparison.  Use VersionString for the full
+// description reported by the library, suitable for diagnostic logs.
+//
+// See mdb_version.
+func VersionTriple() (major, minor, patch int) {
+	var maj, min, pat C.int
+	C.mdb_version(&maj, &min, &pat)
+	return int(maj), int(min), int(pat)
+}
+
 func cbool(b bool) C.int {
 	if b {
 		return 1
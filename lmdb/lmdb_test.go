@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -133,3 +135,18 @@ func TestVersion(t *testing.T) {
 		t.Error("empty version string")
 	}
 }
+
+func TestVersionTriple(t *testing.T) {
+	maj, min, patch := VersionTriple()
+	_maj, _min, _patch, _ := Version()
+	if maj != _maj || min != _min || patch != _patch {
+		t.Errorf("version triple %d.%d.%d (!= %d.%d.%d)", maj, min, patch, _maj, _min, _patch)
+	}
+	version := fmt.Sprintf("%d.%d.%d", maj, min, patch)
+	if !regexp.MustCompile(`^\d+\.\d+\.\d+$`).MatchString(version) {
+		t.Errorf("invalid version: %q", version)
+	}
+	if !strings.Contains(VersionString(), version) {
+		t.Errorf("version %q not in version string %q", version, VersionString())
+	}
+}
@@ -80,3 +80,17 @@ int lmdbgo_mdb_cursor_get2(MDB_cursor *cur, char *kdata, size_t kn, char *vdata,
     LMDBGO_SET_VAL(val, vn, vdata);
     return mdb_cursor_get(cur, key, val, op);
 }
+
+int lmdbgo_mdb_cmp(MDB_txn *txn, MDB_dbi dbi, char *adata, size_t an, char *bdata, size_t bn) {
+    MDB_val a, b;
+    LMDBGO_SET_VAL(&a, an, adata);
+    LMDBGO_SET_VAL(&b, bn, bdata);
+    return mdb_cmp(txn, dbi, &a, &b);
+}
+
+int lmdbgo_mdb_dcmp(MDB_txn *txn, MDB_dbi dbi, char *adata, size_t an, char *bdata, size_t bn) {
+    MDB_val a, b;
+    LMDBGO_SET_VAL(&a, an, adata);
+    LMDBGO_SET_VAL(&b, bn, bdata);
+    return mdb_dcmp(txn, dbi, &a, &b);
+}
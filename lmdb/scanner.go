@@ -0,0 +1,114 @@
+package lmdb
+
+import "errors"
+
+// errScannerClosed is returned by Scanner.Err after Scan is called on a
+// closed Scanner.
+var errScannerClosed = errors.New("scanner is closed")
+
+// Scanner iterates over the items of a database in key order, in the style of
+// bufio.Scanner.  A Scanner is only valid inside the transaction it was created
+// in, and like every Cursor it must not be used from multiple goroutines at
+// the same time.
+//
+// The package lmdbscan provides a Scanner with control over the cursor ops
+// used to move.
+type Scanner struct {
+	cur    *Cursor
+	filter func(key, val []byte) bool
+	key    []byte
+	val    []byte
+	err    error
+	set    bool // the cursor was positioned by SeekTo and Scan must not move it
+}
+
+// NewScanner returns a Scanner over dbi in txn.  An error opening the cursor
+// is reported by Err after the first call to Scan.  The Scanner must be
+// closed when it is no longer needed, see Cursor.Close.
+func NewScanner(txn *Txn, dbi DBI) *Scanner {
+	s := &Scanner{}
+	s.cur, s.err = txn.OpenCursor(dbi)
+	return s
+}
+
+// SetFilter makes Scan skip items for which fn returns false.  fn is passed
+// the slices Key and Value would return.  A nil fn removes the filter.
+func (s *Scanner) SetFilter(fn func(key, val []byte) bool) {
+	s.filter = fn
+}
+
+// SeekTo positions s so that the next call to Scan yields the first item with
+// a key not less than key, or the first item after it accepted by the filter.
+// SeekTo reports whether an item with such a key exists.
+func (s *Scanner) SeekTo(key []byte) bool {
+	if !s.checkOpen() {
+		return false
+	}
+	s.key, s.val, s.err = s.cur.Get(key, nil, SetRange)
+	s.set = s.err == nil
+	return s.set
+}
+
+// Scan moves s to the next item accepted by the filter and reports whether
+// there was one.  The first call to Scan moves to the first item, unless
+// SeekTo was called.  Scan returns false at the end of the database or on an
+// error, which Err returns.
+func (s *Scanner) Scan() bool {
+	if !s.checkOpen() {
+		return false
+	}
+	for {
+		if s.set {
+			s.set = false
+		} else {
+			s.key, s.val, s.err = s.cur.Get(nil, nil, Next)
+		}
+		if s.err != nil {
+			s.key, s.val = nil, nil
+			return false
+		}
+		if s.filter == nil || s.filter(s.key, s.val) {
+			return true
+		}
+	}
+}
+
+// Key returns the key of the item read by the last call to Scan.
+func (s *Scanner) Key() []byte {
+	return s.key
+}
+
+// Value returns the value of the item read by the last call to Scan.
+func (s *Scanner) Value() []byte {
+	return s.val
+}
+
+// Err returns the error that stopped Scan, reaching the end of the database
+// is not an error.
+func (s *Scanner) Err() error {
+	if IsNotFound(s.err) {
+		return nil
+	}
+	return s.err
+}
+
+// Close closes the cursor of s.  Close does not terminate the transaction and
+// is idempotent.  The error result is always nil and keeps Close compatible
+// with io.Closer.
+func (s *Scanner) Close() error {
+	if s.cur != nil {
+		s.cur.Close()
+		s.cur = nil
+	}
+	return nil
+}
+
+func (s *Scanner) checkOpen() bool {
+	if s.cur != nil {
+		return true
+	}
+	if s.err == nil || IsNotFound(s.err) {
+		s.err = errScannerClosed
+	}
+	return false
+}
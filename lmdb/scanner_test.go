@@ -0,0 +1,77 @@
+package lmdb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	var dbi DBI
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err = txn.OpenDBI("testdb", Create)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 10; i++ {
+			if err = txn.Put(dbi, []byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprint(i)), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(s *Scanner) string {
+		var keys []string
+		for s.Scan() {
+			keys = append(keys, string(s.Key())+"="+string(s.Value()))
+		}
+		if err := s.Err(); err != nil {
+			t.Error(err)
+		}
+		return strings.Join(keys, ",")
+	}
+	err = env.View(func(txn *Txn) error {
+		s := NewScanner(txn, dbi)
+		defer s.Close()
+		if got := scan(s); got != "key0=0,key1=1,key2=2,key3=3,key4=4,key5=5,key6=6,key7=7,key8=8,key9=9" {
+			t.Errorf("scan: %s", got)
+		}
+
+		s = NewScanner(txn, dbi)
+		defer s.Close()
+		if !s.SeekTo([]byte("key55")) {
+			t.Errorf("seek: %v", s.Err())
+		}
+		s.SetFilter(func(k, v []byte) bool { return v[0]%2 == 0 })
+		if got := scan(s); got != "key6=6,key8=8" {
+			t.Errorf("seek and filter: %s", got)
+		}
+		if s.SeekTo([]byte("key99")) {
+			t.Errorf("seek past the end")
+		}
+		if s.Scan() || s.Err() != nil {
+			t.Errorf("scan past the end: %v", s.Err())
+		}
+
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Errorf("second close: %v", err)
+		}
+		if s.Scan() || s.Err() != errScannerClosed {
+			t.Errorf("scan after close: %v", s.Err())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
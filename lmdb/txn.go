@@ -18,6 +18,18 @@ import (
 // Create flag must always be supplied when opening a non-root DBI for the
 // first time. When a DBI is created, the other flags will be persisted in the
 // LMDB and automatically applied when opening the DBI.
+//
+// The flags may be combined with bitwise or.  DupFixed, ReverseDup, and
+// IntegerDup only have an effect together with DupSort.  Common combinations
+// are:
+//
+//	DupSort                         sorted sets of variable size values per key
+//	DupSort|DupFixed                sets of fixed size values, enables GetMultiple
+//	DupSort|DupFixed|IntegerDup     sets of fixed size native integers
+//	IntegerKey                      native integer keys, all of the same size
+//
+// Flags other than Create are ignored when opening a database that already
+// exists, the persisted flags apply instead.  Txn.Flags reports them.
 const (
 	// Flags for Txn.OpenDBI.
 
@@ -63,6 +75,10 @@ type Txn struct {
 	// reset/renewed
 	id uintptr
 
+	// gen is incremented whenever the snapshot held by the Txn is released
+	// (reset or terminated) so Val values can detect that they are stale.
+	gen uint64
+
 	// Pointer to scratch space for key and val in readonly transactions
 	cbuf unsafe.Pointer
 
@@ -141,6 +157,12 @@ func (txn *Txn) getID() uintptr {
 	return uintptr(C.mdb_txn_id(txn._txn))
 }
 
+// IsReadOnly returns true if txn was begun with the Readonly flag, as by
+// Env.View.  Subtransactions are never read-only.
+func (txn *Txn) IsReadOnly() bool {
+	return txn.readonly
+}
+
 // RunOp executes fn with txn as an argument.  During the execution of fn no
 // goroutine may call the Commit, Abort, Reset, and Renew methods on txn.
 // RunOp returns the result of fn without any further action.  RunOp will not
@@ -244,6 +266,7 @@ func (txn *Txn) clearTxn() {
 	// Clear the C object to prevent any potential future use of the freed
 	// pointer.
 	txn._txn = nil
+	txn.gen++
 
 	// Clear txn.id because it no longer matches the value of txn._txn (and
 	// future calls to txn.ID() should not see the stale id).  Instead of
@@ -273,6 +296,12 @@ func (txn *Txn) resetID() {
 // be called to release its slot in the lock table and free its memory.  Reset
 // panics if txn is managed by Update, View, etc.
 //
+// Reset must only be called on readonly transactions, it has no effect on
+// write transactions.  Reading from txn (or from cursors opened in it) after
+// Reset and before a successful call to Renew has undefined results.  Reset
+// followed by Renew is cheaper than aborting and beginning a new readonly
+// transaction, which makes it suitable for polling loops.
+//
 // See mdb_txn_reset.
 func (txn *Txn) Reset() {
 	if txn.managed {
@@ -284,10 +313,12 @@ func (txn *Txn) Reset() {
 
 func (txn *Txn) reset() {
 	C.mdb_txn_reset(txn._txn)
+	txn.gen++
 }
 
 // Renew reuses a transaction that was previously reset by calling txn.Reset().
-// Renew panics if txn is managed by Update, View, etc.
+// The renewed transaction views the latest snapshot of the environment.  Renew
+// panics if txn is managed by Update, View, etc.
 //
 // See mdb_txn_renew.
 func (txn *Txn) Renew() error {
@@ -449,6 +480,96 @@ func (txn *Txn) Get(dbi DBI, key []byte) ([]byte, error) {
 	return b, nil
 }
 
+// BulkGet retrieves the values of keys from database dbi and returns them in
+// the same order, with nil for keys that are not present.  The values are
+// always copies, regardless of txn.RawRead.  BulkGet returns the first error
+// other than NotFound, along with no values.
+//
+// See mdb_get.
+func (txn *Txn) BulkGet(dbi DBI, keys [][]byte) ([][]byte, error) {
+	vals := make([][]byte, len(keys))
+	for i, key := range keys {
+		kdata, kn := valBytes(key)
+		ret := C.lmdbgo_mdb_get(
+			txn._txn, C.MDB_dbi(dbi),
+			(*C.char)(unsafe.Pointer(&kdata[0])), C.size_t(kn),
+			txn.val,
+		)
+		if ret == C.MDB_NOTFOUND {
+			continue
+		}
+		if ret != success {
+			*txn.val = C.MDB_val{}
+			return nil, operrno("mdb_get", ret)
+		}
+		vals[i] = getBytesCopy(txn.val)
+	}
+	*txn.val = C.MDB_val{}
+	return vals, nil
+}
+
+// GetVal retrieves items from database dbi like Get but returns a Val that
+// references the value inside the memory map without copying it, regardless of
+// txn.RawRead.  The returned Val becomes invalid when txn is reset or
+// terminated.
+//
+// See mdb_get.
+func (txn *Txn) GetVal(dbi DBI, key []byte) (Val, error) {
+	kdata, kn := valBytes(key)
+	ret := C.lmdbgo_mdb_get(
+		txn._txn, C.MDB_dbi(dbi),
+		(*C.char)(unsafe.Pointer(&kdata[0])), C.size_t(kn),
+		txn.val,
+	)
+	err := operrno("mdb_get", ret)
+	if err != nil {
+		*txn.val = C.MDB_val{}
+		return Val{}, err
+	}
+	v := txn.newVal(txn.val)
+	*txn.val = C.MDB_val{}
+	return v, nil
+}
+
+// GetUnsafe retrieves items from database dbi like Get but always returns a
+// slice referencing the value inside the memory map, regardless of
+// txn.RawRead, so it never allocates.
+//
+// The returned slice must not be accessed after txn is reset or terminated.
+// It must not be written to either, except in a write transaction of an
+// environment opened with WriteMap, and even then changes bypass LMDB and
+// are not undone if txn aborts.  Without WriteMap the memory map is readonly
+// and writing to the slice crashes the program.
+//
+// See mdb_get.
+func (txn *Txn) GetUnsafe(dbi DBI, key []byte) ([]byte, error) {
+	kdata, kn := valBytes(key)
+	ret := C.lmdbgo_mdb_get(
+		txn._txn, C.MDB_dbi(dbi),
+		(*C.char)(unsafe.Pointer(&kdata[0])), C.size_t(kn),
+		txn.val,
+	)
+	err := operrno("mdb_get", ret)
+	if err != nil {
+		*txn.val = C.MDB_val{}
+		return nil, err
+	}
+	b := getBytes(txn.val)
+	*txn.val = C.MDB_val{}
+	return b, nil
+}
+
+// GetString is like Get for string keys and values.  The returned string is
+// always a copy and remains valid after txn terminates, regardless of
+// txn.RawRead.
+func (txn *Txn) GetString(dbi DBI, key string) (string, error) {
+	b, err := txn.GetUnsafe(dbi, []byte(key))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 func (txn *Txn) putNilKey(dbi DBI, flags uint) error {
 	// mdb_put with an empty key will always fail
 	ret := C.lmdbgo_mdb_put2(txn._txn, C.MDB_dbi(dbi), nil, 0, nil, 0, C.uint(flags))
@@ -477,9 +598,21 @@ func (txn *Txn) Put(dbi DBI, key []byte, val []byte, flags uint) error {
 	return operrno("mdb_put", ret)
 }
 
+// PutString is like Put for string keys and values.
+func (txn *Txn) PutString(dbi DBI, key, val string, flags uint) error {
+	return txn.Put(dbi, []byte(key), []byte(val), flags)
+}
+
 // PutReserve returns a []byte of length n that can be written to, potentially
 // avoiding a memcopy.  The returned byte slice is only valid in txn's thread,
 // before it has terminated.
+//
+// The returned slice references space reserved inside the memory map.  It must
+// be filled before the next update operation in txn, and it must not be
+// accessed after txn commits or aborts.  Applications can serialize values
+// directly into the slice instead of encoding into a temporary buffer.
+//
+// See mdb_put and MDB_RESERVE.
 func (txn *Txn) PutReserve(dbi DBI, key []byte, n int, flags uint) ([]byte, error) {
 	if len(key) == 0 {
 		return nil, txn.putNilKey(dbi, flags)
@@ -516,7 +649,44 @@ func (txn *Txn) Del(dbi DBI, key, val []byte) error {
 	return operrno("mdb_del", ret)
 }
 
-// OpenCursor allocates and initializes a Cursor to database dbi.
+// Cmp compares two keys the way database dbi orders them and returns a
+// negative number, zero, or a positive number if a sorts before, equal to, or
+// after b.  It honors the ReverseKey and IntegerKey flags of dbi.
+//
+// See mdb_cmp.
+func (txn *Txn) Cmp(dbi DBI, a, b []byte) int {
+	adata, an := valBytes(a)
+	bdata, bn := valBytes(b)
+	return int(C.lmdbgo_mdb_cmp(
+		txn._txn, C.MDB_dbi(dbi),
+		(*C.char)(unsafe.Pointer(&adata[0])), C.size_t(an),
+		(*C.char)(unsafe.Pointer(&bdata[0])), C.size_t(bn),
+	))
+}
+
+// DCmp is like Cmp for the values of a key in a DupSort database, it honors
+// the ReverseDup and IntegerDup flags of dbi.
+//
+// See mdb_dcmp.
+func (txn *Txn) DCmp(dbi DBI, a, b []byte) int {
+	adata, an := valBytes(a)
+	bdata, bn := valBytes(b)
+	return int(C.lmdbgo_mdb_dcmp(
+		txn._txn, C.MDB_dbi(dbi),
+		(*C.char)(unsafe.Pointer(&adata[0])), C.size_t(an),
+		(*C.char)(unsafe.Pointer(&bdata[0])), C.size_t(bn),
+	))
+}
+
+// OpenCursor allocates and initializes a Cursor to database dbi.  A cursor
+// must not be used after its transaction terminates, unless it belongs to a
+// readonly transaction and is passed to Cursor.Renew.
+//
+// Cursors of write transactions are released by LMDB when the transaction
+// terminates.  Cursors of readonly transactions are not and must be closed
+// with Cursor.Close, which may happen before or after the transaction
+// terminates.  As a safety net a readonly cursor that becomes unreachable
+// without being closed is closed by a finalizer, Close removes the finalizer.
 //
 // See mdb_cursor_open.
 func (txn *Txn) OpenCursor(dbi DBI) (*Cursor, error) {
@@ -11,6 +11,95 @@ import (
 	"time"
 )
 
+func TestTxn_IsReadOnly(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	err := env.View(func(txn *Txn) error {
+		if !txn.IsReadOnly() {
+			t.Errorf("view txn is not read-only")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	err = env.Update(func(txn *Txn) error {
+		if txn.IsReadOnly() {
+			t.Errorf("update txn is read-only")
+		}
+		return txn.Sub(func(sub *Txn) error {
+			if sub.IsReadOnly() {
+				t.Errorf("subtransaction is read-only")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTxn_PutString(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	db, err := openRoot(env, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairs := map[string]string{
+		"plain":       "value",
+		"héllo, 世界":   "🙂 ünïcödé",
+		"nul\x00key":  "nul\x00value\x00",
+		"empty value": "",
+	}
+	err = env.Update(func(txn *Txn) error {
+		for k, v := range pairs {
+			if err := txn.PutString(db, k, v, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	err = env.View(func(txn *Txn) error {
+		txn.RawRead = true
+		for k := range pairs {
+			v, err := txn.GetString(db, k)
+			if err != nil {
+				return err
+			}
+			got[k] = v
+		}
+		_, err := txn.GetString(db, "missing")
+		if !IsNotFound(err) {
+			t.Errorf("missing key: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// got is read after the transaction terminated
+	for k, v := range pairs {
+		if got[k] != v {
+			t.Errorf("%q: %q (!= %q)", k, got[k], v)
+		}
+	}
+
+	err = env.Update(func(txn *Txn) error {
+		return txn.PutString(db, "", "v", 0)
+	})
+	if !IsErrno(err, BadValSize) {
+		t.Errorf("empty key: %v", err)
+	}
+}
+
 func TestTxn_ID(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -197,6 +286,54 @@ func TestTxn_Drop(t *testing.T) {
 	}
 }
 
+func TestTxn_Cmp(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	err := env.Update(func(txn *Txn) error {
+		plain, err := txn.OpenDBI("plain", Create|DupSort)
+		if err != nil {
+			return err
+		}
+		reverse, err := txn.OpenDBI("reverse", Create|ReverseKey|DupSort|ReverseDup)
+		if err != nil {
+			return err
+		}
+		for _, tc := range []struct {
+			dbi  DBI
+			a, b string
+			want int
+		}{
+			{plain, "ab", "ba", -1},
+			{plain, "ab", "ab", 0},
+			{plain, "", "a", -1},
+			{reverse, "ab", "ba", 1},
+			{reverse, "ba", "ca", -1},
+		} {
+			if got := txn.Cmp(tc.dbi, []byte(tc.a), []byte(tc.b)); sign(got) != tc.want {
+				t.Errorf("Cmp(%q, %q): %d (want sign %d)", tc.a, tc.b, got, tc.want)
+			}
+			if got := txn.DCmp(tc.dbi, []byte(tc.a), []byte(tc.b)); sign(got) != tc.want {
+				t.Errorf("DCmp(%q, %q): %d (want sign %d)", tc.a, tc.b, got, tc.want)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
 func TestTxn_Del(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -337,6 +474,58 @@ func TestTxn_PutReserve(t *testing.T) {
 	}
 }
 
+func TestTxn_PutReserve_struct(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	type point struct {
+		X, Y int32
+		Z    uint64
+	}
+	want := point{X: -7, Y: 42, Z: 1 << 40}
+	size := binary.Size(want)
+
+	db, err := openRoot(env, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *Txn) (err error) {
+		p, err := txn.PutReserve(db, []byte("point"), size, 0)
+		if err != nil {
+			return err
+		}
+		if len(p) != size {
+			return fmt.Errorf("reserved %d bytes (!= %d)", len(p), size)
+		}
+		binary.BigEndian.PutUint32(p[0:], uint32(want.X))
+		binary.BigEndian.PutUint32(p[4:], uint32(want.Y))
+		binary.BigEndian.PutUint64(p[8:], want.Z)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.View(func(txn *Txn) (err error) {
+		v, err := txn.Get(db, []byte("point"))
+		if err != nil {
+			return err
+		}
+		var got point
+		err = binary.Read(bytes.NewReader(v), binary.BigEndian, &got)
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("value: %+v (!= %+v)", got, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 func TestTxn_bytesBuffer(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -423,6 +612,99 @@ func TestTxn_Put_overwrite(t *testing.T) {
 	}
 }
 
+func TestTxn_GetUnsafe(t *testing.T) {
+	env := setupFlags(t, WriteMap)
+	defer clean(env, t)
+
+	db, err := openRoot(env, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = env.Update(func(txn *Txn) (err error) {
+		if err = txn.Put(db, []byte("k"), []byte("abc"), 0); err != nil {
+			return err
+		}
+		v, err := txn.GetUnsafe(db, []byte("k"))
+		if err != nil {
+			return err
+		}
+		if string(v) != "abc" {
+			t.Errorf("value: %q", v)
+		}
+		// a write transaction on a WriteMap environment may modify the map
+		v[0] = 'x'
+		v, err = txn.Get(db, []byte("k"))
+		if err != nil {
+			return err
+		}
+		if string(v) != "xbc" {
+			t.Errorf("modified value: %q", v)
+		}
+		if _, err = txn.GetUnsafe(db, []byte("missing")); !IsNotFound(err) {
+			t.Errorf("missing key: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTxn_BulkGet(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	db, err := openRoot(env, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = env.Update(func(txn *Txn) (err error) {
+		for _, k := range []string{"a", "c", "e"} {
+			if err = txn.Put(db, []byte(k), []byte("v"+k), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = env.View(func(txn *Txn) (err error) {
+		txn.RawRead = true
+		keys := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+		vals, err := txn.BulkGet(db, keys)
+		if err != nil {
+			return err
+		}
+		if len(vals) != len(keys) {
+			return fmt.Errorf("unexpected number of values: %d", len(vals))
+		}
+		for i, want := range []string{"va", "", "vc", "", "ve"} {
+			if want == "" && vals[i] != nil {
+				t.Errorf("missing key %q: %q", keys[i], vals[i])
+			}
+			if want != "" && string(vals[i]) != want {
+				t.Errorf("key %q: %q (!= %q)", keys[i], vals[i], want)
+			}
+		}
+
+		_, err = txn.BulkGet(db, [][]byte{[]byte("a"), nil})
+		if !IsErrno(err, BadValSize) {
+			t.Errorf("empty key: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 func TestTxn_OpenDBI_emptyName(t *testing.T) {
 	env := setup(t)
 	defer clean(env, t)
@@ -813,6 +1095,46 @@ func TestTxn_Renew(t *testing.T) {
 	}
 }
 
+func TestTxn_Renew_loop(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	dbroot, err := openRoot(env, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txn, err := env.BeginTxn(nil, Readonly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txn.Abort()
+	txn.Reset()
+
+	for i := 0; i < 100; i++ {
+		v := []byte(fmt.Sprint(i))
+		err = env.Update(func(txn *Txn) (err error) {
+			return txn.Put(dbroot, []byte("k"), v, 0)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = txn.Renew()
+		if err != nil {
+			t.Fatalf("renew %d: %v", i, err)
+		}
+		val, err := txn.Get(dbroot, []byte("k"))
+		if err != nil {
+			t.Fatalf("get %d: %v", i, err)
+		}
+		if !bytes.Equal(val, v) {
+			t.Fatalf("renew %d: stale value %q (!= %q)", i, val, v)
+		}
+		txn.Reset()
+	}
+}
+
 func TestTxn_Renew_noReset(t *testing.T) {
 	env := setup(t)
 	path, err := env.Path()
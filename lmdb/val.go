@@ -94,6 +94,69 @@ func (m *Multi) Page() []byte {
 	return m.page[:len(m.page):len(m.page)]
 }
 
+// Val references a value inside the memory map of an environment without
+// copying it.  A Val is only usable during the lifetime of the transaction
+// that produced it, and only until that transaction is reset.  Valid reports
+// whether the Val may still be used.  Bytes and String panic when called on
+// an invalid Val rather than reading memory that may have been reused or
+// unmapped.  In a write transaction the referenced memory may also be changed
+// by any later update in the transaction, which Valid cannot detect.
+//
+// The zero Val is invalid.
+type Val struct {
+	data unsafe.Pointer
+	len  int
+
+	// txn and gen are the sentinel used to detect use after the transaction
+	// terminated.  txn.gen is incremented each time the transaction's
+	// snapshot is released.
+	txn *Txn
+	gen uint64
+}
+
+func (txn *Txn) newVal(val *C.MDB_val) Val {
+	return Val{
+		data: val.mv_data,
+		len:  int(val.mv_size),
+		txn:  txn,
+		gen:  txn.gen,
+	}
+}
+
+// Valid returns true if the transaction that produced v is still active.
+func (v Val) Valid() bool {
+	return v.txn != nil && v.txn._txn != nil && v.txn.gen == v.gen
+}
+
+// Len returns the length of the value in bytes.
+func (v Val) Len() int {
+	return v.len
+}
+
+// Bytes returns a readonly slice referencing the value inside the memory map.
+// The slice must not be modified and must not be accessed after the
+// transaction that produced v has terminated.  Bytes panics if v is not
+// valid.
+func (v Val) Bytes() []byte {
+	v.check()
+	if v.data == nil {
+		return nil
+	}
+	return (*[valMaxSize]byte)(v.data)[:v.len:v.len]
+}
+
+// String returns a copy of the value as a string.  String panics if v is not
+// valid.
+func (v Val) String() string {
+	return string(v.Bytes())
+}
+
+func (v Val) check() {
+	if !v.Valid() {
+		panic("lmdb: Val used after its transaction terminated")
+	}
+}
+
 var eb = []byte{0}
 
 func valBytes(b []byte) ([]byte, int) {
@@ -83,3 +83,98 @@ func TestVal(t *testing.T) {
 		t.Errorf("getBytesCopy() overlaps with orignal slice")
 	}
 }
+
+func TestTxn_GetVal(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	db, err := openRoot(env, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *Txn) (err error) {
+		return txn.Put(db, []byte("k"), []byte("hello"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var val Val
+	err = env.View(func(txn *Txn) (err error) {
+		val, err = txn.GetVal(db, []byte("k"))
+		if err != nil {
+			return err
+		}
+		if !val.Valid() {
+			t.Errorf("val is not valid inside its transaction")
+		}
+		if val.Len() != 5 {
+			t.Errorf("len: %d (!= 5)", val.Len())
+		}
+		if !bytes.Equal(val.Bytes(), []byte("hello")) {
+			t.Errorf("bytes: %q", val.Bytes())
+		}
+		if val.String() != "hello" {
+			t.Errorf("string: %q", val.String())
+		}
+		_, err = txn.GetVal(db, []byte("missing"))
+		if !IsNotFound(err) {
+			t.Errorf("missing: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if val.Valid() {
+		t.Errorf("val is valid after its transaction terminated")
+	}
+	assertValPanic(t, val)
+	assertValPanic(t, Val{})
+}
+
+func TestTxn_GetVal_reset(t *testing.T) {
+	env := setup(t)
+	defer clean(env, t)
+
+	db, err := openRoot(env, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *Txn) (err error) {
+		return txn.Put(db, []byte("k"), []byte("v"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txn, err := env.BeginTxn(nil, Readonly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txn.Abort()
+
+	val, err := txn.GetVal(db, []byte("k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn.Reset()
+	if err := txn.Renew(); err != nil {
+		t.Fatal(err)
+	}
+	if val.Valid() {
+		t.Errorf("val is valid after its transaction was reset")
+	}
+	assertValPanic(t, val)
+}
+
+func assertValPanic(t *testing.T, val Val) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	val.Bytes()
+}
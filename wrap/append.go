@@ -0,0 +1,75 @@
+package wrap
+
+import "github.com/Data-Corruption/lmdb-go/lmdb"
+
+// Append inserts a key/value pair at the end of the database using
+// MDB_APPEND, which skips the tree search done by Write. The key must sort
+// strictly after every key already in the database, otherwise the returned
+// error satisfies lmdb.IsErrno(err, lmdb.KeyExist) and nothing is written.
+func (db *DB) Append(dbName string, key, value []byte) error {
+	return db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			stored, err := db.encodeValue(txn, dbi, dbName, key, value)
+			if err != nil {
+				return err
+			}
+			if err := txn.Put(dbi, key, stored, lmdb.Append); err != nil {
+				return err
+			}
+			return db.recordWrite(txn, OpPut, dbName, key, stored)
+		}, db.event(OpPut, dbName, key, value))
+	})
+}
+
+// BatchAppend appends pairs in a single transaction, see Append. Pairs must be
+// sorted in strictly ascending key order and sort after every existing key. If
+// any pair is out of order none of the pairs are written.
+func (db *DB) BatchAppend(dbName string, pairs []KV) error {
+	return db.observeWrite(dbName, func() error {
+		return db.putBatch(dbName, pairs, lmdb.Append)
+	})
+}
+
+// putBatch writes pairs into dbName in a single transaction with the
+// Txn.Put flags.
+func (db *DB) putBatch(dbName string, pairs []KV, flags uint) error {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return err
+	}
+	for _, kv := range pairs {
+		if len(kv.Key) == 0 {
+			return ErrEmptyKey
+		}
+	}
+	var events *[]KeyEvent
+	if db.watched(dbName) {
+		events = &[]KeyEvent{}
+	}
+	return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		for _, kv := range pairs {
+			stored, err := db.encodeValue(txn, dbi, dbName, kv.Key, kv.Value)
+			if err != nil {
+				return err
+			}
+			if err := txn.Put(dbi, kv.Key, stored, flags); err != nil {
+				return err
+			}
+			if err := db.recordWrite(txn, OpPut, dbName, kv.Key, stored); err != nil {
+				return err
+			}
+			db.addEvent(events, OpPut, dbName, kv.Key, kv.Value)
+		}
+		return nil
+	}, events)
+}
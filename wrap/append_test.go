@@ -0,0 +1,121 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_Append(t *testing.T) {
+	db := newTestDB(t, "a")
+	for _, k := range []string{"a", "b", "c"} {
+		if err := db.Append("a", []byte(k), []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Append("a", []byte("b0"), []byte("v")); !lmdb.IsErrno(err, lmdb.KeyExist) {
+		t.Errorf("out of order append: %v", err)
+	}
+	if err := db.Append("a", []byte("c"), []byte("v")); !lmdb.IsErrno(err, lmdb.KeyExist) {
+		t.Errorf("duplicate append: %v", err)
+	}
+	if err := db.Append("nope", []byte("d"), nil); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+	if n, _ := db.Count("a"); n != 3 {
+		t.Errorf("count: %d", n)
+	}
+}
+
+func TestDB_BatchAppend(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a")
+	err := db.BatchAppend("a", []KV{{[]byte("b"), nil}, {[]byte("c"), nil}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an unsorted batch is rejected as a whole
+	err = db.BatchAppend("a", []KV{{[]byte("e"), nil}, {[]byte("d"), nil}})
+	if !lmdb.IsErrno(err, lmdb.KeyExist) {
+		t.Errorf("unsorted batch: %v", err)
+	}
+	if n, _ := db.Count("a"); n != 3 {
+		t.Errorf("count: %d", n)
+	}
+	if err := db.BatchAppend("a", []KV{{nil, nil}}); err != ErrEmptyKey {
+		t.Errorf("empty key: %v", err)
+	}
+}
+
+func sortedKey(i int) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(i))
+	return k
+}
+
+func BenchmarkDB_Append(b *testing.B) {
+	db := newTestDB(b, "a")
+	val := make([]byte, 64)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.Append("a", sortedKey(i), val); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDB_Write_sorted(b *testing.B) {
+	db := newTestDB(b, "a")
+	val := make([]byte, 64)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.Write("a", sortedKey(i), val); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkLoad1M loads one million sorted keys per iteration in a single
+// transaction, isolating the cost of the tree operations from commits.
+func benchmarkLoad1M(b *testing.B, load func(db *DB, pairs []KV) error) {
+	const n = 1000000
+	pairs := make([]KV, n)
+	val := make([]byte, 16)
+	for i := range pairs {
+		pairs[i] = KV{sortedKey(i), val}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		db := newTestDB(b, "a")
+		b.StartTimer()
+		if err := load(db, pairs); err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		db.Close()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkDB_BatchAppend_1M(b *testing.B) {
+	benchmarkLoad1M(b, func(db *DB, pairs []KV) error {
+		return db.BatchAppend("a", pairs)
+	})
+}
+
+func BenchmarkDB_BatchWrite_1M(b *testing.B) {
+	benchmarkLoad1M(b, func(db *DB, pairs []KV) error {
+		dbi := db.GetDBis()["a"]
+		return db.Update(func(txn *lmdb.Txn) error {
+			for _, kv := range pairs {
+				if err := txn.Put(dbi, kv.Key, kv.Value, 0); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
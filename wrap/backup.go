@@ -0,0 +1,255 @@
+package wrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// ErrDirNotEmpty is returned when a backup would be written to a directory
+// that already holds files.
+var ErrDirNotEmpty = errors.New("directory is not empty")
+
+// writeDeadliner is implemented by destinations that support write
+// deadlines, like net.Conn and os.File.
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// CopyToWriter writes a consistent backup of the environment to w. The
+// backup is a complete data file that can be opened by New after being saved
+// as data.mdb in an empty directory. If compact is true free pages are
+// omitted and pages are renumbered, which is slower but produces a smaller
+// file.
+//
+// When w is an *os.File CopyToWriter behaves like BackupTo. Otherwise LMDB
+// writes the backup into a pipe which is streamed to w, so nothing is staged
+// on disk. An error returned by w aborts the copy and is returned. If
+// Options.CopyTimeout is set and w has a SetWriteDeadline method, the
+// deadline is set before writing starts.
+func (db *DB) CopyToWriter(w io.Writer, compact bool) error {
+	return db.CopyToWriterContext(context.Background(), w, compact)
+}
+
+// CopyToWriterContext is like CopyToWriter but aborts the copy, and returns
+// ctx.Err(), when ctx is done. An *os.File written to directly is only
+// checked before the copy starts, LMDB cannot be interrupted while it writes
+// to a file.
+func (db *DB) CopyToWriterContext(ctx context.Context, w io.Writer, compact bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d, ok := w.(writeDeadliner); ok && db.opts.CopyTimeout > 0 {
+		if err := d.SetWriteDeadline(time.Now().Add(db.opts.CopyTimeout)); err != nil {
+			return err
+		}
+	}
+	if f, ok := w.(*os.File); ok {
+		return db.BackupTo(f, compact)
+	}
+
+	if err := db.acquire(&db.active); err != nil {
+		return err
+	}
+	defer db.release(&db.active)
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer pr.Close()
+
+	cw := &countingWriter{w: w}
+	stopProgress := db.reportProgress(cw.written)
+	quit := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			// fail the writes of LMDB with EPIPE so the copy stops
+			pr.Close()
+		case <-quit:
+		}
+	}()
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(cw, pr)
+		if err != nil {
+			pr.Close()
+		}
+		copied <- err
+	}()
+	err = db.env.CopyFDFlag(pw.Fd(), copyFlags(compact))
+	pw.Close()
+	werr := <-copied
+	close(quit)
+	stopProgress()
+	if err == nil && werr == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// BackupTo writes a consistent backup of the environment to f, see
+// CopyToWriter. LMDB writes to the file descriptor of f directly, which may be
+// a pipe or a socket. A failed write, like ENOSPC or EPIPE, aborts the copy
+// and is returned, the environment stays usable.
+func (db *DB) BackupTo(f *os.File, compact bool) error {
+	if err := db.acquire(&db.active); err != nil {
+		return err
+	}
+	defer db.release(&db.active)
+	size := func() int64 { return -1 }
+	if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+		start := fi.Size()
+		size = func() int64 {
+			fi, err := f.Stat()
+			if err != nil {
+				return 0
+			}
+			return fi.Size() - start
+		}
+	}
+	defer db.reportProgress(size)()
+	return db.env.CopyFDFlag(f.Fd(), copyFlags(compact))
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64 // accessed atomically
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	atomic.AddInt64(&cw.n, int64(n))
+	return n, err
+}
+
+func (cw *countingWriter) written() int64 {
+	return atomic.LoadInt64(&cw.n)
+}
+
+// statSize returns the size of the file at path, or zero if it cannot be
+// read.
+func statSize(path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// reportProgress calls Options.BackupProgress with the result of written
+// until the returned function is called, which waits for any call in
+// progress. A negative result of written means progress is unknown and
+// nothing is reported.
+func (db *DB) reportProgress(written func() int64) (stop func()) {
+	fn := db.opts.BackupProgress
+	if fn == nil || written() < 0 {
+		return func() {}
+	}
+	var total int64
+	if info, err := db.env.Info(); err == nil {
+		if stat, err := db.env.Stat(); err == nil {
+			total = (info.LastPNO + 1) * int64(stat.PSize)
+		}
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(db.opts.BackupProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fn(written(), total)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// copyFlags returns the mdb_env_copy2 flags for a backup.
+func copyFlags(compact bool) uint {
+	if compact {
+		return lmdb.CopyCompact
+	}
+	return 0
+}
+
+// Backup writes a consistent copy of the environment to destDir, creating the
+// directory if needed. The copy can be opened by New like the original. It
+// runs within a read transaction, so reads and writes continue while it is
+// made and the copy reflects the data committed when it started.
+//
+// If destDir holds files Backup returns an error wrapping ErrDirNotEmpty,
+// unless force is set in which case an environment found there is replaced
+// and other files are left alone.
+func (db *DB) Backup(destDir string, force bool) error {
+	return db.backupDir(destDir, force, 0)
+}
+
+// BackupCompact is like Backup but omits free pages and renumbers the live
+// ones, so the copy is only as large as the data it holds. It is slower than
+// Backup and is the way to reclaim the space of a data file grown by deletes.
+func (db *DB) BackupCompact(destDir string, force bool) error {
+	return db.backupDir(destDir, force, lmdb.CopyCompact)
+}
+
+// backupDir copies the environment to destDir with the mdb_env_copy2 flags.
+func (db *DB) backupDir(destDir string, force bool, flags uint) error {
+	if err := db.acquire(&db.active); err != nil {
+		return err
+	}
+	defer db.release(&db.active)
+	if err := prepareBackupDir(destDir, force); err != nil {
+		return err
+	}
+	defer db.reportProgress(func() int64 { return statSize(filepath.Join(destDir, "data.mdb")) })()
+	return db.env.CopyFlag(destDir, flags)
+}
+
+// prepareBackupDir creates destDir, or checks that it is empty, and with force
+// removes the files of an environment in it. LMDB refuses to copy over an
+// existing data file.
+func prepareBackupDir(destDir string, force bool) error {
+	if err := os.MkdirAll(destDir, dirMode); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if !force {
+		return fmt.Errorf("%w: %q", ErrDirNotEmpty, destDir)
+	}
+	for _, name := range []string{"data.mdb", "lock.mdb"} {
+		if err := os.Remove(filepath.Join(destDir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package wrap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_CopyToWriter(t *testing.T) {
+	db := newTestDB(t, "users")
+	for i := 0; i < 100; i++ {
+		mustWrite(t, db, "users", fmt.Sprintf("user:%03d", i))
+	}
+
+	for _, compact := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := db.CopyToWriter(&buf, compact); err != nil {
+			t.Fatalf("compact=%v: %v", compact, err)
+		}
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "data.mdb"), buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		restored := openCopy(t, dir)
+		if n, err := restored.Count("users"); err != nil || n != 100 {
+			t.Errorf("compact=%v: restored count %d %v", compact, n, err)
+		}
+		if v, err := restored.Read("users", []byte("user:042")); err != nil || string(v) != "v:user:042" {
+			t.Errorf("compact=%v: restored value %q %v", compact, v, err)
+		}
+	}
+}
+
+func TestDB_CopyToWriter_file(t *testing.T) {
+	db := newTestDB(t, "users")
+	mustWrite(t, db, "users", "a", "b")
+
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "data.mdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CopyToWriter(f, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := openCopy(t, dir).Count("users"); err != nil || n != 2 {
+		t.Errorf("restored count %d %v", n, err)
+	}
+}
+
+// openCopy opens the users database of a backup restored into dir.
+func openCopy(t *testing.T, dir string) *DB {
+	t.Helper()
+	db, _, err := New(dir, []string{"users"})
+	if err != nil {
+		t.Fatalf("open backup: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestDB_Backup(t *testing.T) {
+	db := newTestDB(t, "users")
+	for i := 0; i < 100; i++ {
+		mustWrite(t, db, "users", fmt.Sprintf("user:%03d", i))
+	}
+
+	// keep writers busy while the copy is made
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := db.Write("users", []byte(fmt.Sprintf("load:%d:%d", w, i)), []byte("x")); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	dir := filepath.Join(t.TempDir(), "backup")
+	err := db.Backup(dir, false)
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	if err := env.SetMaxDBs(MaxNamedDBs); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Open(dir, lmdb.Readonly, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = env.View(func(txn *lmdb.Txn) error {
+		dbi, err := txn.OpenDBI("users", 0)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 100; i++ {
+			k := fmt.Sprintf("user:%03d", i)
+			v, err := txn.Get(dbi, []byte(k))
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			if string(v) != "v:"+k {
+				t.Errorf("%s: %q", k, v)
+			}
+		}
+		return nil
+	})
+	env.Close()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err := db.Backup(dir, false); !errors.Is(err, ErrDirNotEmpty) {
+		t.Errorf("existing backup: %v", err)
+	}
+	mustWrite(t, db, "users", "late")
+	if err := db.Backup(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openCopy(t, dir).Read("users", []byte("late")); err != nil {
+		t.Errorf("forced backup: %v", err)
+	}
+}
+
+func TestDB_BackupCompact(t *testing.T) {
+	db := newTestDB(t, "users")
+	val := bytes.Repeat([]byte("x"), 1000)
+	for i := 0; i < 2000; i++ {
+		if err := db.Write("users", []byte(fmt.Sprintf("user:%04d", i)), val); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2000; i++ {
+		if i%20 == 0 {
+			continue
+		}
+		if err := db.Delete("users", []byte(fmt.Sprintf("user:%04d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plain := filepath.Join(t.TempDir(), "plain")
+	if err := db.Backup(plain, false); err != nil {
+		t.Fatal(err)
+	}
+	compact := filepath.Join(t.TempDir(), "compact")
+	if err := db.BackupCompact(compact, false); err != nil {
+		t.Fatal(err)
+	}
+	size := func(dir string) int64 {
+		fi, err := os.Stat(filepath.Join(dir, "data.mdb"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Size()
+	}
+	if p, c := size(plain), size(compact); c*4 > p {
+		t.Errorf("compact copy %d bytes, plain copy %d bytes", c, p)
+	}
+	if n, err := openCopy(t, compact).Count("users"); err != nil || n != 100 {
+		t.Errorf("compact copy: %d entries %v", n, err)
+	}
+	if err := db.BackupCompact(compact, false); !errors.Is(err, ErrDirNotEmpty) {
+		t.Errorf("existing backup: %v", err)
+	}
+}
+
+// failWriter accepts n bytes and then fails.
+type failWriter struct {
+	n int
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errWriteFailed
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestDB_CopyToWriter_error(t *testing.T) {
+	db := newTestDB(t, "users")
+	for i := 0; i < 1000; i++ {
+		mustWrite(t, db, "users", fmt.Sprintf("user:%04d", i))
+	}
+	for _, compact := range []bool{false, true} {
+		if err := db.CopyToWriter(&failWriter{n: 4096}, compact); err != errWriteFailed {
+			t.Errorf("compact=%v: %v", compact, err)
+		}
+	}
+
+	// the environment is still usable
+	mustWrite(t, db, "users", "late")
+	var buf bytes.Buffer
+	if err := db.CopyToWriter(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() == 0 {
+		t.Errorf("empty backup")
+	}
+}
+
+func TestDB_BackupTo(t *testing.T) {
+	db := newTestDB(t, "users")
+	mustWrite(t, db, "users", "a", "b")
+
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "data.mdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.BackupTo(f, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := openCopy(t, dir).Count("users"); err != nil || n != 2 {
+		t.Errorf("restored count %d %v", n, err)
+	}
+
+	full, err := os.OpenFile("/dev/full", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skipf("no /dev/full: %v", err)
+	}
+	defer full.Close()
+	if err := db.BackupTo(full, false); !lmdb.IsErrnoSys(err, syscall.ENOSPC) {
+		t.Errorf("full device: %v", err)
+	}
+	mustWrite(t, db, "users", "c")
+}
+
+func TestDB_backupProgress(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	var last, total int64
+	var returned, late bool
+	progress := func(written, t int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		late = late || returned
+		calls++
+		last, total = written, t
+	}
+	db, _, err := New(t.TempDir(), []string{"users"}, WithBackupProgress(time.Millisecond, progress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	val := bytes.Repeat([]byte("x"), 4000)
+	for i := 0; i < 1000; i++ {
+		if err := db.Write("users", []byte(fmt.Sprintf("user:%04d", i)), val); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a slow writer keeps the copy running for several intervals
+	w := writerFunc(func(p []byte) (int, error) {
+		time.Sleep(time.Millisecond)
+		return len(p), nil
+	})
+	if err := db.CopyToWriter(w, false); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	returned = true
+	if calls == 0 || last <= 0 || total < last {
+		t.Errorf("progress: %d calls, last %d of %d", calls, last, total)
+	}
+	mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	if late {
+		t.Errorf("progress reported after return")
+	}
+	mu.Unlock()
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestDB_CopyToWriterContext(t *testing.T) {
+	db := newTestDB(t, "users")
+	for i := 0; i < 1000; i++ {
+		mustWrite(t, db, "users", fmt.Sprintf("user:%04d", i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := writerFunc(func(p []byte) (int, error) {
+		cancel()
+		time.Sleep(time.Millisecond)
+		return len(p), nil
+	})
+	if err := db.CopyToWriterContext(ctx, w, false); err != context.Canceled {
+		t.Errorf("canceled copy: %v", err)
+	}
+	if err := db.CopyToWriterContext(ctx, &bytes.Buffer{}, false); err != context.Canceled {
+		t.Errorf("canceled before copy: %v", err)
+	}
+
+	// the environment is still usable
+	mustWrite(t, db, "users", "late")
+	if err := db.CopyToWriterContext(context.Background(), &bytes.Buffer{}, true); err != nil {
+		t.Error(err)
+	}
+}
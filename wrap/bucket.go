@@ -0,0 +1,102 @@
+package wrap
+
+import "github.com/Data-Corruption/lmdb-go/lmdb"
+
+// Bucket is a handle bound to a single named database. A Bucket is safe for
+// concurrent use and fails with ErrDBClosed after its DB is closed, like the
+// DB methods it mirrors. It looks up the database handle on every call, so it
+// keeps working after Compact reopens the environment, and fails with
+// ErrDbNameNotFound once its database is dropped or renamed.
+type Bucket struct {
+	db   *DB
+	name string
+}
+
+// Bucket returns a Bucket for the named database.
+func (db *DB) Bucket(name string) (*Bucket, error) {
+	if _, err := db.getDBI(name); err != nil {
+		return nil, err
+	}
+	return &Bucket{db: db, name: name}, nil
+}
+
+// Name returns the name of the bucket's database.
+func (b *Bucket) Name() string {
+	return b.name
+}
+
+// DBI returns the current handle of the bucket's database.
+func (b *Bucket) DBI() lmdb.DBI {
+	dbi, _ := b.db.lookup(b.name)
+	return dbi
+}
+
+// Read retrieves a value from the bucket.
+func (b *Bucket) Read(key []byte) (val []byte, err error) {
+	err = b.db.observeRead(b.name, func() error {
+		if len(key) == 0 {
+			return ErrEmptyKey
+		}
+		dbi, err := b.db.getDBI(b.name)
+		if err != nil {
+			return err
+		}
+		if val, err = b.db.get(b.name, dbi, key); err == nil {
+			val, err = b.db.decodeValue(b.name, key, val)
+		}
+		return err
+	})
+	return val, err
+}
+
+// Has reports whether key is present in the bucket.
+func (b *Bucket) Has(key []byte) (bool, error) {
+	_, err := b.Read(key)
+	if lmdb.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Write inserts a key/value pair into the bucket.
+func (b *Bucket) Write(key, value []byte) error {
+	return b.db.observeWrite(b.name, func() error {
+		if len(key) == 0 {
+			return ErrEmptyKey
+		}
+		dbi, err := b.db.getDBI(b.name)
+		if err != nil {
+			return err
+		}
+		return b.db.put(b.name, dbi, key, value)
+	})
+}
+
+// Delete removes a key/value pair from the bucket.
+func (b *Bucket) Delete(key []byte) error {
+	return b.db.observeWrite(b.name, func() error {
+		if len(key) == 0 {
+			return ErrEmptyKey
+		}
+		dbi, err := b.db.getDBI(b.name)
+		if err != nil {
+			return err
+		}
+		return b.db.del(b.name, dbi, key)
+	})
+}
+
+// Scan is like DB.Scan for the bucket's database.
+func (b *Bucket) Scan(prefix []byte, opts ScanOptions) ([]KV, error) {
+	return b.db.collect(b.name, prefix, prefixEnd(prefix), opts)
+}
+
+// Range is like DB.Range for the bucket's database.
+func (b *Bucket) Range(start, end []byte, opts ScanOptions) ([]KV, error) {
+	return b.db.collect(b.name, start, end, opts)
+}
+
+// ForEach is like DB.ForEach for the bucket's database.
+func (b *Bucket) ForEach(prefix []byte, opts ScanOptions, fn func(key, val []byte) error) error {
+	return b.db.ForEach(b.name, prefix, opts, fn)
+}
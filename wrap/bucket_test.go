@@ -0,0 +1,82 @@
+package wrap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_Bucket(t *testing.T) {
+	db := newTestDB(t, "a", "b")
+	if _, err := db.Bucket("nope"); err != ErrDbNameNotFound {
+		t.Errorf("unknown bucket: %v", err)
+	}
+	b, err := db.Bucket("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Name() != "a" {
+		t.Errorf("name: %q", b.Name())
+	}
+
+	if err := b.Write([]byte("k1"), []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Write(nil, []byte("v")); err != ErrEmptyKey {
+		t.Errorf("empty key: %v", err)
+	}
+	val, err := b.Read([]byte("k1"))
+	if err != nil || string(val) != "v1" {
+		t.Errorf("read: %q %v", val, err)
+	}
+	// the bucket writes to its own database only
+	if _, err := db.Read("b", []byte("k1")); !lmdb.IsNotFound(err) {
+		t.Errorf("other database: %v", err)
+	}
+	kvs, err := b.Scan([]byte("k"), ScanOptions{})
+	if err != nil || len(kvs) != 1 {
+		t.Errorf("scan: %q %v", kvs, err)
+	}
+	if err := b.Delete([]byte("k1")); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := b.Has([]byte("k1")); ok || err != nil {
+		t.Errorf("has after delete: %v %v", ok, err)
+	}
+
+	db.Close()
+	if err := b.Write([]byte("k"), []byte("v")); err != ErrDBClosed {
+		t.Errorf("write after close: %v", err)
+	}
+	if _, err := b.Read([]byte("k")); err != ErrDBClosed {
+		t.Errorf("read after close: %v", err)
+	}
+}
+
+func TestBucket_concurrent(t *testing.T) {
+	db := newTestDB(t, "a")
+	b, err := db.Bucket("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := []byte{byte(i)}
+			if err := b.Write(key, key); err != nil {
+				t.Error(err)
+			}
+			if _, err := b.Read(key); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	n, err := db.Count("a")
+	if err != nil || n != 8 {
+		t.Errorf("count: %d %v", n, err)
+	}
+}
@@ -0,0 +1,128 @@
+package wrap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+	"github.com/Data-Corruption/lmdb-go/wrap/keys"
+)
+
+// changelogSeqName is the internal sequence numbering changelog records.
+const changelogSeqName = "\x00changelog"
+
+// trimBatchSize is the number of changelog records TrimChangelog deletes per
+// transaction.
+const trimBatchSize = 1000
+
+// errBadChange is returned when a changelog record cannot be decoded.
+var errBadChange = errors.New("malformed changelog record")
+
+// Change is a mutation recorded in the changelog, see DBOptions.Changelog.
+type Change struct {
+	Seq    uint64
+	Op     Op
+	DBName string
+	Key    []byte
+
+	// Value is the value stored by an OpPut, including the header in a
+	// Versioned database. For an OpDelete it is nil, except for RemoveValue
+	// where it holds the removed member.
+	Value []byte
+}
+
+// ReadChangelog returns up to limit changes with a sequence number greater
+// than afterSeq in commit order, zero or less means no limit. Pass the Seq of
+// the last change read to continue tailing, or zero to start at the oldest
+// change still in the log.
+func (db *DB) ReadChangelog(afterSeq uint64, limit int) (changes []Change, err error) {
+	err = db.view(func(txn *lmdb.Txn) error {
+		cur, err := txn.OpenCursor(db.changelog)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		k, v, err := cur.Get(keys.AppendUint64(nil, afterSeq+1), nil, lmdb.SetRange)
+		for ; err == nil && (limit <= 0 || len(changes) < limit); k, v, err = cur.Get(nil, nil, lmdb.Next) {
+			c, err := decodeChange(k, v)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, c)
+		}
+		if lmdb.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// TrimChangelog deletes every change with a sequence number up to and
+// including uptoSeq, typically once all followers have applied them. Sequence
+// numbers are never reused after a trim.
+func (db *DB) TrimChangelog(uptoSeq uint64) error {
+	end := keys.AppendUint64(nil, uptoSeq)
+	for {
+		n := 0
+		err := db.update(func(txn *lmdb.Txn) error {
+			cur, err := txn.OpenCursor(db.changelog)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+			k, _, err := cur.Get(nil, nil, lmdb.First)
+			for ; err == nil && n < trimBatchSize && bytes.Compare(k, end) <= 0; n++ {
+				if err = cur.Del(0); err != nil {
+					return err
+				}
+				k, _, err = cur.Get(nil, nil, lmdb.Next)
+			}
+			if lmdb.IsNotFound(err) {
+				return nil
+			}
+			return err
+		})
+		if err != nil || n < trimBatchSize {
+			return err
+		}
+	}
+}
+
+// logChange appends a change to the changelog in txn if dbName has the
+// changelog enabled.
+func (db *DB) logChange(txn *lmdb.Txn, op Op, dbName string, key, val []byte) error {
+	if !db.opts.DBs[dbName].Changelog {
+		return nil
+	}
+	seq, err := db.incSequence(txn, changelogSeqName)
+	if err != nil {
+		return err
+	}
+	rec := append([]byte{byte(op)}, keys.AppendString(nil, dbName)...)
+	rec = append(keys.AppendBytes(rec, key), val...)
+	return txn.Put(db.changelog, keys.AppendUint64(nil, seq), rec, lmdb.Append)
+}
+
+func decodeChange(k, v []byte) (Change, error) {
+	if len(k) != 8 || len(v) == 0 {
+		return Change{}, errBadChange
+	}
+	c := Change{Seq: binary.BigEndian.Uint64(k), Op: Op(v[0])}
+	var rest []byte
+	var err error
+	if c.DBName, rest, err = keys.String(v[1:]); err != nil {
+		return Change{}, errBadChange
+	}
+	if c.Key, rest, err = keys.Bytes(rest); err != nil {
+		return Change{}, errBadChange
+	}
+	if len(rest) > 0 {
+		c.Value = rest
+	}
+	return c, nil
+}
@@ -0,0 +1,79 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDB_changelog(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"users", "cache"}, WithDBOptions("users", DBOptions{Changelog: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mustWrite(t, db, "users", "a")
+	mustWrite(t, db, "cache", "ignored")
+	if err := db.Delete("users", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	err = db.UpdateNamed(func(tx *NamedTxn) error {
+		if err := tx.Put("users", []byte("b"), []byte("vb"), 0); err != nil {
+			return err
+		}
+		return tx.Put("cache", []byte("c"), []byte("vc"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errAbort := errors.New("abort")
+	err = db.UpdateNamed(func(tx *NamedTxn) error {
+		if err := tx.Del("users", []byte("b")); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("aborted update: %v", err)
+	}
+	err = db.Upsert("users", []byte("b"), func([]byte) ([]byte, error) { return []byte("vb2"), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := db.ReadChangelog(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1 put users a v:a", "2 delete users a ", "3 put users b vb", "4 put users b vb2"}
+	if got := formatChanges(changes); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("changes: %q (!= %q)", got, want)
+	}
+	if changes, _ := db.ReadChangelog(1, 2); fmt.Sprint(formatChanges(changes)) != fmt.Sprint(want[1:3]) {
+		t.Errorf("after 1, limit 2: %q", formatChanges(changes))
+	}
+
+	if err := db.TrimChangelog(3); err != nil {
+		t.Fatal(err)
+	}
+	if changes, _ := db.ReadChangelog(0, 0); fmt.Sprint(formatChanges(changes)) != fmt.Sprint(want[3:]) {
+		t.Errorf("after trim: %q", formatChanges(changes))
+	}
+	if err := db.TrimChangelog(10); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, db, "users", "c")
+	changes, _ = db.ReadChangelog(0, 0)
+	if len(changes) != 1 || changes[0].Seq != 5 {
+		t.Errorf("seq after trimming everything: %q", formatChanges(changes))
+	}
+}
+
+func formatChanges(changes []Change) []string {
+	var s []string
+	for _, c := range changes {
+		s = append(s, fmt.Sprintf("%d %v %s %s %s", c.Seq, c.Op, c.DBName, c.Key, c.Value))
+	}
+	return s
+}
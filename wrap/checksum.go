@@ -0,0 +1,186 @@
+package wrap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+var (
+	// ErrChecksumMismatch is matched by the ChecksumError returned when a
+	// value read from a checksummed database does not match its checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrNotChecksummed is returned by AddChecksums when the database was
+	// opened without DBOptions.Checksum.
+	ErrNotChecksummed = errors.New("database is not checksummed")
+)
+
+// ChecksumKind selects the checksum DBOptions.Checksum stores with every
+// value.
+type ChecksumKind uint8
+
+const (
+	ChecksumNone   ChecksumKind = iota // values are stored as written
+	ChecksumCRC32C                     // 4-byte CRC-32 (Castagnoli) trailer
+)
+
+// String returns the name of the checksum.
+func (c ChecksumKind) String() string {
+	switch c {
+	case ChecksumNone:
+		return "none"
+	case ChecksumCRC32C:
+		return "crc32c"
+	}
+	return "unknown"
+}
+
+// checksumLen is the size of the trailer behind checksummed values.
+const checksumLen = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumError reports a value whose stored checksum does not match its
+// contents.
+type ChecksumError struct {
+	DBName string
+	Key    []byte
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s: key %q: checksum mismatch", e.DBName, e.Key)
+}
+
+// Unwrap returns ErrChecksumMismatch.
+func (e *ChecksumError) Unwrap() error {
+	return ErrChecksumMismatch
+}
+
+// AddChecksums migrates an existing database to DBOptions.Checksum by
+// appending a checksum to every value that does not already end with a valid
+// one, and returns the number of values rewritten. The DB must have been
+// opened with Checksum set for dbName, otherwise the returned error wraps
+// ErrNotChecksummed. Reads of values not yet migrated fail with a
+// ChecksumError, so run it right after enabling the option.
+//
+// Values are rewritten in transactions of 10,000 records and the ones
+// committed before an error stay migrated, so an interrupted migration can
+// simply be run again. A plain value ending by chance with the checksum of
+// the bytes before it, a one in four billion event, is left as is and later
+// read without its last four bytes. The rewrite does not change the values
+// read back, so it is not reported to subscribers nor recorded in the
+// changelog or the modification times.
+func (db *DB) AddChecksums(dbName string) (int64, error) {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return 0, err
+	}
+	if !db.checksummed(dbName) {
+		return 0, fmt.Errorf("%w: %q", ErrNotChecksummed, dbName)
+	}
+	var n int64
+	var last []byte
+	for done := false; !done; {
+		err := db.observeWrite(dbName, func() error {
+			return db.updateEvents(dbName, func(txn *lmdb.Txn) (err error) {
+				if err := db.refreshDBI(dbName, &dbi); err != nil {
+					return err
+				}
+				var count int64
+				last, count, done, err = addChecksums(txn, dbi, last, importChunkSize)
+				if err == nil {
+					n += count
+				}
+				return err
+			}, nil)
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// addChecksums seals the values of up to limit records of dbi following the
+// key after, or from the first key if after is nil. It returns the last key
+// visited, the number of values rewritten, and whether the end of dbi was
+// reached.
+func addChecksums(txn *lmdb.Txn, dbi lmdb.DBI, after []byte, limit int) (last []byte, n int64, done bool, err error) {
+	txn.RawRead = true
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	defer cur.Close()
+	var k, v []byte
+	if after == nil {
+		k, v, err = cur.Get(nil, nil, lmdb.First)
+	} else {
+		k, v, err = cur.Get(after, nil, lmdb.SetRange)
+		if err == nil && bytes.Equal(k, after) {
+			k, v, err = cur.Get(nil, nil, lmdb.Next)
+		}
+	}
+	last = after
+	for i := 0; i < limit; i++ {
+		if lmdb.IsNotFound(err) {
+			return last, n, true, nil
+		}
+		if err != nil {
+			return nil, 0, false, err
+		}
+		// k points into the memory map, which the write may change
+		last = copyBytes(k)
+		if !validChecksum(last, v) {
+			if err := cur.PutCurrent(appendChecksum(copyBytes(v), last)); err != nil {
+				return nil, 0, false, err
+			}
+			n++
+		}
+		k, v, err = cur.Get(nil, nil, lmdb.Next)
+	}
+	return last, n, false, nil
+}
+
+// checksummed reports whether the values of dbName carry a checksum.
+func (db *DB) checksummed(dbName string) bool {
+	return db.opts.DBs[dbName].Checksum != ChecksumNone
+}
+
+// unseal verifies the checksum of a value stored in dbName and returns the
+// value without it. The result shares the memory of stored.
+func (db *DB) unseal(dbName string, key, stored []byte) ([]byte, error) {
+	if !db.checksummed(dbName) {
+		return stored, nil
+	}
+	if !validChecksum(key, stored) {
+		return nil, &ChecksumError{DBName: dbName, Key: copyBytes(key)}
+	}
+	n := len(stored) - checksumLen
+	return stored[:n:n], nil
+}
+
+// checksum returns the checksum of value stored under key. The key is
+// covered so a value written under the wrong key is detected too.
+func checksum(key, value []byte) uint32 {
+	return crc32.Update(crc32.Checksum(key, castagnoli), castagnoli, value)
+}
+
+// appendChecksum appends the checksum of value stored under key to value.
+func appendChecksum(value, key []byte) []byte {
+	var b [checksumLen]byte
+	binary.BigEndian.PutUint32(b[:], checksum(key, value))
+	return append(value, b[:]...)
+}
+
+// validChecksum reports whether stored ends with the checksum of the bytes
+// before it.
+func validChecksum(key, stored []byte) bool {
+	n := len(stored) - checksumLen
+	return n >= 0 && binary.BigEndian.Uint32(stored[n:]) == checksum(key, stored[:n])
+}
@@ -0,0 +1,179 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func newChecksumDB(t *testing.T, dir string) *DB {
+	t.Helper()
+	db, _, err := New(dir, []string{"users", "versions"},
+		WithDBOptions("users", DBOptions{Checksum: ChecksumCRC32C}),
+		WithDBOptions("versions", DBOptions{Checksum: ChecksumCRC32C, Versioned: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDB_Checksum(t *testing.T) {
+	db := newChecksumDB(t, t.TempDir())
+	defer db.Close()
+	mustWrite(t, db, "users", "a", "b")
+	if err := db.Append("users", []byte("c"), []byte("v:c")); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := db.Read("users", []byte("a")); err != nil || string(v) != "v:a" {
+		t.Errorf("Read: %q, %v", v, err)
+	}
+	err := db.View(func(txn *lmdb.Txn) error {
+		dbi, _ := db.DBI("users")
+		v, err := txn.Get(dbi, []byte("a"))
+		if err == nil && len(v) != len("v:a")+checksumLen {
+			t.Errorf("stored %q", v)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := db.Scan("users", nil, ScanOptions{})
+	if err != nil || len(kvs) != 3 || string(kvs[2].Value) != "v:c" {
+		t.Errorf("Scan: %q, %v", kvs, err)
+	}
+	it, err := db.NewIterator("users", IterOpts{RawRead: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for it.Next() {
+		if want := "v:" + string(it.Key()); string(it.Value()) != want {
+			t.Errorf("Iterator: %q = %q", it.Key(), it.Value())
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Error(err)
+	}
+	it.Close()
+	err = db.ViewNamed(func(v *NamedView) error {
+		val, err := v.Get("users", []byte("b"))
+		if err == nil && string(val) != "v:b" {
+			t.Errorf("NamedView.Get: %q", val)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := db.CompareAndSwap("users", []byte("a"), []byte("v:a"), []byte("new")); err != nil || !ok {
+		t.Errorf("CompareAndSwap: %v, %v", ok, err)
+	}
+	if n, err := db.IntIncrement("users", []byte("n"), 2); err != nil || n != 2 {
+		t.Errorf("IntIncrement: %d, %v", n, err)
+	}
+
+	ver, err := db.WriteVersioned("versions", []byte("k"), []byte("one"), 0)
+	if err != nil || ver != 1 {
+		t.Fatalf("WriteVersioned: %d, %v", ver, err)
+	}
+	if err := db.Write("versions", []byte("k"), []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	if v, ver, err := db.ReadVersioned("versions", []byte("k")); err != nil || string(v) != "two" || ver != 2 {
+		t.Errorf("ReadVersioned: %q, %d, %v", v, ver, err)
+	}
+
+	r, err := db.Verify(VerifyOpts{})
+	if err != nil || !r.OK() {
+		t.Errorf("Verify: %+v, %v", r, err)
+	}
+
+	_, _, err = New(t.TempDir(), []string{"tags"}, WithDBOptions("tags", DBOptions{Checksum: ChecksumCRC32C, DupSort: true}))
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Checksum with DupSort: %v", err)
+	}
+}
+
+func TestDB_Checksum_corrupt(t *testing.T) {
+	dir := t.TempDir()
+	db := newChecksumDB(t, dir)
+	mustWrite(t, db, "users", "alpha", "beta")
+	db.Close()
+
+	// flip a value on disk, LMDB has no page checksums to notice
+	path := filepath.Join(dir, "data.mdb")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.ReplaceAll(data, []byte("v:alpha"), []byte("v:alphA"))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	db = newChecksumDB(t, dir)
+	defer db.Close()
+	_, err = db.Read("users", []byte("alpha"))
+	var cerr *ChecksumError
+	if !errors.Is(err, ErrChecksumMismatch) || !errors.As(err, &cerr) || string(cerr.Key) != "alpha" || cerr.DBName != "users" {
+		t.Errorf("Read: %v", err)
+	}
+	if v, err := db.Read("users", []byte("beta")); err != nil || string(v) != "v:beta" {
+		t.Errorf("Read beta: %q, %v", v, err)
+	}
+	if _, err := db.Scan("users", nil, ScanOptions{}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Scan: %v", err)
+	}
+	r, err := db.Verify(VerifyOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := r.DBs[0]
+	if r.OK() || d.Failed != 1 || string(d.Issues[0].Key) != "alpha" || d.Issues[0].Problem != "checksum mismatch" {
+		t.Errorf("Verify: %+v", r)
+	}
+}
+
+func TestDB_AddChecksums(t *testing.T) {
+	dir := t.TempDir()
+	db, _, err := New(dir, []string{"users", "versions"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, db, "users", "a", "b", "c")
+	if _, err := db.AddChecksums("users"); !errors.Is(err, ErrNotChecksummed) {
+		t.Errorf("plain db: %v", err)
+	}
+	db.Close()
+
+	db = newChecksumDB(t, dir)
+	defer db.Close()
+	if _, err := db.Read("users", []byte("a")); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Read before migration: %v", err)
+	}
+	// already migrated values are left alone
+	if err := db.Write("users", []byte("b"), []byte("v:b")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.AddChecksums("users"); err != nil || n != 2 {
+		t.Fatalf("AddChecksums: %d, %v", n, err)
+	}
+	kvs, err := db.Scan("users", nil, ScanOptions{})
+	if err != nil || len(kvs) != 3 {
+		t.Fatalf("Scan: %q, %v", kvs, err)
+	}
+	for _, kv := range kvs {
+		if string(kv.Value) != "v:"+string(kv.Key) {
+			t.Errorf("%q = %q", kv.Key, kv.Value)
+		}
+	}
+	if n, err := db.AddChecksums("users"); err != nil || n != 0 {
+		t.Errorf("second run: %d, %v", n, err)
+	}
+}
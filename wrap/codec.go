@@ -0,0 +1,58 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoCodec is returned by ReadAny and WriteAny when a database without a
+// Codec is used with a type other than []byte.
+var ErrNoCodec = errors.New("database has no codec")
+
+// Codec converts values to and from their stored form. It is bound to a
+// database with DBOptions.Codec and used by ReadAny and WriteAny.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// WriteAny encodes v with the database's Codec and stores it under key.
+// Without a Codec v must be a []byte.
+func (db *DB) WriteAny(dbName string, key []byte, v any) error {
+	var data []byte
+	if c := db.codec(dbName); c != nil {
+		var err error
+		if data, err = c.Marshal(v); err != nil {
+			return err
+		}
+	} else if b, ok := v.([]byte); ok {
+		data = b
+	} else {
+		return fmt.Errorf("%w: %q: cannot write %T", ErrNoCodec, dbName, v)
+	}
+	return db.Write(dbName, key, data)
+}
+
+// ReadAny reads the value stored under key and decodes it into out with the
+// database's Codec. Without a Codec out must be a *[]byte.
+func (db *DB) ReadAny(dbName string, key []byte, out any) error {
+	c := db.codec(dbName)
+	p, ok := out.(*[]byte)
+	if c == nil && !ok {
+		return fmt.Errorf("%w: %q: cannot read into %T", ErrNoCodec, dbName, out)
+	}
+	data, err := db.Read(dbName, key)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		*p = data
+		return nil
+	}
+	return c.Unmarshal(data, out)
+}
+
+// codec returns the Codec bound to the named database, or nil.
+func (db *DB) codec(dbName string) Codec {
+	return db.opts.DBs[dbName].Codec
+}
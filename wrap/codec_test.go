@@ -0,0 +1,58 @@
+package wrap
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+func TestDB_codec(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"json", "raw"}, WithDBOptions("json", DBOptions{Codec: jsonCodec{}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	in := testUser{Name: "ann", Age: 30}
+	if err := db.WriteAny("json", []byte("u"), in); err != nil {
+		t.Fatal(err)
+	}
+	var out testUser
+	if err := db.ReadAny("json", []byte("u"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("read: %+v", out)
+	}
+	raw, _ := db.Read("json", []byte("u"))
+	if string(raw) != `{"name":"ann","age":30}` {
+		t.Errorf("stored: %s", raw)
+	}
+
+	// without a codec only []byte is accepted
+	if err := db.WriteAny("raw", []byte("k"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	if err := db.ReadAny("raw", []byte("k"), &b); err != nil || string(b) != "v" {
+		t.Errorf("raw read: %q %v", b, err)
+	}
+	if err := db.WriteAny("raw", []byte("k"), in); !errors.Is(err, ErrNoCodec) {
+		t.Errorf("raw write struct: %v", err)
+	}
+	if err := db.ReadAny("raw", []byte("k"), &out); !errors.Is(err, ErrNoCodec) {
+		t.Errorf("raw read struct: %v", err)
+	}
+}
+
+func TestNew_dbOptionsUnknownName(t *testing.T) {
+	_, _, err := New(t.TempDir(), []string{"a"}, WithDBOptions("b", DBOptions{}))
+	if err != ErrDbNameNotFound {
+		t.Errorf("unknown name: %v", err)
+	}
+}
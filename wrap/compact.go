@@ -0,0 +1,181 @@
+package wrap
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// compactDirName is the directory inside the environment directory holding
+// the copy made by Compact.
+const compactDirName = "compact.tmp"
+
+// Compact rewrites the data file without its free pages, shrinking a file
+// grown by deletes to the size of the data it holds. It makes a compacting
+// copy in a directory inside the environment directory, verifies it with
+// VerifyBackup, closes the environment, renames the copy over data.mdb, and
+// reopens the environment with every registered database.
+//
+// Writes are paused while the copy is made, so none can be lost, and fail
+// with ErrCompacting. Reads continue until the data file is replaced, then
+// every operation fails with ErrCompacting for the short time the environment
+// is closed. With Options.CompactWait operations wait instead. Compact waits
+// for operations in progress and up to Options.CloseTimeout for open
+// snapshots and iterators, and returns ErrHandlesOpen if they remain open,
+// leaving the environment untouched.
+//
+// The original data file is only replaced by a rename of a complete, synced
+// copy, so an interrupted Compact leaves it intact. The copy left by a crash
+// is removed by the next Compact or New. The environment must not be opened
+// by another process, which would keep using the replaced file.
+//
+// Database handles change when the environment is reopened, handles obtained
+// from DBI or GetDBis before Compact must be looked up again. Bucket and
+// Queue look them up on every call.
+func (db *DB) Compact() error {
+	db.compactMu.Lock()
+	defer db.compactMu.Unlock()
+	tmp := filepath.Join(db.path, compactDirName)
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := db.pauseWrites(); err != nil {
+		return err
+	}
+	defer db.resume(&db.compacting)
+	if err := db.backupDir(tmp, false, lmdb.CopyCompact); err != nil {
+		return err
+	}
+	if _, err := VerifyBackup(tmp, nil); err != nil {
+		return err
+	}
+	if err := syncPath(filepath.Join(tmp, "data.mdb")); err != nil {
+		return err
+	}
+	return db.swap(filepath.Join(tmp, "data.mdb"))
+}
+
+// pauseWrites makes new writes wait or fail and waits for those in progress,
+// including writes whose callers gave up after Options.WriteTimeout.
+func (db *DB) pauseWrites() error {
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return ErrDBClosed
+	}
+	db.compacting = true
+	db.mu.Unlock()
+	for {
+		db.mu.Lock()
+		writes := db.writes
+		db.mu.Unlock()
+		if writes == 0 {
+			break
+		}
+		time.Sleep(closePollInterval)
+	}
+	// queues are FIFO, so every write queued earlier has finished once its
+	// writer reaches the barrier
+	for _, ops := range db.uOps {
+		res := make(chan error, 1)
+		ops <- &updateOp{res: res}
+		<-res
+	}
+	return nil
+}
+
+// resume clears flag, db.compacting or db.swapping, and wakes the operations
+// waiting for it.
+func (db *DB) resume(flag *bool) {
+	db.mu.Lock()
+	*flag = false
+	db.resumed.Broadcast()
+	db.mu.Unlock()
+}
+
+// swap replaces the data file of the environment with the file at src.
+func (db *DB) swap(src string) error {
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return ErrDBClosed
+	}
+	db.swapping = true
+	db.mu.Unlock()
+
+	deadline := time.Now().Add(db.opts.CloseTimeout)
+	for {
+		db.mu.Lock()
+		active, handles := db.active, db.handles
+		db.mu.Unlock()
+		if active == 0 && handles == 0 {
+			break
+		}
+		if active == 0 && !time.Now().Before(deadline) {
+			db.resume(&db.swapping)
+			return ErrHandlesOpen
+		}
+		time.Sleep(closePollInterval)
+	}
+
+	db.env.Close()
+	err := os.Rename(src, filepath.Join(db.path, "data.mdb"))
+	if err == nil {
+		err = syncPath(db.path)
+	}
+	// after a failed rename this reopens the original file
+	if rerr := db.reopen(); rerr != nil {
+		db.mu.Lock()
+		db.closed = true
+		db.mu.Unlock()
+		db.resume(&db.swapping)
+		return rerr
+	}
+	db.resume(&db.swapping)
+	return err
+}
+
+// reopen opens the environment again along with every registered database.
+func (db *DB) reopen() error {
+	env, err := openEnv(db.path)
+	if err != nil {
+		return err
+	}
+	dbs := db.GetDBis()
+	err = env.Update(func(txn *lmdb.Txn) error {
+		for name := range dbs {
+			dbi, err := txn.OpenDBI(name, lmdb.Create|db.opts.DBs[name].flags())
+			if err != nil {
+				return err
+			}
+			dbs[name] = dbi
+		}
+		return db.openInternal(txn)
+	})
+	if err != nil {
+		env.Close()
+		return err
+	}
+	db.env = env
+	db.dbsMu.Lock()
+	db.dbs = dbs
+	db.dbsMu.Unlock()
+	return nil
+}
+
+// syncPath flushes the file or directory at path to disk.
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
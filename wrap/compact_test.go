@@ -0,0 +1,252 @@
+package wrap
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDB_Compact(t *testing.T) {
+	dir := t.TempDir()
+	db, _, err := New(dir, []string{"users", "tags", "jobs"}, WithDBOptions("tags", DBOptions{DupSort: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	val := bytes.Repeat([]byte("x"), 1000)
+	for i := 0; i < 2000; i++ {
+		if err := db.Write("users", []byte(fmt.Sprintf("user:%04d", i)), val); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2000; i++ {
+		if i%20 == 0 {
+			continue
+		}
+		if err := db.Delete("users", []byte(fmt.Sprintf("user:%04d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range []string{"b", "a"} {
+		if err := db.AddValue("tags", []byte("k"), []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bucket, err := db.Bucket("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := NewQueue(db, "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Push([]byte("job")); err != nil {
+		t.Fatal(err)
+	}
+	id, err := db.NextSequence("ids")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := func() int64 {
+		fi, err := os.Stat(filepath.Join(dir, "data.mdb"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Size()
+	}
+	before := size()
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if after := size(); after*4 > before {
+		t.Errorf("compacted to %d bytes from %d bytes", after, before)
+	}
+	if _, err := os.Stat(filepath.Join(dir, compactDirName)); !os.IsNotExist(err) {
+		t.Errorf("temporary directory left: %v", err)
+	}
+
+	if n, err := db.Count("users"); err != nil || n != 100 {
+		t.Errorf("compacted: %d entries %v", n, err)
+	}
+	if v, err := bucket.Read([]byte("user:0020")); err != nil || !bytes.Equal(v, val) {
+		t.Errorf("bucket read: %v", err)
+	}
+	if err := bucket.Write([]byte("user:9999"), []byte("new")); err != nil {
+		t.Errorf("bucket write: %v", err)
+	}
+	members, err := db.Members("tags", []byte("k"), 0)
+	if err != nil || len(members) != 2 {
+		t.Errorf("dup members: %q %v", members, err)
+	}
+	if v, err := q.Pop(); err != nil || string(v) != "job" {
+		t.Errorf("queue pop: %q %v", v, err)
+	}
+	if next, err := db.NextSequence("ids"); err != nil || next != id+1 {
+		t.Errorf("sequence after compact: %d %v", next, err)
+	}
+	if err := db.HealthCheck(); err != nil {
+		t.Errorf("health: %v", err)
+	}
+	db.Close()
+
+	db, _, err = New(dir, []string{"users", "tags", "jobs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if n, _ := db.Count("users"); n != 101 {
+		t.Errorf("reopened: %d entries", n)
+	}
+}
+
+func TestDB_Compact_staleCopy(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, compactDirName)
+	writeStale := func() {
+		if err := os.MkdirAll(stale, dirMode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(stale, "data.mdb"), []byte("partial"), fileMode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeStale()
+	db, _, err := New(dir, []string{"users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("New kept the stale copy: %v", err)
+	}
+
+	mustWrite(t, db, "users", "a")
+	writeStale()
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read("users", []byte("a")); err != nil {
+		t.Errorf("read: %v", err)
+	}
+}
+
+func TestDB_Compact_handlesOpen(t *testing.T) {
+	db := newTestDB(t, "users")
+	mustWrite(t, db, "users", "a", "b")
+	it, err := db.NewIterator("users", IterOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Compact(); err != ErrHandlesOpen {
+		t.Errorf("compact with iterator: %v", err)
+	}
+	if !it.Next() || string(it.Key()) != "a" {
+		t.Errorf("iterator after failed compact: %v", it.Err())
+	}
+	it.Close()
+	mustWrite(t, db, "users", "c")
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := db.Count("users"); n != 3 {
+		t.Errorf("compacted: %d entries", n)
+	}
+}
+
+func TestDB_Compact_writesPaused(t *testing.T) {
+	var mu sync.Mutex
+	var writeErr, readErr error
+	var called bool
+	var db *DB
+	progress := func(written, total int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		if called {
+			return
+		}
+		called = true
+		writeErr = db.Write("users", []byte("late"), []byte("v"))
+		_, readErr = db.Read("users", []byte("user:0000"))
+	}
+	db, _, err := New(t.TempDir(), []string{"users"}, WithBackupProgress(time.Millisecond, progress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	val := bytes.Repeat([]byte("x"), 4000)
+	for i := 0; i < 1000; i++ {
+		if err := db.Write("users", []byte(fmt.Sprintf("user:%04d", i)), val); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !called {
+		t.Skip("copy finished before the first progress report")
+	}
+	if writeErr != ErrCompacting {
+		t.Errorf("write during copy: %v", writeErr)
+	}
+	if readErr != nil {
+		t.Errorf("read during copy: %v", readErr)
+	}
+}
+
+func TestDB_Compact_swapWindow(t *testing.T) {
+	for _, wait := range []bool{false, true} {
+		db, _, err := New(t.TempDir(), []string{"users"}, WithCompactWait(wait))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustWrite(t, db, "users", "a")
+
+		// hold the DB in the window where the data file is replaced
+		db.mu.Lock()
+		db.swapping = true
+		db.mu.Unlock()
+		done := make(chan error, 1)
+		go func() {
+			_, err := db.Read("users", []byte("a"))
+			done <- err
+		}()
+		if !wait {
+			if err := <-done; err != ErrCompacting {
+				t.Errorf("read during swap: %v", err)
+			}
+		} else {
+			select {
+			case err := <-done:
+				t.Errorf("read did not wait: %v", err)
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+		db.resume(&db.swapping)
+		if wait {
+			if err := <-done; err != nil {
+				t.Errorf("read after swap: %v", err)
+			}
+		}
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDB_Compact_closed(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if err := db.Compact(); err != ErrDBClosed {
+		t.Errorf("compact after close: %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package wrap
+
+import (
+	"bytes"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// DeleteIfValue deletes key only if its current value is exactly expected and
+// reports whether it was deleted. The comparison and the delete happen in one
+// transaction, which makes DeleteIfValue suitable for releasing a lock record
+// only while it still holds the caller's token. A missing key or a different
+// value are not errors, they return false.
+func (db *DB) DeleteIfValue(dbName string, key, expected []byte) (deleted bool, err error) {
+	deleted, _, err = db.CompareAndDelete(dbName, key, expected)
+	return deleted, err
+}
+
+// CompareAndDelete is like DeleteIfValue but also returns the value that was
+// found when it did not match expected, or nil if the key was missing.
+func (db *DB) CompareAndDelete(dbName string, key, expected []byte) (deleted bool, current []byte, err error) {
+	err = db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		events := &[]KeyEvent{}
+		return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			val, err := txn.Get(dbi, key)
+			if lmdb.IsNotFound(err) {
+				return nil
+			}
+			if err == nil {
+				val, err = db.decodeValue(dbName, key, val)
+			}
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(val, expected) {
+				current = val
+				return nil
+			}
+			if err := txn.Del(dbi, key, nil); err != nil {
+				return err
+			}
+			deleted = true
+			db.addEvent(events, OpDelete, dbName, key, nil)
+			return db.recordWrite(txn, OpDelete, dbName, key, nil)
+		}, events)
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return deleted, current, nil
+}
+
+// CompareAndSwap replaces the value of key with newValue only if its current
+// value is exactly expected and reports whether it did. A nil expected
+// matches only a missing key, so the swap creates it, and a nil newValue
+// deletes the key on a match. The comparison and the write happen in one
+// transaction, which makes CompareAndSwap suitable for optimistic locking. A
+// mismatch is not an error, it returns false.
+func (db *DB) CompareAndSwap(dbName string, key, expected, newValue []byte) (swapped bool, err error) {
+	err = db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		events := &[]KeyEvent{}
+		return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			val, err := txn.Get(dbi, key)
+			found := err == nil
+			if found {
+				val, err = db.decodeValue(dbName, key, val)
+			}
+			if err != nil && !lmdb.IsNotFound(err) {
+				return err
+			}
+			if found != (expected != nil) || !bytes.Equal(val, expected) {
+				return nil
+			}
+			swapped = true
+			if newValue == nil {
+				if !found {
+					return nil
+				}
+				if err := txn.Del(dbi, key, nil); err != nil {
+					return err
+				}
+				db.addEvent(events, OpDelete, dbName, key, nil)
+				return db.recordWrite(txn, OpDelete, dbName, key, nil)
+			}
+			stored, err := db.encodeValue(txn, dbi, dbName, key, newValue)
+			if err != nil {
+				return err
+			}
+			if err := txn.Put(dbi, key, stored, 0); err != nil {
+				return err
+			}
+			db.addEvent(events, OpPut, dbName, key, newValue)
+			return db.recordWrite(txn, OpPut, dbName, key, stored)
+		}, events)
+	})
+	if err != nil {
+		return false, err
+	}
+	return swapped, nil
+}
+
+// Upsert replaces the value of key with the result of fn, which is passed the
+// current value or nil if the key is missing. If fn returns a nil value the
+// key is deleted. The read, fn, and the write run in one write transaction, so
+// concurrent Upserts of a counter never lose an increment. If fn returns an
+// error the transaction aborts and Upsert returns the error.
+//
+// fn runs on the writer goroutine and blocks every other write while it runs,
+// so it should be quick.
+func (db *DB) Upsert(dbName string, key []byte, fn func(existing []byte) ([]byte, error)) error {
+	return db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		events := &[]KeyEvent{}
+		return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			existing, err := txn.Get(dbi, key)
+			found := err == nil
+			if found {
+				existing, err = db.decodeValue(dbName, key, existing)
+			}
+			if err != nil && !lmdb.IsNotFound(err) {
+				return err
+			}
+			val, err := fn(existing)
+			if err != nil {
+				return err
+			}
+			if val == nil {
+				if !found {
+					return nil
+				}
+				if err := txn.Del(dbi, key, nil); err != nil {
+					return err
+				}
+				db.addEvent(events, OpDelete, dbName, key, nil)
+				return db.recordWrite(txn, OpDelete, dbName, key, nil)
+			}
+			stored, err := db.encodeValue(txn, dbi, dbName, key, val)
+			if err != nil {
+				return err
+			}
+			if err := txn.Put(dbi, key, stored, 0); err != nil {
+				return err
+			}
+			db.addEvent(events, OpPut, dbName, key, val)
+			return db.recordWrite(txn, OpPut, dbName, key, stored)
+		}, events)
+	})
+}
+
+// Transfer moves key and its value from srcDB to dstDB in one write
+// transaction. If the key is missing from srcDB the returned error satisfies
+// lmdb.IsNotFound. Unless overwrite is set an existing key in dstDB aborts the
+// transfer with an error satisfying lmdb.IsErrno(err, lmdb.KeyExist), and
+// neither database is changed. Transferring a key to the database it is in
+// only checks that the key exists.
+func (db *DB) Transfer(srcDB, dstDB string, key []byte, overwrite bool) error {
+	return db.UpdateNamed(func(tx *NamedTxn) error {
+		val, err := tx.Get(srcDB, key)
+		if err != nil {
+			return err
+		}
+		if srcDB == dstDB {
+			return nil
+		}
+		var flags uint
+		if !overwrite {
+			flags = lmdb.NoOverwrite
+		}
+		if err := tx.Put(dstDB, key, val, flags); err != nil {
+			return err
+		}
+		return tx.Del(srcDB, key)
+	})
+}
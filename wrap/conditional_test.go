@@ -0,0 +1,186 @@
+package wrap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_DeleteIfValue(t *testing.T) {
+	db := newTestDB(t, "locks")
+	if err := db.Write("locks", []byte("job"), []byte("token-a")); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := db.DeleteIfValue("locks", []byte("job"), []byte("token-b"))
+	if err != nil || deleted {
+		t.Errorf("mismatch: %v %v", deleted, err)
+	}
+	deleted, cur, err := db.CompareAndDelete("locks", []byte("job"), []byte("token"))
+	if err != nil || deleted || string(cur) != "token-a" {
+		t.Errorf("mismatch current: %v %q %v", deleted, cur, err)
+	}
+	if _, err := db.Read("locks", []byte("job")); err != nil {
+		t.Errorf("value deleted on mismatch: %v", err)
+	}
+
+	deleted, err = db.DeleteIfValue("locks", []byte("job"), []byte("token-a"))
+	if err != nil || !deleted {
+		t.Errorf("match: %v %v", deleted, err)
+	}
+	if _, err := db.Read("locks", []byte("job")); !lmdb.IsNotFound(err) {
+		t.Errorf("value not deleted: %v", err)
+	}
+
+	deleted, cur, err = db.CompareAndDelete("locks", []byte("job"), []byte("token-a"))
+	if err != nil || deleted || cur != nil {
+		t.Errorf("missing key: %v %q %v", deleted, cur, err)
+	}
+	if _, err := db.DeleteIfValue("nope", []byte("job"), nil); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+}
+
+func TestDB_CompareAndSwap(t *testing.T) {
+	db := newTestDB(t, "locks")
+	key := []byte("job")
+	for _, tc := range []struct {
+		expected, newValue []byte
+		swapped            bool
+		want               string // value afterwards, "-" if missing
+	}{
+		{[]byte("x"), []byte("a"), false, "-"},
+		{nil, []byte("a"), true, "a"},
+		{nil, []byte("b"), false, "a"},
+		{[]byte("b"), []byte("c"), false, "a"},
+		{[]byte("a"), []byte{}, true, ""},
+		{nil, []byte("d"), false, ""},
+		{[]byte{}, []byte("e"), true, "e"},
+		{[]byte("e"), nil, true, "-"},
+		{nil, nil, true, "-"},
+	} {
+		swapped, err := db.CompareAndSwap("locks", key, tc.expected, tc.newValue)
+		if err != nil || swapped != tc.swapped {
+			t.Errorf("CompareAndSwap(%q, %q): %v %v", tc.expected, tc.newValue, swapped, err)
+		}
+		got := "-"
+		if v, err := db.Read("locks", key); err == nil {
+			got = string(v)
+		} else if !lmdb.IsNotFound(err) {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("CompareAndSwap(%q, %q): value %q (!= %q)", tc.expected, tc.newValue, got, tc.want)
+		}
+	}
+	if _, err := db.CompareAndSwap("nope", key, nil, nil); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+}
+
+func TestDB_Upsert(t *testing.T) {
+	db := newTestDB(t, "counters")
+	key := []byte("hits")
+	incr := func(existing []byte) ([]byte, error) {
+		var n uint64
+		if existing != nil {
+			n = binary.BigEndian.Uint64(existing)
+		}
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, n+1)
+		return b, nil
+	}
+
+	const workers, each = 8, 25
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < each; j++ {
+				if err := db.Upsert("counters", key, incr); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	v, err := db.Read("counters", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := binary.BigEndian.Uint64(v); n != workers*each {
+		t.Errorf("counter: %d (!= %d)", n, workers*each)
+	}
+
+	// an error aborts without writing
+	errAbort := errors.New("abort")
+	err = db.Upsert("counters", key, func([]byte) ([]byte, error) { return []byte("x"), errAbort })
+	if err != errAbort {
+		t.Errorf("abort: %v", err)
+	}
+	if v2, _ := db.Read("counters", key); !bytes.Equal(v2, v) {
+		t.Errorf("value changed by aborted upsert: %q", v2)
+	}
+
+	// nil deletes, also for missing keys
+	for i := 0; i < 2; i++ {
+		if err := db.Upsert("counters", key, func([]byte) ([]byte, error) { return nil, nil }); err != nil {
+			t.Fatalf("delete %d: %v", i, err)
+		}
+	}
+	if _, err := db.Read("counters", key); !lmdb.IsNotFound(err) {
+		t.Errorf("read deleted: %v", err)
+	}
+}
+
+func TestDB_Transfer(t *testing.T) {
+	db := newTestDB(t, "pending", "approved")
+	for _, k := range []string{"a", "b"} {
+		if err := db.Write("pending", []byte(k), []byte("v"+k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Write("approved", []byte("b"), []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Transfer("pending", "approved", []byte("a"), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read("pending", []byte("a")); !lmdb.IsNotFound(err) {
+		t.Errorf("source not deleted: %v", err)
+	}
+	if v, err := db.Read("approved", []byte("a")); err != nil || string(v) != "va" {
+		t.Errorf("destination: %q %v", v, err)
+	}
+
+	err := db.Transfer("pending", "approved", []byte("b"), false)
+	if !lmdb.IsErrno(err, lmdb.KeyExist) {
+		t.Errorf("existing destination: %v", err)
+	}
+	if v, err := db.Read("pending", []byte("b")); err != nil || string(v) != "vb" {
+		t.Errorf("source changed on abort: %q %v", v, err)
+	}
+	if v, err := db.Read("approved", []byte("b")); err != nil || string(v) != "old" {
+		t.Errorf("destination changed on abort: %q %v", v, err)
+	}
+
+	if err := db.Transfer("pending", "approved", []byte("b"), true); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.Read("approved", []byte("b")); err != nil || string(v) != "vb" {
+		t.Errorf("overwrite: %q %v", v, err)
+	}
+
+	if err := db.Transfer("pending", "approved", []byte("a"), true); !lmdb.IsNotFound(err) {
+		t.Errorf("missing source: %v", err)
+	}
+	if err := db.Transfer("approved", "nope", []byte("a"), true); !errors.Is(err, ErrDbNameNotFound) {
+		t.Errorf("unknown db: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package wrap
+
+import (
+	"fmt"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// CopyOpts controls CopyDB.
+type CopyOpts struct {
+	// Overwrite empties a destination database holding records before the
+	// copy.
+	Overwrite bool
+
+	// Merge copies into a destination database holding records, replacing
+	// the values of the keys in both. Records are then written without
+	// lmdb.Append, which is slower.
+	Merge bool
+
+	// CreateDB creates and registers a missing destination database, with
+	// the flags of the source database, like AddDB.
+	CreateDB bool
+
+	// ChunkSize is the number of records written per transaction. Zero
+	// means 10,000.
+	ChunkSize int
+}
+
+// CopyDB copies the records of srcName in src into dstName in dst and
+// returns the number of records copied, for example to move one tenant into
+// an environment of its own. src and dst may be the same DB if the names
+// differ. The destination must have the DupSort and other flags of the
+// source. Unless opts.Overwrite or opts.Merge is set a destination holding
+// records fails the copy with an error wrapping ErrDbNotEmpty.
+//
+// The source is read in one short read transaction per chunk, resuming after
+// the last record copied, and each chunk is written in a transaction of its
+// own with lmdb.Append, since records arrive sorted. No read transaction is
+// held while writing, so a Compact can run between chunks even when src and
+// dst are the same DB. Records written to the source during the copy are
+// copied if they sort after the chunks already read. A chunk holds
+// opts.ChunkSize records. After an error the chunks committed before it stay
+// written and the returned count tells how many records they hold. Values
+// are copied as stored, including the version headers of Versioned
+// databases.
+func CopyDB(src *DB, srcName string, dst *DB, dstName string, opts CopyOpts) (int64, error) {
+	if opts.Overwrite && opts.Merge {
+		return 0, fmt.Errorf("%w: Overwrite and Merge are mutually exclusive", ErrInvalidOption)
+	}
+	if src == dst && srcName == dstName {
+		return 0, fmt.Errorf("%w: cannot copy %q onto itself", ErrInvalidOption, srcName)
+	}
+	sdbi, err := src.getDBI(srcName)
+	if err != nil {
+		return 0, err
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = importChunkSize
+	}
+
+	var flags uint
+	err = src.View(func(txn *lmdb.Txn) (err error) {
+		if err := src.refreshDBI(srcName, &sdbi); err != nil {
+			return err
+		}
+		flags, err = txn.Flags(sdbi)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	hdr := dumpHeader{name: dstName, flags: dumpedFlags(flags)}
+	if err := dst.prepareLoad(hdr, opts.CreateDB); err != nil {
+		return 0, err
+	}
+	count, err := dst.Count(dstName)
+	if err != nil {
+		return 0, err
+	}
+	appending := true
+	switch {
+	case count == 0:
+	case opts.Overwrite:
+		if err := dst.Truncate(dstName); err != nil {
+			return 0, err
+		}
+	case opts.Merge:
+		appending = false
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrDbNotEmpty, dstName)
+	}
+
+	var n int64
+	var prevK, prevV []byte // last record written, to resume and append duplicates
+	for {
+		var chunk []KV
+		done := false
+		err := src.View(func(txn *lmdb.Txn) error {
+			// looked up again since Compact may reopen src between chunks
+			sdbi, err := src.getDBI(srcName)
+			if err != nil {
+				return err
+			}
+			cur, err := txn.OpenCursor(sdbi)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+			chunk = make([]KV, 0, chunkSize)
+			k, v, err := seekAfter(txn, cur, sdbi, prevK, prevV, flags&lmdb.DupSort != 0)
+			for ; err == nil && len(chunk) < chunkSize; k, v, err = cur.Get(nil, nil, lmdb.Next) {
+				chunk = append(chunk, KV{Key: k, Value: v})
+			}
+			if lmdb.IsNotFound(err) {
+				done, err = true, nil
+			}
+			return err
+		})
+		if err != nil {
+			return n, err
+		}
+		if len(chunk) > 0 {
+			if err := dst.loadChunk(hdr, chunk, prevK, appending); err != nil {
+				return n, err
+			}
+			n += int64(len(chunk))
+			last := chunk[len(chunk)-1]
+			prevK, prevV = last.Key, last.Value
+		}
+		if done {
+			return n, nil
+		}
+	}
+}
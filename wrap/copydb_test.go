@@ -0,0 +1,75 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCopyDB(t *testing.T) {
+	src, _, err := New(t.TempDir(), []string{"tenant1", "tags"}, WithDBOptions("tags", DBOptions{DupSort: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for i := 0; i < 25; i++ {
+		mustWrite(t, src, "tenant1", fmt.Sprintf("k%02d", i))
+	}
+	for i := 0; i < 5; i++ {
+		for _, m := range []string{"a", "b", "c"} {
+			if err := src.AddValue("tags", []byte(fmt.Sprintf("t%d", i)), []byte(m)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	dst := newTestDB(t, "users")
+	if _, err := CopyDB(src, "tenant1", dst, "users", CopyOpts{Overwrite: true, Merge: true}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("exclusive options: %v", err)
+	}
+	if _, err := CopyDB(src, "tenant1", src, "tenant1", CopyOpts{}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("copy onto itself: %v", err)
+	}
+	if _, err := CopyDB(src, "tenant1", dst, "missing", CopyOpts{}); !errors.Is(err, ErrDbNameNotFound) {
+		t.Errorf("missing destination: %v", err)
+	}
+
+	n, err := CopyDB(src, "tenant1", dst, "users", CopyOpts{ChunkSize: 10})
+	if err != nil || n != 25 {
+		t.Fatalf("copy: %d %v", n, err)
+	}
+	want, _ := src.Scan("tenant1", nil, ScanOptions{})
+	got, _ := dst.Scan("users", nil, ScanOptions{})
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("copied records differ")
+	}
+
+	if _, err := CopyDB(src, "tenant1", dst, "users", CopyOpts{}); !errors.Is(err, ErrDbNotEmpty) {
+		t.Errorf("non-empty destination: %v", err)
+	}
+	mustWrite(t, dst, "users", "extra")
+	if n, err := CopyDB(src, "tenant1", dst, "users", CopyOpts{Merge: true}); err != nil || n != 25 {
+		t.Errorf("merge: %d %v", n, err)
+	}
+	if c, _ := dst.Count("users"); c != 26 {
+		t.Errorf("%d keys after merge", c)
+	}
+	if n, err := CopyDB(src, "tenant1", dst, "users", CopyOpts{Overwrite: true}); err != nil || n != 25 {
+		t.Errorf("overwrite: %d %v", n, err)
+	}
+	if c, _ := dst.Count("users"); c != 25 {
+		t.Errorf("%d keys after overwrite", c)
+	}
+
+	// duplicates are appended across chunks
+	n, err = CopyDB(src, "tags", dst, "labels", CopyOpts{CreateDB: true, ChunkSize: 4})
+	if err != nil || n != 15 {
+		t.Fatalf("copy dupsort: %d %v", n, err)
+	}
+	if members, _ := dst.Members("labels", []byte("t2"), 0); len(members) != 3 {
+		t.Errorf("duplicates: %q", members)
+	}
+	if _, err := CopyDB(src, "tags", dst, "users", CopyOpts{Overwrite: true}); err == nil {
+		t.Errorf("flags mismatch copied")
+	}
+}
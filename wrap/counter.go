@@ -0,0 +1,87 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+var (
+	// ErrNotCounter is returned when a value read as a counter is not 8
+	// bytes long.
+	ErrNotCounter = errors.New("value is not an 8-byte counter")
+
+	// ErrCounterRange is returned by IntIncrement when the result would be
+	// negative or exceed math.MaxUint64.
+	ErrCounterRange = errors.New("counter out of range")
+)
+
+// IntGet reads the counter stored under key as an 8-byte big-endian integer.
+// If the key is missing IntGet returns ErrNotFound.
+func (db *DB) IntGet(dbName string, key []byte) (uint64, error) {
+	val, err := db.Read(dbName, key)
+	if lmdb.IsNotFound(err) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return decodeCounter(dbName, val)
+}
+
+// IntPut stores value under key as an 8-byte big-endian integer.
+func (db *DB) IntPut(dbName string, key []byte, value uint64) error {
+	return db.Write(dbName, key, encodeCounter(value))
+}
+
+// IntIncrement adds delta to the counter stored under key, a missing key
+// counts as zero, and returns the new value. The read and the write happen in
+// one transaction, so concurrent increments are never lost. A result below
+// zero or above math.MaxUint64 returns an error wrapping ErrCounterRange and
+// leaves the counter unchanged.
+func (db *DB) IntIncrement(dbName string, key []byte, delta int64) (uint64, error) {
+	var next uint64
+	err := db.Upsert(dbName, key, func(existing []byte) ([]byte, error) {
+		var n uint64
+		if existing != nil {
+			var err error
+			if n, err = decodeCounter(dbName, existing); err != nil {
+				return nil, err
+			}
+		}
+		if delta < 0 {
+			// written so that math.MinInt64 does not overflow
+			sub := uint64(-(delta + 1)) + 1
+			if sub > n {
+				return nil, fmt.Errorf("%w: %d%+d", ErrCounterRange, n, delta)
+			}
+			next = n - sub
+		} else {
+			if uint64(delta) > math.MaxUint64-n {
+				return nil, fmt.Errorf("%w: %d%+d", ErrCounterRange, n, delta)
+			}
+			next = n + uint64(delta)
+		}
+		return encodeCounter(next), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+func encodeCounter(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
+
+func decodeCounter(dbName string, val []byte) (uint64, error) {
+	if len(val) != 8 {
+		return 0, fmt.Errorf("%w: %q: %d bytes", ErrNotCounter, dbName, len(val))
+	}
+	return binary.BigEndian.Uint64(val), nil
+}
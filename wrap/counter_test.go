@@ -0,0 +1,82 @@
+package wrap
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_IntGetPut(t *testing.T) {
+	db := newTestDB(t, "counters")
+	key := []byte("hits")
+
+	_, err := db.IntGet("counters", key)
+	if err != ErrNotFound || !lmdb.IsNotFound(err) || !errors.Is(err, lmdb.NotFound) {
+		t.Errorf("missing key: %v", err)
+	}
+	if err := db.IntPut("counters", key, math.MaxUint64-1); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.IntGet("counters", key); err != nil || n != math.MaxUint64-1 {
+		t.Errorf("get: %d %v", n, err)
+	}
+
+	if err := db.Write("counters", []byte("text"), []byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.IntGet("counters", []byte("text")); !errors.Is(err, ErrNotCounter) {
+		t.Errorf("non-counter value: %v", err)
+	}
+	if _, err := db.IntGet("nope", key); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+}
+
+func TestDB_IntIncrement(t *testing.T) {
+	db := newTestDB(t, "counters")
+	key := []byte("hits")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := db.IntIncrement("counters", key, 1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n, err := db.IntGet("counters", key); err != nil || n != 200 {
+		t.Errorf("concurrent increments: %d %v", n, err)
+	}
+
+	if n, err := db.IntIncrement("counters", key, -200); err != nil || n != 0 {
+		t.Errorf("decrement to zero: %d %v", n, err)
+	}
+	if _, err := db.IntIncrement("counters", key, -1); !errors.Is(err, ErrCounterRange) {
+		t.Errorf("underflow: %v", err)
+	}
+	if _, err := db.IntIncrement("counters", key, math.MinInt64); !errors.Is(err, ErrCounterRange) {
+		t.Errorf("underflow by MinInt64: %v", err)
+	}
+	if n, _ := db.IntGet("counters", key); n != 0 {
+		t.Errorf("counter changed by failed decrement: %d", n)
+	}
+
+	if err := db.IntPut("counters", key, math.MaxUint64); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.IntIncrement("counters", key, 1); !errors.Is(err, ErrCounterRange) {
+		t.Errorf("overflow: %v", err)
+	}
+	if n, err := db.IntIncrement("counters", []byte("new"), 5); err != nil || n != 5 {
+		t.Errorf("missing key: %d %v", n, err)
+	}
+}
@@ -0,0 +1,104 @@
+package wrap
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// CSVEncoding selects how ExportCSV writes keys and values.
+type CSVEncoding uint8
+
+const (
+	CSVAuto   CSVEncoding = iota // text as is, other bytes as "0x" and hex
+	CSVHex                       // always hex
+	CSVBase64                    // always standard base64
+)
+
+// CSVOpts controls ExportCSV.
+type CSVOpts struct {
+	// Encoding is the encoding of keys and values.
+	Encoding CSVEncoding
+
+	// Header writes a "key,value" row first.
+	Header bool
+
+	// Prefix restricts the export to keys starting with Prefix.
+	Prefix []byte
+
+	// Limit is the maximum number of rows written, zero or less means no
+	// limit. The header row does not count.
+	Limit int
+}
+
+// ExportCSV writes the records of dbName to w as CSV rows of key and value,
+// in key order and from a single read transaction. Fields are quoted as
+// needed by encoding/csv, so commas, quotes, and newlines survive a round
+// trip through a spreadsheet.
+//
+// With CSVAuto a field is written as is if it is valid UTF-8 without control
+// characters other than tab, newline, and carriage return, and as "0x"
+// followed by hex otherwise. Text that itself starts with "0x" is written as
+// is, so the encodings can only be told apart by content; use CSVHex or
+// CSVBase64 when the output must be decoded exactly. Values are written as
+// stored, including the version headers of Versioned databases.
+func (db *DB) ExportCSV(w io.Writer, dbName string, opts CSVOpts) error {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if opts.Header {
+		if err := cw.Write([]string{"key", "value"}); err != nil {
+			return err
+		}
+	}
+	err = db.View(func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		txn.RawRead = true
+		return scan(txn, dbi, opts.Prefix, prefixEnd(opts.Prefix), ScanOptions{Limit: opts.Limit}, func(k, v []byte) error {
+			return cw.Write([]string{csvField(k, opts.Encoding), csvField(v, opts.Encoding)})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvField encodes one key or value.
+func csvField(b []byte, enc CSVEncoding) string {
+	switch enc {
+	case CSVHex:
+		return hex.EncodeToString(b)
+	case CSVBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	if csvText(b) {
+		return string(b)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// csvText reports whether b can be written to a CSV field as is.
+func csvText(b []byte) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 {
+			return false
+		}
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+		b = b[size:]
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package wrap
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDB_ExportCSV(t *testing.T) {
+	db := newTestDB(t, "users")
+	rows := map[string][]byte{
+		"a":     []byte("plain"),
+		"b":     []byte("x, \"quoted\"\nnext line"),
+		"c":     {0xff, 0x00, 'z'},
+		"d":     []byte("nul\x00inside"),
+		"e":     {},
+		"other": []byte("skipped by prefix"),
+	}
+	for k, v := range rows {
+		if err := db.Write("users", []byte(k), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(opts CSVOpts) [][]string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := db.ExportCSV(&buf, "users", opts); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return records
+	}
+
+	got := read(CSVOpts{Header: true})
+	want := [][]string{
+		{"key", "value"},
+		{"a", "plain"},
+		{"b", "x, \"quoted\"\nnext line"},
+		{"c", "0xff007a"},
+		{"d", "0x6e756c00696e73696465"},
+		{"e", ""},
+		{"other", "skipped by prefix"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("auto: %q", got)
+	}
+
+	got = read(CSVOpts{Encoding: CSVHex, Prefix: []byte("c"), Limit: 1})
+	if !reflect.DeepEqual(got, [][]string{{"63", "ff007a"}}) {
+		t.Errorf("hex: %q", got)
+	}
+	got = read(CSVOpts{Encoding: CSVBase64, Limit: 1})
+	if !reflect.DeepEqual(got, [][]string{{"YQ==", "cGxhaW4="}}) {
+		t.Errorf("base64: %q", got)
+	}
+
+	if err := db.ExportCSV(&bytes.Buffer{}, "missing", CSVOpts{}); !errors.Is(err, ErrDbNameNotFound) {
+		t.Errorf("unknown db: %v", err)
+	}
+	if err := db.ExportCSV(&failWriter{}, "users", CSVOpts{}); !errors.Is(err, errWriteFailed) {
+		t.Errorf("write error: %v", err)
+	}
+}
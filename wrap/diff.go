@@ -0,0 +1,321 @@
+package wrap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sort"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// DiffKind tells how a key differs between two databases.
+type DiffKind uint8
+
+const (
+	DiffAdded    DiffKind = iota + 1 // the key is only in the after database
+	DiffRemoved                      // the key is only in the before database
+	DiffModified                     // the key has different values
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffModified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// DiffEntry is a difference reported by DiffFunc. Old is nil for added keys
+// and New is nil for removed keys.
+type DiffEntry struct {
+	Kind     DiffKind
+	Key      []byte
+	Old, New []byte
+}
+
+// KVPair is a key with its value before and after a change.
+type KVPair struct {
+	Key      []byte
+	Old, New []byte
+}
+
+// DiffResult holds the differences found by Diff, each list in key order.
+type DiffResult struct {
+	Added    []KV
+	Removed  []KV
+	Modified []KVPair
+}
+
+// Diff compares the named database of before and after and returns the keys
+// added, removed, and modified going from before to after, for example a DB
+// and a backup of it opened with New. Diff holds every difference in memory,
+// DiffFunc streams them.
+func Diff(before, after *DB, dbName string) (*DiffResult, error) {
+	res := &DiffResult{}
+	err := DiffFunc(before, after, dbName, func(e DiffEntry) error {
+		switch e.Kind {
+		case DiffAdded:
+			res.Added = append(res.Added, KV{Key: e.Key, Value: e.New})
+		case DiffRemoved:
+			res.Removed = append(res.Removed, KV{Key: e.Key, Value: e.Old})
+		case DiffModified:
+			res.Modified = append(res.Modified, KVPair{Key: e.Key, Old: e.Old, New: e.New})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DiffFunc is like Diff but calls fn with each difference, in key order,
+// instead of collecting them. Both databases are read in read transactions
+// held open for the whole walk, so each side is a consistent snapshot. The
+// slices of an entry are owned by fn. Iteration stops at the first error
+// returned by fn, which DiffFunc returns unless it is ErrStopScan.
+//
+// Keys are walked in the order of the before database, which must have the
+// same ordering flags, such as ReverseKey and IntegerKey, as the after one.
+// Values are compared and reported without the version headers of Versioned
+// databases and the checksums of checksummed ones, each side decoded with the
+// options of its DB, and a value failing its checksum stops the walk with a
+// ChecksumError. In DupSort databases every value of a key is compared on its
+// own and reported as added or removed.
+func DiffFunc(before, after *DB, dbName string, fn func(DiffEntry) error) error {
+	bdbi, err := before.getDBI(dbName)
+	if err != nil {
+		return err
+	}
+	adbi, err := after.getDBI(dbName)
+	if err != nil {
+		return err
+	}
+	err = before.View(func(btxn *lmdb.Txn) error {
+		if err := before.refreshDBI(dbName, &bdbi); err != nil {
+			return err
+		}
+		return after.View(func(atxn *lmdb.Txn) error {
+			if err := after.refreshDBI(dbName, &adbi); err != nil {
+				return err
+			}
+			return diffDBI(btxn, atxn, bdbi, adbi, before.valueDecoder(dbName), after.valueDecoder(dbName), fn)
+		})
+	})
+	if err == ErrStopScan {
+		return nil
+	}
+	return err
+}
+
+// DiffOpts controls DiffAll.
+type DiffOpts struct {
+	// MaxExamples is the maximum number of differences recorded per
+	// database. Zero means 10, a negative value records none. The counts of
+	// a DBDiff are exact regardless.
+	MaxExamples int
+
+	// HashValues records the SHA-256 sums of the values of examples instead
+	// of the values themselves, to bound the size of the report.
+	HashValues bool
+}
+
+// DiffReport is the result of DiffAll.
+type DiffReport struct {
+	DBs []DBDiff // one per database compared, in name order
+}
+
+// Equal reports whether no differences were found.
+func (r DiffReport) Equal() bool {
+	for _, d := range r.DBs {
+		if d.OnlyA+d.OnlyB+d.Differ != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// DBDiff holds the differences found in one database by DiffAll. Examples
+// only in a are reported with Kind DiffRemoved, examples only in b with
+// DiffAdded, as if going from a to b.
+type DBDiff struct {
+	DB     string
+	OnlyA  int64 // keys only in a
+	OnlyB  int64 // keys only in b
+	Differ int64 // keys in both with different values
+
+	// Examples holds the first differences found, in key order, up to
+	// DiffOpts.MaxExamples.
+	Examples []DiffEntry
+}
+
+// DiffAll compares the named databases of a and b, for example an
+// environment and its copy after a migration, and reports the keys only in
+// a, only in b, and in both with different values. An empty dbNames compares
+// every database registered in a or b, each of which must be registered in
+// both.
+//
+// Each DB is read in one read transaction held open for the whole
+// comparison, so every database is compared between the same two snapshots.
+// Keys are walked in database order, values are compared decoded, and DupSort
+// databases are compared by value, like DiffFunc.
+func DiffAll(a, b *DB, dbNames []string, opts DiffOpts) (DiffReport, error) {
+	if len(dbNames) == 0 {
+		seen := make(map[string]bool)
+		for _, db := range []*DB{a, b} {
+			for name := range db.GetDBis() {
+				if !seen[name] {
+					seen[name] = true
+					dbNames = append(dbNames, name)
+				}
+			}
+		}
+		sort.Strings(dbNames)
+	}
+	names, adbis, err := a.resolveDBs(dbNames)
+	if err != nil {
+		return DiffReport{}, err
+	}
+	_, bdbis, err := b.resolveDBs(names)
+	if err != nil {
+		return DiffReport{}, err
+	}
+	max := opts.MaxExamples
+	if max == 0 {
+		max = 10
+	}
+	value := func(v []byte) []byte {
+		if v == nil {
+			return nil
+		}
+		if opts.HashValues {
+			sum := sha256.Sum256(v)
+			return sum[:]
+		}
+		return append([]byte{}, v...)
+	}
+
+	var report DiffReport
+	err = a.View(func(atxn *lmdb.Txn) error {
+		if err := a.refreshDBIs(names, adbis); err != nil {
+			return err
+		}
+		return b.View(func(btxn *lmdb.Txn) error {
+			if err := b.refreshDBIs(names, bdbis); err != nil {
+				return err
+			}
+			atxn.RawRead, btxn.RawRead = true, true
+			report.DBs = make([]DBDiff, len(names))
+			for i, name := range names {
+				d := &report.DBs[i]
+				d.DB = name
+				err := diffDBI(atxn, btxn, adbis[i], bdbis[i], a.valueDecoder(name), b.valueDecoder(name), func(e DiffEntry) error {
+					switch e.Kind {
+					case DiffRemoved:
+						d.OnlyA++
+					case DiffAdded:
+						d.OnlyB++
+					case DiffModified:
+						d.Differ++
+					}
+					if len(d.Examples) < max {
+						e.Key, e.Old, e.New = append([]byte{}, e.Key...), value(e.Old), value(e.New)
+						d.Examples = append(d.Examples, e)
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return DiffReport{}, err
+	}
+	return report, nil
+}
+
+// valueDecoder returns decodeValue for the values of dbName.
+func (db *DB) valueDecoder(dbName string) func(k, v []byte) ([]byte, error) {
+	return func(k, v []byte) ([]byte, error) {
+		return db.decodeValue(dbName, k, v)
+	}
+}
+
+// diffDBI walks bdbi in btxn and adbi in atxn in lockstep, in the order of
+// bdbi, and calls fn with each difference. The values of each side are passed
+// through bdec and adec, a nil decoder leaves them as stored.
+func diffDBI(btxn, atxn *lmdb.Txn, bdbi, adbi lmdb.DBI, bdec, adec func(k, v []byte) ([]byte, error), fn func(DiffEntry) error) error {
+	flags, err := btxn.Flags(bdbi)
+	if err != nil {
+		return err
+	}
+	dup := flags&lmdb.DupSort != 0
+	bcur, err := btxn.OpenCursor(bdbi)
+	if err != nil {
+		return err
+	}
+	defer bcur.Close()
+	acur, err := atxn.OpenCursor(adbi)
+	if err != nil {
+		return err
+	}
+	defer acur.Close()
+
+	get := func(cur *lmdb.Cursor, dec func(k, v []byte) ([]byte, error), op uint) ([]byte, []byte, error) {
+		k, v, err := cur.Get(nil, nil, op)
+		if err == nil && dec != nil {
+			v, err = dec(k, v)
+		}
+		return k, v, err
+	}
+	bk, bv, berr := get(bcur, bdec, lmdb.First)
+	ak, av, aerr := get(acur, adec, lmdb.First)
+	for {
+		if berr != nil && !lmdb.IsNotFound(berr) {
+			return berr
+		}
+		if aerr != nil && !lmdb.IsNotFound(aerr) {
+			return aerr
+		}
+		bdone, adone := berr != nil, aerr != nil
+		if bdone && adone {
+			return nil
+		}
+		var cmp int
+		switch {
+		case bdone:
+			cmp = 1
+		case adone:
+			cmp = -1
+		default:
+			cmp = btxn.Cmp(bdbi, bk, ak)
+			if cmp == 0 && dup {
+				cmp = btxn.DCmp(bdbi, bv, av)
+			}
+		}
+		switch {
+		case cmp < 0:
+			err = fn(DiffEntry{Kind: DiffRemoved, Key: bk, Old: bv})
+			bk, bv, berr = get(bcur, bdec, lmdb.Next)
+		case cmp > 0:
+			err = fn(DiffEntry{Kind: DiffAdded, Key: ak, New: av})
+			ak, av, aerr = get(acur, adec, lmdb.Next)
+		default:
+			if !bytes.Equal(bv, av) {
+				err = fn(DiffEntry{Kind: DiffModified, Key: bk, Old: bv, New: av})
+			}
+			bk, bv, berr = get(bcur, bdec, lmdb.Next)
+			ak, av, aerr = get(acur, adec, lmdb.Next)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDiff(t *testing.T) {
+	before := newTestDB(t, "users")
+	after := newTestDB(t, "users")
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("k%d", i)
+		mustWrite(t, before, "users", key)
+		if i >= 3 {
+			mustWrite(t, after, "users", key)
+		}
+	}
+	for _, k := range []string{"k4", "k6", "k9"} {
+		if err := after.Write("users", []byte(k), []byte("changed")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite(t, after, "users", "a", "k45", "z")
+
+	d, err := Diff(before, after, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%s", d.Added); got != "[{a v:a} {k45 v:k45} {z v:z}]" {
+		t.Errorf("added: %s", got)
+	}
+	if got := fmt.Sprintf("%s", d.Removed); got != "[{k0 v:k0} {k1 v:k1} {k2 v:k2}]" {
+		t.Errorf("removed: %s", got)
+	}
+	if got := fmt.Sprintf("%s", d.Modified); got != "[{k4 v:k4 changed} {k6 v:k6 changed} {k9 v:k9 changed}]" {
+		t.Errorf("modified: %s", got)
+	}
+
+	if d, err := Diff(before, before, "users"); err != nil || len(d.Added)+len(d.Removed)+len(d.Modified) != 0 {
+		t.Errorf("self diff: %+v %v", d, err)
+	}
+
+	var kinds []DiffKind
+	err = DiffFunc(before, after, "users", func(e DiffEntry) error {
+		kinds = append(kinds, e.Kind)
+		if len(kinds) == 2 {
+			return ErrStopScan
+		}
+		return nil
+	})
+	if err != nil || fmt.Sprint(kinds) != "[added removed]" {
+		t.Errorf("stopped diff: %v %v", kinds, err)
+	}
+	if _, err := Diff(before, after, "nope"); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+}
+
+func TestDiff_dupSort(t *testing.T) {
+	open := func() *DB {
+		db, _, err := New(t.TempDir(), []string{"tags"}, WithDBOptions("tags", DBOptions{DupSort: true}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	before, after := open(), open()
+	for _, m := range []string{"a", "b"} {
+		before.AddValue("tags", []byte("k"), []byte(m))
+	}
+	for _, m := range []string{"b", "c"} {
+		after.AddValue("tags", []byte("k"), []byte(m))
+	}
+	d, err := Diff(before, after, "tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%s %s %d", d.Added, d.Removed, len(d.Modified)) != "[{k c}] [{k a}] 0" {
+		t.Errorf("dup diff: %s %s %s", d.Added, d.Removed, d.Modified)
+	}
+}
+
+// newOrderedDBs returns two DBs holding "ints", an IntegerKey database, and
+// "rev", a ReverseKey one, whose keys sort differently than their bytes. In
+// both databases after has one key removed, one added, and one modified.
+func newOrderedDBs(t *testing.T) (before, after *DB) {
+	open := func() *DB {
+		db, _, err := New(t.TempDir(), []string{"ints", "rev"},
+			WithDBOptions("ints", DBOptions{Flags: lmdb.IntegerKey}),
+			WithDBOptions("rev", DBOptions{Flags: lmdb.ReverseKey}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	write := func(db *DB, n uint32, v string) {
+		// little endian, the byte order of IntegerKey on common hosts
+		key := make([]byte, 4)
+		binary.LittleEndian.PutUint32(key, n)
+		if err := db.Write("ints", key, []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, after = open(), open()
+	for _, n := range []uint32{1, 2, 256, 65536} {
+		write(before, n, "v")
+	}
+	for _, n := range []uint32{1, 257, 65536} {
+		write(after, n, "v")
+	}
+	write(after, 256, "changed")
+	mustWrite(t, before, "rev", "ab", "ba", "ca", "bb")
+	mustWrite(t, after, "rev", "ab", "ac", "bb")
+	if err := after.Write("rev", []byte("ca"), []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	return before, after
+}
+
+func TestDiff_keyOrder(t *testing.T) {
+	before, after := newOrderedDBs(t)
+	for _, name := range []string{"ints", "rev"} {
+		d, err := Diff(before, after, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Added) != 1 || len(d.Removed) != 1 || len(d.Modified) != 1 || string(d.Modified[0].New) != "changed" {
+			t.Errorf("%s: %+v", name, d)
+		}
+	}
+}
+
+func TestDiff_framed(t *testing.T) {
+	open := func() *DB {
+		db, _, err := New(t.TempDir(), []string{"users"},
+			WithDBOptions("users", DBOptions{Versioned: true, Checksum: ChecksumCRC32C}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	before, after := open(), open()
+	mustWrite(t, before, "users", "a", "b")
+	// rewritten, so the versions differ from after
+	mustWrite(t, before, "users", "a", "b")
+	mustWrite(t, after, "users", "a")
+	if err := after.Write("users", []byte("b"), []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Diff(before, after, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%s %s %s", d.Added, d.Removed, d.Modified); got != "[] [] [{b v:b changed}]" {
+		t.Errorf("framed diff: %s", got)
+	}
+}
+
+func TestDiffAll(t *testing.T) {
+	a := newTestDB(t, "users", "tags")
+	b := newTestDB(t, "users", "tags")
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("k%02d", i)
+		mustWrite(t, a, "users", key)
+		if i%4 != 0 {
+			mustWrite(t, b, "users", key)
+		}
+	}
+	mustWrite(t, a, "tags", "x")
+	mustWrite(t, b, "tags", "x")
+	if err := b.Write("users", []byte("k01"), []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, b, "users", "new")
+
+	r, err := DiffAll(a, b, nil, DiffOpts{MaxExamples: 3, HashValues: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Equal() || len(r.DBs) != 2 {
+		t.Fatalf("report: %+v", r)
+	}
+	if d := r.DBs[0]; d.DB != "tags" || d.OnlyA+d.OnlyB+d.Differ != 0 || d.Examples != nil {
+		t.Errorf("tags: %+v", d)
+	}
+	d := r.DBs[1]
+	if d.DB != "users" || d.OnlyA != 5 || d.OnlyB != 1 || d.Differ != 1 {
+		t.Errorf("users counts: %+v", d)
+	}
+	var got []string
+	for _, e := range d.Examples {
+		got = append(got, fmt.Sprintf("%s %s", e.Kind, e.Key))
+	}
+	if fmt.Sprint(got) != "[removed k00 modified k01 removed k04]" {
+		t.Errorf("examples: %v", got)
+	}
+	if old := d.Examples[1].Old; len(old) != 32 {
+		t.Errorf("hashed value: %x", old)
+	}
+
+	r, err = DiffAll(a, b, []string{"users"}, DiffOpts{MaxExamples: -1})
+	if err != nil || len(r.DBs) != 1 || r.DBs[0].Examples != nil || r.DBs[0].OnlyA != 5 {
+		t.Errorf("no examples: %+v %v", r, err)
+	}
+	if r, err := DiffAll(a, a, nil, DiffOpts{}); err != nil || !r.Equal() {
+		t.Errorf("self diff: %+v %v", r, err)
+	}
+	c := newTestDB(t, "users")
+	if _, err := DiffAll(a, c, nil, DiffOpts{}); !errors.Is(err, ErrDbNameNotFound) {
+		t.Errorf("missing db: %v", err)
+	}
+}
+
+func TestDiffAll_ordered(t *testing.T) {
+	a, b := newOrderedDBs(t)
+	r, err := DiffAll(a, b, nil, DiffOpts{})
+	if err != nil || len(r.DBs) != 2 {
+		t.Fatalf("report: %+v %v", r, err)
+	}
+	for _, d := range r.DBs {
+		if d.OnlyA != 1 || d.OnlyB != 1 || d.Differ != 1 {
+			t.Errorf("%s: %+v", d.DB, d)
+		}
+	}
+
+	opts := []Option{WithDBOptions("users", DBOptions{Versioned: true, Checksum: ChecksumCRC32C})}
+	c, _, err := New(t.TempDir(), []string{"users"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	d, _, err := New(t.TempDir(), []string{"users"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	mustWrite(t, c, "users", "a", "a")
+	mustWrite(t, d, "users", "a")
+	if r, err := DiffAll(c, d, nil, DiffOpts{}); err != nil || !r.Equal() {
+		t.Errorf("framed: %+v %v", r, err)
+	}
+}
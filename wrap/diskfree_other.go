@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package wrap
+
+// diskFree reports the free space as unknown, there is no shim for this
+// platform.
+func diskFree(path string) (int64, error) {
+	return -1, nil
+}
@@ -0,0 +1,177 @@
+package wrap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// dumpVersion is the VERSION written in dump headers, the one of mdb_dump.
+const dumpVersion = 3
+
+// dumpFlags are the database flags recorded in dump headers, in the order
+// mdb_dump writes them.
+var dumpFlags = []struct {
+	flag uint
+	name string
+}{
+	{lmdb.ReverseKey, "reversekey"},
+	{lmdb.DupSort, "dupsort"},
+	{lmdb.IntegerKey, "integerkey"},
+	{lmdb.DupFixed, "dupfixed"},
+	{lmdb.IntegerDup, "integerdup"},
+	{lmdb.ReverseDup, "reversedup"},
+}
+
+// dumpedFlags returns the flags of dumpFlags set in flags.
+func dumpedFlags(flags uint) uint {
+	var out uint
+	for _, f := range dumpFlags {
+		out |= flags & f.flag
+	}
+	return out
+}
+
+// DumpOptions controls the output of Dump.
+type DumpOptions struct {
+	// DBNames lists the databases to dump. Empty means every registered
+	// database in name order.
+	DBNames []string
+
+	// Printable writes printable bytes as they are and escapes the others,
+	// like mdb_dump -p, instead of writing every byte in hex.
+	Printable bool
+}
+
+// Dump writes the databases selected by opts to w in the text format of
+// mdb_dump -a, one section per database, so the output can be read by
+// mdb_load and by Load. Every database is read in a single read transaction,
+// so the sections are consistent with each other. Values are written as
+// stored, including the version headers of Versioned databases.
+func (db *DB) Dump(w io.Writer, opts DumpOptions) error {
+	names, dbis, err := db.resolveDBs(opts.DBNames)
+	if err != nil {
+		return err
+	}
+	info, err := db.EnvInfo()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	err = db.View(func(txn *lmdb.Txn) error {
+		if err := db.refreshDBIs(names, dbis); err != nil {
+			return err
+		}
+		txn.RawRead = true
+		for i, name := range names {
+			if err := dumpDBI(bw, txn, dbis[i], name, info, opts.Printable); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// resolveDBs returns the handles of the named databases, or the names and
+// handles of every registered database in name order if names is empty.
+func (db *DB) resolveDBs(names []string) ([]string, []lmdb.DBI, error) {
+	if len(names) == 0 {
+		for name := range db.GetDBis() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	dbis := make([]lmdb.DBI, len(names))
+	for i, name := range names {
+		dbi, err := db.getDBI(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %q", err, name)
+		}
+		dbis[i] = dbi
+	}
+	return names, dbis, nil
+}
+
+// refreshDBIs is refreshDBI for the names and handles returned by
+// resolveDBs.
+func (db *DB) refreshDBIs(names []string, dbis []lmdb.DBI) error {
+	for i, name := range names {
+		if err := db.refreshDBI(name, &dbis[i]); err != nil {
+			return fmt.Errorf("%w: %q", err, name)
+		}
+	}
+	return nil
+}
+
+// dumpDBI writes the section of one database.
+func dumpDBI(w *bufio.Writer, txn *lmdb.Txn, dbi lmdb.DBI, name string, info *lmdb.EnvInfo, printable bool) error {
+	flags, err := txn.Flags(dbi)
+	if err != nil {
+		return err
+	}
+	stat, err := txn.Stat(dbi)
+	if err != nil {
+		return err
+	}
+	format := "bytevalue"
+	if printable {
+		format = "print"
+	}
+	fmt.Fprintf(w, "VERSION=%d\nformat=%s\ndatabase=%s\ntype=btree\n", dumpVersion, format, name)
+	fmt.Fprintf(w, "mapsize=%d\n", info.MapSize)
+	if info.MapAddr != 0 {
+		fmt.Fprintf(w, "mapaddr=%#x\n", info.MapAddr)
+	}
+	fmt.Fprintf(w, "maxreaders=%d\n", info.MaxReaders)
+	if flags&lmdb.DupSort != 0 {
+		fmt.Fprintf(w, "duplicates=1\n")
+	}
+	for _, f := range dumpFlags {
+		if flags&f.flag != 0 {
+			fmt.Fprintf(w, "%s=1\n", f.name)
+		}
+	}
+	fmt.Fprintf(w, "db_pagesize=%d\nHEADER=END\n", stat.PSize)
+
+	err = scan(txn, dbi, nil, nil, ScanOptions{}, func(k, v []byte) error {
+		dumpValue(w, k, printable)
+		dumpValue(w, v, printable)
+		// a failed write sticks to w, stop early
+		_, err := w.Write(nil)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.WriteString("DATA=END\n")
+	return err
+}
+
+// dumpValue writes one key or value line.
+func dumpValue(w *bufio.Writer, b []byte, printable bool) {
+	const hexDigits = "0123456789abcdef"
+	w.WriteByte(' ')
+	for _, c := range b {
+		switch {
+		case printable && c == '\\':
+			w.WriteString(`\\`)
+		case printable && c >= 0x20 && c < 0x7f:
+			w.WriteByte(c)
+		case printable:
+			w.WriteByte('\\')
+			fallthrough
+		default:
+			w.WriteByte(hexDigits[c>>4])
+			w.WriteByte(hexDigits[c&0xf])
+		}
+	}
+	w.WriteByte('\n')
+}
@@ -0,0 +1,60 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestDB_Dump(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"users", "tags"}, WithDBOptions("tags", DBOptions{DupSort: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Write("users", []byte("a b"), []byte("x\\y\x00\xff")); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []string{"2", "1"} {
+		if err := db.AddValue("tags", []byte("k"), []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := db.EnvInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := func(format, name, flags string) string {
+		return fmt.Sprintf("VERSION=3\nformat=%s\ndatabase=%s\ntype=btree\nmapsize=%d\nmaxreaders=%d\n%sdb_pagesize=%d\nHEADER=END\n",
+			format, name, info.MapSize, info.MaxReaders, flags, os.Getpagesize())
+	}
+	dupFlags := "duplicates=1\ndupsort=1\n"
+
+	var buf bytes.Buffer
+	if err := db.Dump(&buf, DumpOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := header("bytevalue", "tags", dupFlags) + " 6b\n 31\n 6b\n 32\nDATA=END\n" +
+		header("bytevalue", "users", "") + " 612062\n 785c7900ff\nDATA=END\n"
+	if buf.String() != want {
+		t.Errorf("hex dump:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := db.Dump(&buf, DumpOptions{DBNames: []string{"users"}, Printable: true}); err != nil {
+		t.Fatal(err)
+	}
+	want = header("print", "users", "") + " a b\n x\\\\y\\00\\ff\nDATA=END\n"
+	if buf.String() != want {
+		t.Errorf("printable dump:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	if err := db.Dump(&buf, DumpOptions{DBNames: []string{"nope"}}); !errors.Is(err, ErrDbNameNotFound) {
+		t.Errorf("unknown db: %v", err)
+	}
+	if err := db.Dump(&failWriter{n: 10}, DumpOptions{}); err != errWriteFailed {
+		t.Errorf("failed write: %v", err)
+	}
+}
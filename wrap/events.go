@@ -0,0 +1,151 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+)
+
+// Op identifies the kind of change reported by a KeyEvent.
+type Op uint8
+
+const (
+	OpPut    Op = iota + 1 // a key was written
+	OpDelete               // a key was deleted
+
+	// OpMissed marks the place in a Watch channel where events were dropped
+	// because the receiver fell behind, see MarkMissed. Its key is nil.
+	OpMissed
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpPut:
+		return "put"
+	case OpDelete:
+		return "delete"
+	case OpMissed:
+		return "missed"
+	}
+	return "unknown"
+}
+
+// KeyEvent describes a committed change to a key of a named database.
+type KeyEvent struct {
+	Op     Op
+	DBName string
+	Key    []byte // owned by the receiver
+	Value  []byte // value written by an OpPut, owned by the receiver
+}
+
+// Subscribe registers ch to receive a KeyEvent for every key written or
+// deleted in the named database by the methods of DB, Bucket, and the other
+// helpers of this package. Transactions run with Update modify keys that the
+// package cannot see and produce no events.
+//
+// Events are sent once their transaction commits, in commit order, with a
+// non-blocking send. If ch is full the event is dropped and counted by
+// DroppedEvents, so ch should be buffered and drained promptly. Subscribing the
+// same channel twice has no effect.
+func (db *DB) Subscribe(dbName string, ch chan<- KeyEvent) error {
+	if ch == nil {
+		return errors.New("wrap: nil subscriber channel")
+	}
+	if _, err := db.getDBI(dbName); err != nil {
+		return err
+	}
+	db.subMu.Lock()
+	defer db.subMu.Unlock()
+	for _, c := range db.subs[dbName] {
+		if c == ch {
+			return nil
+		}
+	}
+	if db.subs == nil {
+		db.subs = make(map[string][]chan<- KeyEvent)
+	}
+	db.subs[dbName] = append(db.subs[dbName], ch)
+	return nil
+}
+
+// Unsubscribe removes ch from the subscribers of the named database. Events
+// already being delivered may still be sent on ch after Unsubscribe returns.
+func (db *DB) Unsubscribe(dbName string, ch chan<- KeyEvent) {
+	db.subMu.Lock()
+	defer db.subMu.Unlock()
+	subs := db.subs[dbName]
+	for i, c := range subs {
+		if c == ch {
+			// copy so a concurrent publish keeps a consistent slice
+			subs = append(append([]chan<- KeyEvent(nil), subs[:i]...), subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(db.subs, dbName)
+	} else {
+		db.subs[dbName] = subs
+	}
+}
+
+// DroppedEvents returns the number of events dropped because a subscriber
+// channel was full.
+func (db *DB) DroppedEvents() uint64 {
+	return atomic.LoadUint64(&db.dropped)
+}
+
+// watched reports whether the named database has subscribers or watchers.
+func (db *DB) watched(dbName string) bool {
+	db.subMu.RLock()
+	defer db.subMu.RUnlock()
+	return len(db.subs[dbName]) > 0 || len(db.watchers[dbName]) > 0
+}
+
+// event returns a list holding a single event for op on key, or nil if the
+// database has no subscribers.
+func (db *DB) event(op Op, dbName string, key, val []byte) *[]KeyEvent {
+	if !db.watched(dbName) {
+		return nil
+	}
+	return &[]KeyEvent{newKeyEvent(op, dbName, key, val)}
+}
+
+// addEvent appends an event to events if the database has subscribers.
+// events may be nil.
+func (db *DB) addEvent(events *[]KeyEvent, op Op, dbName string, key, val []byte) {
+	if events != nil && db.watched(dbName) {
+		*events = append(*events, newKeyEvent(op, dbName, key, val))
+	}
+}
+
+func newKeyEvent(op Op, dbName string, key, val []byte) KeyEvent {
+	ev := KeyEvent{Op: op, DBName: dbName, Key: copyBytes(key)}
+	if op == OpPut {
+		ev.Value = copyBytes(val)
+	}
+	return ev
+}
+
+// publish delivers committed events to their subscribers.
+func (db *DB) publish(events []KeyEvent) {
+	db.subMu.RLock()
+	defer db.subMu.RUnlock()
+	for _, ev := range events {
+		for _, ch := range db.subs[ev.DBName] {
+			select {
+			case ch <- ev:
+			default:
+				atomic.AddUint64(&db.dropped, 1)
+			}
+		}
+		for _, w := range db.watchers[ev.DBName] {
+			if bytes.HasPrefix(ev.Key, w.prefix) {
+				w.send(ev)
+			}
+		}
+	}
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,104 @@
+package wrap
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_Subscribe(t *testing.T) {
+	db := newTestDB(t, "a", "b")
+	ch := make(chan KeyEvent, 100)
+	if err := db.Subscribe("a", ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Subscribe("a", ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Subscribe("nope", ch); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+
+	var want []KeyEvent
+	for i := 0; i < 10; i++ {
+		key := []byte(fmt.Sprintf("k%d", i))
+		if err := db.Write("a", key, []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, KeyEvent{OpPut, "a", key, []byte("v")})
+		if i%3 == 0 {
+			if err := db.Delete("a", key); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, KeyEvent{OpDelete, "a", key, nil})
+		}
+	}
+	// other databases, failed transactions, and raw updates produce no events
+	mustWrite(t, db, "b", "x")
+	db.Delete("a", []byte("missing"))
+	db.Update(func(txn *lmdb.Txn) error {
+		return txn.Put(db.GetDBis()["a"], []byte("raw"), nil, 0)
+	})
+	if err := db.BatchAppend("a", []KV{{[]byte("z1"), nil}, {[]byte("z2"), nil}}); err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, KeyEvent{OpPut, "a", []byte("z1"), nil}, KeyEvent{OpPut, "a", []byte("z2"), nil})
+
+	for i, w := range want {
+		select {
+		case ev := <-ch:
+			if ev.Op != w.Op || ev.DBName != w.DBName || string(ev.Key) != string(w.Key) || string(ev.Value) != string(w.Value) {
+				t.Errorf("event %d: %v %s %q %q (!= %v %s %q %q)", i, ev.Op, ev.DBName, ev.Key, ev.Value, w.Op, w.DBName, w.Key, w.Value)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d not delivered", i)
+		}
+	}
+	select {
+	case ev := <-ch:
+		t.Errorf("unexpected event: %v %q", ev.Op, ev.Key)
+	default:
+	}
+
+	db.Unsubscribe("a", ch)
+	mustWrite(t, db, "a", "after")
+	select {
+	case ev := <-ch:
+		t.Errorf("event after unsubscribe: %q", ev.Key)
+	default:
+	}
+}
+
+func TestDB_Subscribe_dropped(t *testing.T) {
+	db := newTestDB(t, "a")
+	ch := make(chan KeyEvent, 1)
+	if err := db.Subscribe("a", ch); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, db, "a", "k1", "k2", "k3")
+	if n := db.DroppedEvents(); n != 2 {
+		t.Errorf("dropped: %d (!= 2)", n)
+	}
+	if ev := <-ch; string(ev.Key) != "k1" {
+		t.Errorf("first event: %q", ev.Key)
+	}
+}
+
+func TestDB_Subscribe_expiry(t *testing.T) {
+	db := newTestDB(t, "a")
+	ch := make(chan KeyEvent, 10)
+	db.Subscribe("a", ch)
+	if err := db.PutWithTTL("a", []byte("k"), nil, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.expire(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []Op{OpPut, OpDelete} {
+		if ev := <-ch; ev.Op != op || string(ev.Key) != "k" {
+			t.Errorf("event: %v %q (!= %v)", ev.Op, ev.Key, op)
+		}
+	}
+}
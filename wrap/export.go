@@ -0,0 +1,150 @@
+package wrap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// ErrInvalidExport is returned by Import when the stream was not written by
+// Export or by a version of it Import does not understand.
+var ErrInvalidExport = errors.New("invalid export stream")
+
+// export stream header
+const (
+	exportMagic   = "LMKV"
+	exportVersion = 1
+)
+
+// importChunkSize is the number of entries Import writes per transaction.
+const importChunkSize = 10000
+
+// Export writes every key/value pair of the named database to w in a simple
+// binary format read by Import. The stream starts with a magic string and a
+// version byte, followed by one record per pair in key order: a 4-byte key
+// length, the key, an 8-byte value length, and the value, lengths being
+// big-endian. The pairs are read in a single read transaction. Values are
+// written as stored, including the version headers of Versioned databases.
+func (db *DB) Export(dbName string, w io.Writer) error {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(exportMagic)
+	bw.WriteByte(exportVersion)
+	err = db.View(func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		txn.RawRead = true
+		var lens [8]byte
+		return scan(txn, dbi, nil, nil, ScanOptions{}, func(k, v []byte) error {
+			binary.BigEndian.PutUint32(lens[:4], uint32(len(k)))
+			bw.Write(lens[:4])
+			bw.Write(k)
+			binary.BigEndian.PutUint64(lens[:], uint64(len(v)))
+			bw.Write(lens[:])
+			// a failed write sticks to bw, stop early
+			_, err := bw.Write(v)
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Import writes the pairs of a stream produced by Export into the named
+// database, replacing the values of existing keys. The pairs are written in
+// transactions of 10,000, so a failed Import leaves the chunks written before
+// the failure in place. A stream not starting with the Export header is
+// rejected with an error wrapping ErrInvalidExport before anything is
+// written, and a truncated stream fails with io.ErrUnexpectedEOF.
+func (db *DB) Import(dbName string, r io.Reader) error {
+	return db.observeWrite(dbName, func() error {
+		if _, err := db.getDBI(dbName); err != nil {
+			return err
+		}
+		br := bufio.NewReader(r)
+		header := make([]byte, len(exportMagic)+1)
+		if _, err := io.ReadFull(br, header); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+		if string(header[:len(exportMagic)]) != exportMagic {
+			return fmt.Errorf("%w: bad magic %q", ErrInvalidExport, header[:len(exportMagic)])
+		}
+		if v := header[len(exportMagic)]; v != exportVersion {
+			return fmt.Errorf("%w: unsupported version %d", ErrInvalidExport, v)
+		}
+
+		chunk := make([]KV, 0, importChunkSize)
+		for {
+			kv, err := readExportRecord(br)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if chunk = append(chunk, kv); len(chunk) == importChunkSize {
+				if err := db.putBatch(dbName, chunk, 0); err != nil {
+					return err
+				}
+				chunk = chunk[:0]
+			}
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+		return db.putBatch(dbName, chunk, 0)
+	})
+}
+
+// readExportRecord reads one pair written by Export. It returns io.EOF only
+// at the end of the last record.
+func readExportRecord(r *bufio.Reader) (KV, error) {
+	var lens [8]byte
+	if _, err := io.ReadFull(r, lens[:4]); err != nil {
+		return KV{}, err
+	}
+	key, err := readExportBytes(r, uint64(binary.BigEndian.Uint32(lens[:4])))
+	if err != nil {
+		return KV{}, err
+	}
+	if _, err := io.ReadFull(r, lens[:]); err != nil {
+		return KV{}, noEOF(err)
+	}
+	val, err := readExportBytes(r, binary.BigEndian.Uint64(lens[:]))
+	if err != nil {
+		return KV{}, err
+	}
+	return KV{Key: key, Value: val}, nil
+}
+
+// readExportBytes reads n bytes, growing the buffer as they arrive so a
+// corrupt length cannot allocate more than the stream holds.
+func readExportBytes(r io.Reader, n uint64) ([]byte, error) {
+	if n > MapSize {
+		return nil, fmt.Errorf("%w: record of %d bytes", ErrInvalidExport, n)
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, noEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// noEOF turns io.EOF in the middle of a record into io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
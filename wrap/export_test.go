@@ -0,0 +1,84 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestDB_ExportImport(t *testing.T) {
+	db := newTestDB(t, "users", "other")
+	const n = 10000
+	pairs := make([]KV, n)
+	for i := range pairs {
+		pairs[i] = KV{Key: []byte(fmt.Sprintf("user:%05d", i)), Value: []byte(fmt.Sprintf("value %d", i))}
+	}
+	pairs[7].Value = nil
+	if err := db.BatchAppend("users", pairs); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := db.Export("users", &buf); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+	if err := db.Truncate("users"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Import("users", bytes.NewReader(stream)); err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := db.Scan("users", nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != n {
+		t.Fatalf("imported %d entries", len(kvs))
+	}
+	for i, kv := range kvs {
+		if !bytes.Equal(kv.Key, pairs[i].Key) || !bytes.Equal(kv.Value, pairs[i].Value) {
+			t.Fatalf("entry %d: %q=%q", i, kv.Key, kv.Value)
+		}
+	}
+
+	// importing into another database overwrites existing keys
+	mustWrite(t, db, "other", "user:00001")
+	if err := db.Import("other", bytes.NewReader(stream)); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.Read("other", []byte("user:00001")); err != nil || string(v) != "value 1" {
+		t.Errorf("overwritten: %q %v", v, err)
+	}
+}
+
+func TestDB_Import_invalid(t *testing.T) {
+	db := newTestDB(t, "users")
+	mustWrite(t, db, "users", "a", "b")
+	var buf bytes.Buffer
+	if err := db.Export("users", &buf); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+
+	for name, in := range map[string][]byte{
+		"empty":   nil,
+		"magic":   append([]byte("XXXX"), stream[4:]...),
+		"version": append(append([]byte(exportMagic), 9), stream[5:]...),
+	} {
+		if err := db.Import("users", bytes.NewReader(in)); !errors.Is(err, ErrInvalidExport) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if err := db.Import("users", bytes.NewReader(stream[:len(stream)-1])); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated: %v", err)
+	}
+	if err := db.Import("nope", bytes.NewReader(stream)); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+	if err := db.Export("nope", &buf); err != ErrDbNameNotFound {
+		t.Errorf("export unknown db: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package wrap
+
+import (
+	"encoding/binary"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// The DB methods store the values of a database framed according to its
+// options: behind an 8-byte version header if it is Versioned and followed by
+// a checksum if it is checksummed. Every method writing values frames them
+// with encodeValue and every method reading values strips the frame with
+// decodeValue, so the methods can be mixed freely on one database.
+
+// encodeValue returns value as stored under key in dbName, with the version
+// following the current version of key in txn.
+func (db *DB) encodeValue(txn *lmdb.Txn, dbi lmdb.DBI, dbName string, key, value []byte) ([]byte, error) {
+	var version uint64
+	if db.versioned(dbName) {
+		cur, err := db.currentVersion(txn, dbi, dbName, key)
+		if err != nil {
+			return nil, err
+		}
+		version = cur + 1
+	}
+	return db.frame(dbName, key, value, version), nil
+}
+
+// frame returns value as stored under key in dbName with version, which is
+// ignored unless dbName is Versioned.
+func (db *DB) frame(dbName string, key, value []byte, version uint64) []byte {
+	versioned, checksummed := db.versioned(dbName), db.checksummed(dbName)
+	if !versioned && !checksummed {
+		return value
+	}
+	n := len(value)
+	if versioned {
+		n += versionLen
+	}
+	if checksummed {
+		n += checksumLen
+	}
+	buf := make([]byte, 0, n)
+	if versioned {
+		var h [versionLen]byte
+		binary.BigEndian.PutUint64(h[:], version)
+		buf = append(buf, h[:]...)
+	}
+	buf = append(buf, value...)
+	if checksummed {
+		buf = appendChecksum(buf, key)
+	}
+	return buf
+}
+
+// decodeValue returns the value stored under key in dbName without its frame.
+// The result shares the memory of stored.
+func (db *DB) decodeValue(dbName string, key, stored []byte) ([]byte, error) {
+	value, _, err := db.decodeVersioned(dbName, key, stored)
+	return value, err
+}
+
+// decodeVersioned is decodeValue also returning the version of the value,
+// zero unless dbName is Versioned.
+func (db *DB) decodeVersioned(dbName string, key, stored []byte) (value []byte, version uint64, err error) {
+	value, err = db.unseal(dbName, key, stored)
+	if err != nil || !db.versioned(dbName) {
+		return value, 0, err
+	}
+	return splitVersion(key, value)
+}
+
+// decoding returns fn preceded by decodeValue for the scans of dbName.
+func (db *DB) decoding(dbName string, fn func(k, v []byte) error) func(k, v []byte) error {
+	if !db.versioned(dbName) && !db.checksummed(dbName) {
+		return fn
+	}
+	return func(k, v []byte) error {
+		v, err := db.decodeValue(dbName, k, v)
+		if err != nil {
+			return err
+		}
+		return fn(k, v)
+	}
+}
+
+// currentVersion returns the version of key in txn, zero if it is missing.
+func (db *DB) currentVersion(txn *lmdb.Txn, dbi lmdb.DBI, dbName string, key []byte) (uint64, error) {
+	stored, err := txn.Get(dbi, key)
+	if lmdb.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	_, version, err := db.decodeVersioned(dbName, key, stored)
+	return version, err
+}
@@ -0,0 +1,41 @@
+package wrap
+
+import "github.com/Data-Corruption/lmdb-go/lmdb"
+
+// HealthCheck verifies that the environment is readable by beginning a read
+// transaction and seeking a cursor in the first database passed to New, or in
+// the root database if there were none. It is cheap enough for liveness
+// probes and copies no value data.
+//
+// HealthCheck returns ErrDBClosed after Close and otherwise any error reported
+// by LMDB as-is. It checks the health of the environment only, not the
+// contents of every named database.
+func (db *DB) HealthCheck() error {
+	db.dbsMu.RLock()
+	probe := db.probe
+	db.dbsMu.RUnlock()
+	return db.view(func(txn *lmdb.Txn) error {
+		var dbi lmdb.DBI
+		var err error
+		if probe == "" {
+			dbi, err = txn.OpenRoot(0)
+		} else {
+			dbi, err = db.getDBI(probe)
+		}
+		if err != nil {
+			return err
+		}
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		txn.RawRead = true
+		_, _, err = cur.Get(nil, nil, lmdb.First)
+		if lmdb.IsNotFound(err) {
+			// an empty database is healthy
+			return nil
+		}
+		return err
+	})
+}
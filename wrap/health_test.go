@@ -0,0 +1,36 @@
+package wrap
+
+import "testing"
+
+func TestDB_HealthCheck(t *testing.T) {
+	db := newTestDB(t, "a", "b")
+	if err := db.HealthCheck(); err != nil {
+		t.Errorf("empty: %v", err)
+	}
+	mustWrite(t, db, "a", "k")
+	if err := db.HealthCheck(); err != nil {
+		t.Errorf("non-empty: %v", err)
+	}
+
+	root := newTestDB(t)
+	if err := root.HealthCheck(); err != nil {
+		t.Errorf("no named databases: %v", err)
+	}
+
+	db.Close()
+	if err := db.HealthCheck(); err != ErrDBClosed {
+		t.Errorf("closed: %v", err)
+	}
+}
+
+func BenchmarkDB_HealthCheck(b *testing.B) {
+	db := newTestDB(b, "a")
+	mustWrite(b, db, "a", "k")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.HealthCheck(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package wrap
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// CommitSummary describes a committed write transaction to
+// Options.OnAfterCommit.
+type CommitSummary struct {
+	// Duration is the time from beginning the transaction to its commit.
+	Duration time.Duration
+
+	// DBNames lists the databases written by the transaction when they are
+	// known: the database of a single database method like Write or
+	// UpdateDB, and the databases written through NamedTxn.Put and Del, in
+	// the order they were first written. It is nil for transactions run with
+	// Update that make no NamedTxn writes.
+	DBNames []string
+}
+
+// WithOnBeforeCommit sets Options.OnBeforeCommit.
+func WithOnBeforeCommit(fn func(tx *NamedTxn) error) Option {
+	return func(o *Options) { o.OnBeforeCommit = fn }
+}
+
+// WithOnAfterCommit sets Options.OnAfterCommit.
+func WithOnAfterCommit(fn func(summary CommitSummary)) Option {
+	return func(o *Options) { o.OnAfterCommit = fn }
+}
+
+// withBeforeCommit returns the transaction function of u, running the
+// OnBeforeCommit hook after it when one is set.
+func (db *DB) withBeforeCommit(u *updateOp) lmdb.TxnOp {
+	hook := db.opts.OnBeforeCommit
+	if hook == nil {
+		return u.op
+	}
+	return func(txn *lmdb.Txn) (err error) {
+		if err := u.op(txn); err != nil {
+			return err
+		}
+		// the writer owns u, so the hook's writes can be recorded in it
+		if u.events == nil {
+			u.events = &[]KeyEvent{}
+		}
+		if u.touched == nil {
+			u.touched = &[]string{}
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("wrap: OnBeforeCommit panicked: %v", r)
+			}
+		}()
+		return hook(&NamedTxn{NamedView: NamedView{db: db, txn: txn}, events: u.events, touched: u.touched})
+	}
+}
+
+// afterCommit runs the OnAfterCommit hook for the committed transaction of u.
+// A panic in the hook is logged so the writer goroutine survives it.
+func (db *DB) afterCommit(u *updateOp, d time.Duration) {
+	hook := db.opts.OnAfterCommit
+	if hook == nil {
+		return
+	}
+	summary := CommitSummary{Duration: d}
+	if u.dbName != "" {
+		summary.DBNames = append(summary.DBNames, u.dbName)
+	}
+	if u.touched != nil {
+		for _, n := range *u.touched {
+			if n != u.dbName {
+				summary.DBNames = append(summary.DBNames, n)
+			}
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("wrap: OnAfterCommit panicked: %v", r)
+		}
+	}()
+	hook(summary)
+}
@@ -0,0 +1,104 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_commitHooks(t *testing.T) {
+	errVeto := errors.New("veto")
+	var mu sync.Mutex
+	var summaries []CommitSummary
+	before := func(tx *NamedTxn) error {
+		v, err := tx.Get("a", []byte("veto"))
+		if err == nil && string(v) == "panic" {
+			panic("boom")
+		}
+		if err == nil {
+			return errVeto
+		}
+		return tx.Put("meta", []byte("updatedAt"), []byte("now"), 0)
+	}
+	after := func(s CommitSummary) {
+		mu.Lock()
+		summaries = append(summaries, s)
+		mu.Unlock()
+		if len(s.DBNames) > 0 && s.DBNames[0] == "panic" {
+			panic("after")
+		}
+	}
+	db, _, err := New(t.TempDir(), []string{"a", "meta", "panic"}, WithOnBeforeCommit(before), WithOnAfterCommit(after))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mustWrite(t, db, "a", "k")
+	if v, err := db.Read("meta", []byte("updatedAt")); err != nil || string(v) != "now" {
+		t.Errorf("hook write: %q %v", v, err)
+	}
+	err = db.UpdateNamed(func(tx *NamedTxn) error {
+		return tx.Put("a", []byte("veto"), []byte("yes"), 0)
+	})
+	if err != errVeto {
+		t.Errorf("veto: %v", err)
+	}
+	if _, err := db.Read("a", []byte("veto")); !lmdb.IsNotFound(err) {
+		t.Errorf("vetoed write committed: %v", err)
+	}
+
+	// panics in hooks do not stop the writer
+	err = db.Update(func(txn *lmdb.Txn) error {
+		dbi, _ := db.DBI("a")
+		return txn.Put(dbi, []byte("veto"), []byte("panic"), 0)
+	})
+	if err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("panicking before hook: %v", err)
+	}
+	mustWrite(t, db, "panic", "k")
+	mustWrite(t, db, "a", "k2")
+
+	mu.Lock()
+	defer mu.Unlock()
+	var got []string
+	for _, s := range summaries {
+		if s.Duration <= 0 {
+			t.Errorf("duration: %v", s.Duration)
+		}
+		got = append(got, fmt.Sprint(s.DBNames))
+	}
+	// failed transactions are not reported
+	want := []string{"[a meta]", "[panic meta]", "[a meta]"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("summaries: %v (!= %v)", got, want)
+	}
+}
+
+func TestDB_commitHooks_update(t *testing.T) {
+	var names [][]string
+	db, _, err := New(t.TempDir(), []string{"a", "b"}, WithOnAfterCommit(func(s CommitSummary) {
+		names = append(names, s.DBNames)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.Update(func(txn *lmdb.Txn) error { return nil })
+	db.UpdateNamed(func(tx *NamedTxn) error {
+		for _, n := range []string{"b", "a", "b"} {
+			if err := tx.Put(n, []byte("k"), nil, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if fmt.Sprint(names) != "[[] [b a]]" {
+		t.Errorf("names: %v", names)
+	}
+}
@@ -0,0 +1,200 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"runtime"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// ErrIteratorClosed is returned by Iterator.Err after the iterator is closed.
+var ErrIteratorClosed = errors.New("iterator is closed")
+
+// IterOpts configures an Iterator returned by NewIterator.
+type IterOpts struct {
+	// Prefix restricts the iterator to keys starting with Prefix.
+	Prefix []byte
+
+	// RawRead makes Key and Value return slices pointing directly into the
+	// memory map. Such slices are readonly and only valid until the iterator
+	// moves or is closed.
+	RawRead bool
+}
+
+// iterator positions
+const (
+	iterUnpositioned = iota
+	iterValid
+	iterEOF // moved past the last key
+	iterBOF // moved before the first key
+)
+
+// Iterator is a cursor over a single named database that observes a
+// consistent snapshot for its whole lifetime. An Iterator holds a read
+// transaction open until Close is called, so it must always be closed. An
+// Iterator must not be used from multiple goroutines at the same time.
+//
+// A new Iterator is not positioned: the first call to Next moves to the first
+// key and the first call to Prev moves to the last key.
+type Iterator struct {
+	db     *DB
+	name   string
+	txn    *lmdb.Txn
+	cur    *lmdb.Cursor
+	lo, hi []byte
+	pos    int
+	key    []byte
+	val    []byte
+	err    error
+}
+
+// NewIterator opens an Iterator over the named database. The Iterator must be
+// closed to release its read transaction, and while it is open DB.Close fails
+// with ErrHandlesOpen.
+func (db *DB) NewIterator(dbName string, opts IterOpts) (*Iterator, error) {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return nil, err
+	}
+	txn, err := db.beginSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.refreshDBI(dbName, &dbi); err != nil {
+		db.endSnapshot(txn)
+		return nil, err
+	}
+	txn.RawRead = opts.RawRead
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		db.endSnapshot(txn)
+		return nil, err
+	}
+	it := &Iterator{db: db, name: dbName, txn: txn, cur: cur, lo: opts.Prefix, hi: prefixEnd(opts.Prefix)}
+	runtime.SetFinalizer(it, func(it *Iterator) {
+		log.Printf("wrap: closing unreachable iterator %p, call Close when done", it)
+		it.Close()
+	})
+	return it, nil
+}
+
+// Seek moves the iterator to the first key not less than key and reports
+// whether the iterator is positioned at a valid pair.
+func (it *Iterator) Seek(key []byte) bool {
+	if !it.checkOpen() {
+		return false
+	}
+	if bytes.Compare(key, it.lo) < 0 {
+		key = it.lo
+	}
+	return it.move(seekFirst(it.cur, key))
+}
+
+// Next moves the iterator to the next key and reports whether the iterator is
+// positioned at a valid pair.
+func (it *Iterator) Next() bool {
+	if !it.checkOpen() {
+		return false
+	}
+	switch it.pos {
+	case iterUnpositioned, iterBOF:
+		return it.move(seekFirst(it.cur, it.lo))
+	case iterValid:
+		return it.move(it.cur.Get(nil, nil, lmdb.Next))
+	}
+	return false
+}
+
+// Prev moves the iterator to the previous key and reports whether the
+// iterator is positioned at a valid pair.
+func (it *Iterator) Prev() bool {
+	if !it.checkOpen() {
+		return false
+	}
+	switch it.pos {
+	case iterUnpositioned, iterEOF:
+		return it.movePrev(seekLast(it.cur, it.hi))
+	case iterValid:
+		return it.movePrev(it.cur.Get(nil, nil, lmdb.Prev))
+	}
+	return false
+}
+
+// move records the result of a forward cursor movement.
+func (it *Iterator) move(k, v []byte, err error) bool {
+	if err == nil && len(it.hi) > 0 && bytes.Compare(k, it.hi) >= 0 {
+		err = ErrNotFound
+	}
+	return it.set(k, v, err, iterEOF)
+}
+
+// movePrev records the result of a backward cursor movement.
+func (it *Iterator) movePrev(k, v []byte, err error) bool {
+	if err == nil && bytes.Compare(k, it.lo) < 0 {
+		err = ErrNotFound
+	}
+	return it.set(k, v, err, iterBOF)
+}
+
+func (it *Iterator) set(k, v []byte, err error, end int) bool {
+	it.key, it.val = nil, nil
+	if err == nil {
+		v, err = it.db.decodeValue(it.name, k, v)
+	}
+	switch {
+	case err == nil:
+		it.key, it.val, it.pos = k, v, iterValid
+	case lmdb.IsNotFound(err):
+		it.pos = end
+	default:
+		it.err, it.pos = err, iterUnpositioned
+	}
+	return it.pos == iterValid
+}
+
+// Valid reports whether the iterator is positioned at a valid pair.
+func (it *Iterator) Valid() bool {
+	return it.err == nil && it.pos == iterValid
+}
+
+// Key returns the key at the current position, or nil if the iterator is not
+// valid.
+func (it *Iterator) Key() []byte {
+	return it.key
+}
+
+// Value returns the value at the current position, or nil if the iterator is
+// not valid.
+func (it *Iterator) Value() []byte {
+	return it.val
+}
+
+// Err returns the first error encountered by the iterator, reaching either end
+// of the database is not an error. Err returns ErrIteratorClosed after Close.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close releases the iterator's read transaction. Close is idempotent.
+func (it *Iterator) Close() error {
+	if it.txn == nil {
+		return nil
+	}
+	runtime.SetFinalizer(it, nil)
+	it.cur.Close()
+	it.db.endSnapshot(it.txn)
+	it.cur, it.txn = nil, nil
+	it.key, it.val = nil, nil
+	it.err = ErrIteratorClosed
+	return nil
+}
+
+func (it *Iterator) checkOpen() bool {
+	if it.txn == nil {
+		it.err = ErrIteratorClosed
+		return false
+	}
+	return it.err == nil
+}
@@ -0,0 +1,116 @@
+package wrap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func iterKeys(it *Iterator, step func() bool) []string {
+	keys := []string{}
+	for step() {
+		keys = append(keys, string(it.Key()))
+	}
+	return keys
+}
+
+func TestIterator(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a", "b", "c", "d")
+
+	it, err := db.NewIterator("a", IterOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	if it.Valid() {
+		t.Errorf("new iterator is valid")
+	}
+	if keys := iterKeys(it, it.Next); !reflect.DeepEqual(keys, []string{"a", "b", "c", "d"}) {
+		t.Errorf("next: %q", keys)
+	}
+	if keys := iterKeys(it, it.Prev); !reflect.DeepEqual(keys, []string{"d", "c", "b", "a"}) {
+		t.Errorf("prev: %q", keys)
+	}
+	if !it.Seek([]byte("bb")) || string(it.Key()) != "c" || string(it.Value()) != "v:c" {
+		t.Errorf("seek: %q=%q", it.Key(), it.Value())
+	}
+	if !it.Prev() || string(it.Key()) != "b" {
+		t.Errorf("prev after seek: %q", it.Key())
+	}
+	if it.Seek([]byte("e")) {
+		t.Errorf("seek past end: %q", it.Key())
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("err: %v", err)
+	}
+}
+
+func TestIterator_prefix(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a", "p1", "p2", "p3", "q")
+
+	it, err := db.NewIterator("a", IterOpts{Prefix: []byte("p")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	if keys := iterKeys(it, it.Next); !reflect.DeepEqual(keys, []string{"p1", "p2", "p3"}) {
+		t.Errorf("next: %q", keys)
+	}
+	if keys := iterKeys(it, it.Prev); !reflect.DeepEqual(keys, []string{"p3", "p2", "p1"}) {
+		t.Errorf("prev: %q", keys)
+	}
+	if !it.Seek([]byte("a")) || string(it.Key()) != "p1" {
+		t.Errorf("seek before prefix: %q", it.Key())
+	}
+}
+
+func TestIterator_snapshot(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a")
+
+	it, err := db.NewIterator("a", IterOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	mustWrite(t, db, "a", "b")
+	if keys := iterKeys(it, it.Next); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Errorf("iterator observed a later write: %q", keys)
+	}
+}
+
+func TestIterator_Close(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a")
+
+	it, err := db.NewIterator("a", IterOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	if err := it.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := it.Close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+	if it.Next() || it.Prev() || it.Seek([]byte("a")) || it.Valid() {
+		t.Errorf("closed iterator moved")
+	}
+	if it.Key() != nil {
+		t.Errorf("closed iterator key: %q", it.Key())
+	}
+	if err := it.Err(); err != ErrIteratorClosed {
+		t.Errorf("err: %v", err)
+	}
+
+	if _, err := db.NewIterator("b", IterOpts{}); err != ErrDbNameNotFound {
+		t.Errorf("unknown name: %v", err)
+	}
+}
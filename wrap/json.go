@@ -0,0 +1,60 @@
+package wrap
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// WriteJSON stores the JSON encoding of v under key.
+func (db *DB) WriteJSON(dbName string, key []byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return db.Write(dbName, key, data)
+}
+
+// ReadJSON reads the value stored under key and decodes it into out using
+// json.Unmarshal. A decoding error is wrapped with the database name and key.
+func (db *DB) ReadJSON(dbName string, key []byte, out any) error {
+	data, err := db.Read(dbName, key)
+	if err != nil {
+		return err
+	}
+	return unmarshalJSON(dbName, key, data, out)
+}
+
+// ReadJSONView is like ReadJSON but decodes directly from the memory map inside
+// the read transaction, saving a copy of the stored value. encoding/json never
+// retains the input, so out is safe to use after ReadJSONView returns.
+func (db *DB) ReadJSONView(dbName string, key []byte, out any) error {
+	return db.observeRead(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		return db.view(func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			txn.RawRead = true
+			data, err := txn.Get(dbi, key)
+			if err == nil {
+				data, err = db.decodeValue(dbName, key, data)
+			}
+			if err != nil {
+				return err
+			}
+			return unmarshalJSON(dbName, key, data, out)
+		})
+	})
+}
+
+func unmarshalJSON(dbName string, key, data []byte, out any) error {
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s: key %q: %w", dbName, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package wrap
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_JSON(t *testing.T) {
+	db := newTestDB(t, "a")
+	in := testUser{Name: "ann", Age: 30}
+	if err := db.WriteJSON("a", []byte("u1"), in); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, read := range []func(string, []byte, any) error{db.ReadJSON, db.ReadJSONView} {
+		var out testUser
+		if err := read("a", []byte("u1"), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out != in {
+			t.Errorf("read: %+v", out)
+		}
+		if err := read("a", []byte("missing"), &out); !lmdb.IsNotFound(err) {
+			t.Errorf("missing key: %v", err)
+		}
+	}
+
+	// strings decoded from the memory map must not alias it
+	var m map[string]any
+	if err := db.ReadJSONView("a", []byte("u1"), &m); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, db, "a", "u1")
+	if m["name"] != "ann" {
+		t.Errorf("decoded value changed: %v", m)
+	}
+}
+
+func TestDB_ReadJSON_badRecord(t *testing.T) {
+	db := newTestDB(t, "a")
+	if err := db.Write("a", []byte("bad"), []byte("{not json")); err != nil {
+		t.Fatal(err)
+	}
+	var out testUser
+	for _, read := range []func(string, []byte, any) error{db.ReadJSON, db.ReadJSONView} {
+		err := read("a", []byte("bad"), &out)
+		var serr *json.SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("error: %v", err)
+		}
+		if !strings.Contains(err.Error(), `"bad"`) {
+			t.Errorf("error does not name the key: %v", err)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package wrap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// jsonlRecord is one line written by ExportJSONL. Exactly one of the value
+// fields is set.
+type jsonlRecord struct {
+	DB        string          `json:"db"`
+	Key       []byte          `json:"key"`                  // base64
+	Value     *string         `json:"value,omitempty"`      // valid UTF-8
+	ValueB64  []byte          `json:"value_b64,omitempty"`  // any other bytes, base64
+	ValueJSON json.RawMessage `json:"value_json,omitempty"` // decoded with the database's Codec
+}
+
+// ExportOpts controls ExportJSONL.
+type ExportOpts struct {
+	// Prefix restricts the export to keys starting with Prefix.
+	Prefix []byte
+
+	// Decode decodes the values of databases with a Codec, see
+	// DBOptions.Codec, and writes them as JSON instead of as stored.
+	Decode bool
+}
+
+// ExportJSONL writes the records of the named databases to w as
+// newline-delimited JSON and returns the number of records written. An empty
+// dbNames exports every registered database in name order. Each line is an
+// object with the database name in "db", the key in base64 in "key", and the
+// value in "value" if it is valid UTF-8, in base64 in "value_b64" otherwise,
+// or, with opts.Decode, decoded by the database's Codec in "value_json".
+//
+// Each database is read in its own read transaction, so each is exported
+// from a consistent snapshot. Values are written as stored, including the
+// version headers of Versioned databases and the checksums of checksummed
+// ones, except for the values decoded with opts.Decode.
+func (db *DB) ExportJSONL(w io.Writer, dbNames []string, opts ExportOpts) (int64, error) {
+	names, dbis, err := db.resolveDBs(dbNames)
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	var n int64
+	for i, name := range names {
+		var codec Codec
+		if opts.Decode {
+			codec = db.codec(name)
+		}
+		err := db.View(func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(name, &dbis[i]); err != nil {
+				return err
+			}
+			txn.RawRead = codec == nil
+			return scan(txn, dbis[i], opts.Prefix, prefixEnd(opts.Prefix), ScanOptions{}, func(k, v []byte) error {
+				rec := jsonlRecord{DB: name, Key: k}
+				if codec != nil {
+					v, err := db.decodeValue(name, k, v)
+					if err != nil {
+						return err
+					}
+					var decoded any
+					if err := codec.Unmarshal(v, &decoded); err != nil {
+						return fmt.Errorf("%s: key %q: %w", name, k, err)
+					}
+					data, err := json.Marshal(decoded)
+					if err != nil {
+						return fmt.Errorf("%s: key %q: %w", name, k, err)
+					}
+					rec.ValueJSON = data
+				} else if utf8.Valid(v) {
+					s := string(v)
+					rec.Value = &s
+				} else {
+					rec.ValueB64 = v
+				}
+				if err := enc.Encode(&rec); err != nil {
+					return err
+				}
+				n++
+				return nil
+			})
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// ConflictPolicy selects what ImportJSONL does with records whose key already
+// exists.
+type ConflictPolicy uint8
+
+const (
+	ConflictOverwrite ConflictPolicy = iota // replace the stored value
+	ConflictSkip                            // keep the stored value and count the record as skipped
+	ConflictFail                            // keep the stored value and count the record as failed
+)
+
+// ImportOpts controls ImportJSONL.
+type ImportOpts struct {
+	// Conflict is the policy for keys that already exist.
+	Conflict ConflictPolicy
+
+	// CreateDBs creates and registers unknown databases with AddDB instead
+	// of failing their records.
+	CreateDBs bool
+
+	// ChunkSize is the number of records written per transaction. Zero
+	// means 10,000.
+	ChunkSize int
+}
+
+// ImportStats counts the records read by ImportJSONL.
+type ImportStats struct {
+	Inserted int64 // records written
+	Skipped  int64 // records of existing keys kept by ConflictSkip
+	Failed   int64 // malformed records and records that could not be written
+
+	// FirstErrorLine is the line number of the first failed record, zero if
+	// none failed.
+	FirstErrorLine int
+}
+
+// importFailure is the failure of one line of an ImportJSONL stream.
+type importFailure struct {
+	line int
+	err  error
+}
+
+// ImportJSONL reads records in the format written by ExportJSONL and writes
+// them in transactions of opts.ChunkSize records. Values exported with
+// ExportOpts.Decode are encoded again with the database's Codec and stored
+// like Write stores them, the others are stored as exported.
+//
+// A record that cannot be imported, because it is malformed, names an unknown
+// database, or conflicts with an existing key under ConflictFail, is counted
+// as failed and the import goes on with the next record. ImportJSONL then
+// returns the error of the first failure along with its line number. An error
+// reading r or committing a chunk stops the import, the chunks committed
+// before it stay written.
+func (db *DB) ImportJSONL(r io.Reader, opts ImportOpts) (ImportStats, error) {
+	var stats ImportStats
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = importChunkSize
+	}
+	var first error
+	fail := func(line int, err error) {
+		stats.Failed++
+		// conflicts are found after the later lines of their chunk are read
+		if first == nil || line < stats.FirstErrorLine {
+			first = fmt.Errorf("line %d: %w", line, err)
+			stats.FirstErrorLine = line
+		}
+	}
+
+	br := bufio.NewReader(r)
+	chunk := make([]jsonlImport, 0, chunkSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		failed, err := db.importChunk(chunk, opts.Conflict, &stats)
+		if err != nil {
+			stats.Failed += int64(len(chunk))
+			return err
+		}
+		for _, f := range failed {
+			fail(f.line, f.err)
+		}
+		chunk = chunk[:0]
+		return nil
+	}
+	for line := 1; ; line++ {
+		text, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return stats, err
+		}
+		if len(bytes.TrimSpace(text)) > 0 {
+			rec, rerr := db.decodeJSONL(text, opts.CreateDBs)
+			if rerr != nil {
+				fail(line, rerr)
+			} else if chunk = append(chunk, jsonlImport{rec, line}); len(chunk) == chunkSize {
+				if err := flush(); err != nil {
+					return stats, fmt.Errorf("line %d: %w", line, err)
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+	return stats, first
+}
+
+// jsonlImport is a decoded record and its line number.
+type jsonlImport struct {
+	kv   namedKV
+	line int
+}
+
+// namedKV is a key/value pair of a named database.
+type namedKV struct {
+	db         string
+	key, value []byte
+	decoded    bool // value is not framed, see encodeValue
+}
+
+// decodeJSONL decodes one line written by ExportJSONL.
+func (db *DB) decodeJSONL(line []byte, create bool) (namedKV, error) {
+	var rec jsonlRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return namedKV{}, err
+	}
+	if len(rec.Key) == 0 {
+		return namedKV{}, ErrEmptyKey
+	}
+	if _, ok := db.lookup(rec.DB); !ok {
+		if !create {
+			return namedKV{}, fmt.Errorf("%w: %q", ErrDbNameNotFound, rec.DB)
+		}
+		if err := db.AddDB(rec.DB); err != nil && err != ErrDuplicateDbName {
+			return namedKV{}, err
+		}
+	}
+	kv := namedKV{db: rec.DB, key: rec.Key}
+	switch {
+	case rec.Value != nil:
+		kv.value = []byte(*rec.Value)
+	case rec.ValueJSON != nil:
+		c := db.codec(rec.DB)
+		if c == nil {
+			return namedKV{}, fmt.Errorf("%w: %q: cannot import value_json", ErrNoCodec, rec.DB)
+		}
+		var decoded any
+		if err := json.Unmarshal(rec.ValueJSON, &decoded); err != nil {
+			return namedKV{}, err
+		}
+		data, err := c.Marshal(decoded)
+		if err != nil {
+			return namedKV{}, err
+		}
+		kv.value, kv.decoded = data, true
+	case rec.ValueB64 != nil:
+		kv.value = rec.ValueB64
+	default:
+		return namedKV{}, errors.New("record has no value")
+	}
+	return kv, nil
+}
+
+// importChunk writes chunk in one transaction and returns the records that
+// failed because of a conflict.
+func (db *DB) importChunk(chunk []jsonlImport, policy ConflictPolicy, stats *ImportStats) ([]importFailure, error) {
+	var failed []importFailure
+	var inserted, skipped int64
+	events := &[]KeyEvent{}
+	err := db.observeWrite("", func() error {
+		return db.updateEvents("", func(txn *lmdb.Txn) error {
+			var flags uint
+			if policy != ConflictOverwrite {
+				flags = lmdb.NoOverwrite
+			}
+			for _, rec := range chunk {
+				dbi, err := db.getDBI(rec.kv.db)
+				if err != nil {
+					return err
+				}
+				stored := rec.kv.value
+				if rec.kv.decoded {
+					if stored, err = db.encodeValue(txn, dbi, rec.kv.db, rec.kv.key, stored); err != nil {
+						return err
+					}
+				}
+				err = txn.Put(dbi, rec.kv.key, stored, flags)
+				if lmdb.IsErrno(err, lmdb.KeyExist) {
+					if policy == ConflictSkip {
+						skipped++
+					} else {
+						failed = append(failed, importFailure{rec.line, err})
+					}
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				if err := db.recordWrite(txn, OpPut, rec.kv.db, rec.kv.key, stored); err != nil {
+					return err
+				}
+				db.addEvent(events, OpPut, rec.kv.db, rec.kv.key, rec.kv.value)
+				inserted++
+			}
+			return nil
+		}, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.Inserted += inserted
+	stats.Skipped += skipped
+	return failed, nil
+}
@@ -0,0 +1,180 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_ExportJSONL(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"users", "docs"}, WithDBOptions("docs", DBOptions{Codec: jsonCodec{}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mustWrite(t, db, "users", "t1:a", "t2:b")
+	if err := db.Write("users", []byte("t1:bin"), []byte{0xff, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.WriteAny("docs", []byte("d"), map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	n, err := db.ExportJSONL(&buf, nil, ExportOpts{})
+	if err != nil || n != 4 {
+		t.Fatalf("export: %d %v", n, err)
+	}
+	want := `{"db":"docs","key":"ZA==","value":"{\"n\":1}"}
+{"db":"users","key":"dDE6YQ==","value":"v:t1:a"}
+{"db":"users","key":"dDE6Ymlu","value_b64":"/wA="}
+{"db":"users","key":"dDI6Yg==","value":"v:t2:b"}
+`
+	if buf.String() != want {
+		t.Errorf("export:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	n, err = db.ExportJSONL(&buf, []string{"users", "docs"}, ExportOpts{Prefix: []byte("t2:"), Decode: true})
+	if err != nil || n != 1 || !strings.Contains(buf.String(), `"dDI6Yg=="`) {
+		t.Errorf("prefix export: %d %v\n%s", n, err, buf.String())
+	}
+	buf.Reset()
+	if _, err := db.ExportJSONL(&buf, []string{"docs"}, ExportOpts{Decode: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"db":"docs","key":"ZA==","value_json":{"n":1}}` + "\n"; buf.String() != want {
+		t.Errorf("decoded export: %s", buf.String())
+	}
+	if _, err := db.ExportJSONL(&buf, []string{"nope"}, ExportOpts{}); err == nil {
+		t.Errorf("unknown db exported")
+	}
+}
+
+func TestDB_ImportJSONL(t *testing.T) {
+	opts := []Option{WithDBOptions("docs", DBOptions{Codec: jsonCodec{}})}
+	src, _, err := New(t.TempDir(), []string{"users", "docs"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for i := 0; i < 25; i++ {
+		mustWrite(t, src, "users", fmt.Sprintf("k%02d", i))
+	}
+	if err := src.Write("users", []byte("bin"), []byte{0xff}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.WriteAny("docs", []byte("d"), map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := src.ExportJSONL(&buf, nil, ExportOpts{Decode: true}); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.String()
+
+	dst, _, err := New(t.TempDir(), []string{"docs"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if _, err := dst.ImportJSONL(strings.NewReader(stream), ImportOpts{}); !errors.Is(err, ErrDbNameNotFound) {
+		t.Errorf("unknown db: %v", err)
+	}
+	stats, err := dst.ImportJSONL(strings.NewReader(stream), ImportOpts{CreateDBs: true, ChunkSize: 10})
+	if err != nil || stats.Inserted != 27 || stats.Failed != 0 {
+		t.Fatalf("import: %+v %v", stats, err)
+	}
+	diff, err := Diff(src, dst, "users")
+	if err != nil || len(diff.Added)+len(diff.Removed)+len(diff.Modified) != 0 {
+		t.Errorf("users differ: %+v %v", diff, err)
+	}
+	var doc map[string]int
+	if err := dst.ReadAny("docs", []byte("d"), &doc); err != nil || doc["n"] != 1 {
+		t.Errorf("decoded value: %v %v", doc, err)
+	}
+
+	mustWrite(t, dst, "users", "new")
+	if err := dst.Write("users", []byte("k03"), []byte("local")); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = dst.ImportJSONL(strings.NewReader(stream), ImportOpts{Conflict: ConflictSkip})
+	if err != nil || stats.Inserted != 0 || stats.Skipped != 27 {
+		t.Errorf("skip: %+v %v", stats, err)
+	}
+	if v, _ := dst.Read("users", []byte("k03")); string(v) != "local" {
+		t.Errorf("skipped key overwritten: %q", v)
+	}
+
+	in := stream + "not json\n" + `{"db":"users","key":"bmV3MQ==","value":"x"}` + "\n"
+	stats, err = dst.ImportJSONL(strings.NewReader(in), ImportOpts{Conflict: ConflictFail})
+	var opErr *lmdb.OpError
+	if !errors.As(err, &opErr) || opErr.Errno != lmdb.KeyExist || !strings.HasPrefix(err.Error(), "line 1:") {
+		t.Errorf("fail policy: %v", err)
+	}
+	if stats.Inserted != 1 || stats.Failed != 28 || stats.FirstErrorLine != 1 {
+		t.Errorf("fail policy: %+v", stats)
+	}
+	if _, err := dst.Read("users", []byte("new1")); err != nil {
+		t.Errorf("record after failures: %v", err)
+	}
+
+	if _, err := dst.ImportJSONL(strings.NewReader(stream), ImportOpts{}); err != nil {
+		t.Errorf("overwrite: %v", err)
+	}
+	if v, _ := dst.Read("users", []byte("k03")); string(v) != "v:k03" {
+		t.Errorf("overwritten value: %q", v)
+	}
+}
+
+func TestDB_JSONL_checksum(t *testing.T) {
+	opts := []Option{
+		WithDBOptions("docs", DBOptions{Codec: jsonCodec{}, Checksum: ChecksumCRC32C}),
+		WithDBOptions("versions", DBOptions{Codec: jsonCodec{}, Checksum: ChecksumCRC32C, Versioned: true}),
+	}
+	src, _, err := New(t.TempDir(), []string{"docs", "versions"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for _, name := range []string{"docs", "versions"} {
+		if err := src.WriteAny(name, []byte("d"), map[string]int{"n": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := src.ExportJSONL(&buf, nil, ExportOpts{Decode: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"db":"docs","key":"ZA==","value_json":{"n":1}}
+{"db":"versions","key":"ZA==","value_json":{"n":1}}
+`
+	if buf.String() != want {
+		t.Errorf("decoded export:\n%s", buf.String())
+	}
+
+	dst, _, err := New(t.TempDir(), []string{"docs", "versions"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if stats, err := dst.ImportJSONL(&buf, ImportOpts{}); err != nil || stats.Inserted != 2 {
+		t.Fatalf("import: %+v %v", stats, err)
+	}
+	for _, name := range []string{"docs", "versions"} {
+		var doc map[string]int
+		if err := dst.ReadAny(name, []byte("d"), &doc); err != nil || doc["n"] != 1 {
+			t.Errorf("%s: decoded value: %v %v", name, doc, err)
+		}
+	}
+	if _, ver, err := dst.ReadVersioned("versions", []byte("d")); err != nil || ver != 1 {
+		t.Errorf("version: %d %v", ver, err)
+	}
+	if r, err := dst.Verify(VerifyOpts{}); err != nil || !r.OK() {
+		t.Errorf("verify: %+v %v", r, err)
+	}
+}
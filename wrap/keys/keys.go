@@ -0,0 +1,117 @@
+// Package keys encodes composite keys whose byte-wise order matches the order
+// of their components.
+//
+// LMDB sorts keys by comparing their bytes, so a key built from several parts
+// only range-scans correctly if every part is encoded in an order-preserving
+// way. Integers are written big-endian, with the sign bit of signed integers
+// inverted, and variable-length strings are 0x00-escaped and terminated so
+// that a shorter string sorts before any string extending it.
+//
+//	key := keys.AppendUint64(nil, uint64(ts.UnixNano()))
+//	key = keys.AppendString(key, id)
+//
+//	ts, rest, err := keys.Uint64(key)
+//	id, rest, err := keys.String(rest)
+//
+// Keys must be decoded with the same sequence of types they were encoded with.
+package keys
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrMalformed is returned when a key cannot be decoded as the requested type.
+var ErrMalformed = errors.New("malformed key")
+
+// escape bytes used by the variable-length encoding. A literal 0x00 is written
+// as 0x00 0xFF and the value is terminated by 0x00 0x01.
+const (
+	escape      = 0x00
+	escapedZero = 0xFF
+	terminator  = 0x01
+)
+
+// AppendUint64 appends the 8-byte big-endian encoding of v to dst.
+func AppendUint64(dst []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(dst, b[:]...)
+}
+
+// AppendInt64 appends an 8-byte encoding of v to dst that sorts negative
+// values before positive ones.
+func AppendInt64(dst []byte, v int64) []byte {
+	return AppendUint64(dst, uint64(v)^(1<<63))
+}
+
+// AppendString appends the escaped, terminated encoding of s to dst.
+func AppendString(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		dst = appendEscaped(dst, s[i])
+	}
+	return append(dst, escape, terminator)
+}
+
+// AppendBytes appends the escaped, terminated encoding of b to dst.
+func AppendBytes(dst, b []byte) []byte {
+	for _, c := range b {
+		dst = appendEscaped(dst, c)
+	}
+	return append(dst, escape, terminator)
+}
+
+func appendEscaped(dst []byte, c byte) []byte {
+	if c == escape {
+		return append(dst, escape, escapedZero)
+	}
+	return append(dst, c)
+}
+
+// Uint64 decodes a value written by AppendUint64 from the front of key and
+// returns it along with the remaining bytes.
+func Uint64(key []byte) (v uint64, rest []byte, err error) {
+	if len(key) < 8 {
+		return 0, key, ErrMalformed
+	}
+	return binary.BigEndian.Uint64(key), key[8:], nil
+}
+
+// Int64 decodes a value written by AppendInt64 from the front of key and
+// returns it along with the remaining bytes.
+func Int64(key []byte) (v int64, rest []byte, err error) {
+	u, rest, err := Uint64(key)
+	return int64(u ^ (1 << 63)), rest, err
+}
+
+// String decodes a value written by AppendString from the front of key and
+// returns it along with the remaining bytes.
+func String(key []byte) (s string, rest []byte, err error) {
+	b, rest, err := Bytes(key)
+	return string(b), rest, err
+}
+
+// Bytes decodes a value written by AppendBytes from the front of key and
+// returns a newly allocated copy of it along with the remaining bytes.
+func Bytes(key []byte) (b []byte, rest []byte, err error) {
+	b = make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		if key[i] != escape {
+			b = append(b, key[i])
+			continue
+		}
+		if i+1 == len(key) {
+			break
+		}
+		switch key[i+1] {
+		case terminator:
+			return b, key[i+2:], nil
+		case escapedZero:
+			b = append(b, escape)
+			i++
+		default:
+			return nil, key, ErrMalformed
+		}
+	}
+	return nil, key, ErrMalformed
+}
@@ -0,0 +1,149 @@
+package keys
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestUint64_roundTrip(t *testing.T) {
+	for _, v := range []uint64{0, 1, 255, 256, math.MaxUint32, math.MaxUint64} {
+		key := AppendUint64([]byte("p"), v)
+		got, rest, err := Uint64(key[1:])
+		if err != nil || got != v || len(rest) != 0 {
+			t.Errorf("%d: %d %q %v", v, got, rest, err)
+		}
+	}
+	if _, _, err := Uint64([]byte{1, 2, 3}); err != ErrMalformed {
+		t.Errorf("short key: %v", err)
+	}
+}
+
+func TestInt64_roundTrip(t *testing.T) {
+	for _, v := range []int64{math.MinInt64, -1, 0, 1, math.MaxInt64} {
+		got, _, err := Int64(AppendInt64(nil, v))
+		if err != nil || got != v {
+			t.Errorf("%d: %d %v", v, got, err)
+		}
+	}
+}
+
+func TestString_roundTrip(t *testing.T) {
+	for _, s := range []string{"", "a", "\x00", "a\x00b", "\x00\x00\xff", "\xff\x01"} {
+		key := AppendString(nil, s)
+		key = AppendUint64(key, 7)
+		got, rest, err := String(key)
+		if err != nil || got != s {
+			t.Errorf("%q: %q %v", s, got, err)
+			continue
+		}
+		if n, _, err := Uint64(rest); err != nil || n != 7 {
+			t.Errorf("%q: trailing component %d %v", s, n, err)
+		}
+	}
+}
+
+func TestBytes_malformed(t *testing.T) {
+	for _, key := range [][]byte{
+		nil,
+		[]byte("abc"),     // no terminator
+		{'a', 0x00},       // truncated escape
+		{'a', 0x00, 0x02}, // invalid escape
+	} {
+		if _, _, err := Bytes(key); err != ErrMalformed {
+			t.Errorf("%q: %v", key, err)
+		}
+	}
+}
+
+func sign(c int) int {
+	switch {
+	case c < 0:
+		return -1
+	case c > 0:
+		return 1
+	}
+	return 0
+}
+
+func cmpUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// The encoded form of a (uint64, string) tuple must compare like the tuple.
+func TestOrder_uint64String(t *testing.T) {
+	f := func(a uint64, s string, b uint64, r string) bool {
+		want := cmpUint64(a, b)
+		if want == 0 {
+			want = strings.Compare(s, r)
+		}
+		x := AppendString(AppendUint64(nil, a), s)
+		y := AppendString(AppendUint64(nil, b), r)
+		return sign(bytes.Compare(x, y)) == want
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Byte strings are compared before a trailing integer, so a string that is a
+// prefix of another must still sort first whatever follows it.
+func TestOrder_bytesInt64(t *testing.T) {
+	f := func(s []byte, a int64, r []byte, b int64) bool {
+		want := bytes.Compare(s, r)
+		if want == 0 {
+			want = cmpInt64(a, b)
+		}
+		x := AppendInt64(AppendBytes(nil, s), a)
+		y := AppendInt64(AppendBytes(nil, r), b)
+		return sign(bytes.Compare(x, y)) == want
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+
+	// the cases most likely to go wrong, prefixes and embedded zeros
+	g := func(s []byte, a, b int64) bool {
+		return f(s, a, append(s, 0), b) && f(append(s, 0), a, append(s, 0, 0), b)
+	}
+	if err := quick.Check(g, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOrder_roundTrip(t *testing.T) {
+	f := func(a uint64, s []byte, b int64) bool {
+		key := AppendInt64(AppendBytes(AppendUint64(nil, a), s), b)
+		x, rest, err := Uint64(key)
+		if err != nil || x != a {
+			return false
+		}
+		y, rest, err := Bytes(rest)
+		if err != nil || !bytes.Equal(y, s) {
+			return false
+		}
+		z, rest, err := Int64(rest)
+		return err == nil && z == b && len(rest) == 0
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
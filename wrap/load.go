@@ -0,0 +1,361 @@
+package wrap
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// ErrInvalidDump is returned by Load for input that is not in the mdb_dump
+// text format. The error names the offending line.
+var ErrInvalidDump = errors.New("invalid dump")
+
+// LoadOptions controls Load.
+type LoadOptions struct {
+	// DBNames lists the databases to load. Sections of other databases are
+	// skipped. Empty means every section of the dump.
+	DBNames []string
+
+	// CreateDBs creates and registers the databases of the dump which are
+	// not registered, like AddDB, with the flags recorded in the dump.
+	// Without it such a section fails with an error wrapping
+	// ErrDbNameNotFound.
+	CreateDBs bool
+
+	// Append writes with lmdb.Append, which is faster but requires each
+	// section to be sorted and to sort after the existing keys of its
+	// database, as in the output of Dump loaded into empty databases.
+	Append bool
+
+	// ChunkSize is the number of records written per transaction. Zero
+	// means 10,000.
+	ChunkSize int
+}
+
+// Load reads a dump in the text format of mdb_dump, as written by Dump or by
+// mdb_dump -a, and writes its records into the databases it names. Both the
+// printable and the hex formats are accepted, and every section of the dump
+// is loaded in order. A section must name its database, dumps of the main
+// database are rejected. The databases of existing sections must have the
+// DupSort and other flags recorded in the dump.
+//
+// Records are written in transactions of opts.ChunkSize records. Load returns
+// the number of records committed, which after an error tells how far a
+// partial load got. Malformed input fails with an error wrapping
+// ErrInvalidDump that includes the line number.
+func (db *DB) Load(r io.Reader, opts LoadOptions) (records int64, err error) {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = importChunkSize
+	}
+	var wanted map[string]bool
+	if len(opts.DBNames) > 0 {
+		wanted = make(map[string]bool, len(opts.DBNames))
+		for _, name := range opts.DBNames {
+			wanted[name] = true
+		}
+	}
+
+	d := &dumpReader{r: bufio.NewReader(r)}
+	for {
+		hdr, err := d.header()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		skip := wanted != nil && !wanted[hdr.name]
+		if !skip {
+			if err := db.prepareLoad(hdr, opts.CreateDBs); err != nil {
+				return records, fmt.Errorf("line %d: %w", d.line, err)
+			}
+		}
+
+		chunk := make([]KV, 0, chunkSize)
+		var prev []byte // last key written, to append duplicates
+		flush := func() error {
+			if skip || len(chunk) == 0 {
+				chunk = chunk[:0]
+				return nil
+			}
+			if err := db.loadChunk(hdr, chunk, prev, opts.Append); err != nil {
+				return err
+			}
+			records += int64(len(chunk))
+			prev = chunk[len(chunk)-1].Key
+			chunk = make([]KV, 0, chunkSize)
+			return nil
+		}
+		for {
+			kv, err := d.record(hdr.printable)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return records, err
+			}
+			if chunk = append(chunk, kv); len(chunk) == chunkSize {
+				if err := flush(); err != nil {
+					return records, err
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			return records, err
+		}
+	}
+}
+
+// prepareLoad checks that the database of a dump section is registered with
+// the flags of the dump, creating it if allowed.
+func (db *DB) prepareLoad(hdr dumpHeader, create bool) error {
+	dbi, ok := db.lookup(hdr.name)
+	if !ok {
+		if !create {
+			return fmt.Errorf("%w: %q", ErrDbNameNotFound, hdr.name)
+		}
+		return db.observeWrite(hdr.name, func() error {
+			return db.addDB(hdr.name, hdr.flags)
+		})
+	}
+	var flags uint
+	err := db.View(func(txn *lmdb.Txn) (err error) {
+		if err := db.refreshDBI(hdr.name, &dbi); err != nil {
+			return err
+		}
+		flags, err = txn.Flags(dbi)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if dumpedFlags(flags) != hdr.flags {
+		return fmt.Errorf("database %q has flags %#x, the dump has %#x", hdr.name, dumpedFlags(flags), hdr.flags)
+	}
+	return nil
+}
+
+// loadChunk writes the records of a dump section in one transaction. prev is
+// the last key written by the previous chunk of the section.
+func (db *DB) loadChunk(hdr dumpHeader, chunk []KV, prev []byte, appending bool) error {
+	return db.observeWrite(hdr.name, func() error {
+		dbi, err := db.getDBI(hdr.name)
+		if err != nil {
+			return err
+		}
+		var events *[]KeyEvent
+		if db.watched(hdr.name) {
+			events = &[]KeyEvent{}
+		}
+		dupSort := hdr.flags&lmdb.DupSort != 0
+		return db.updateEvents(hdr.name, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(hdr.name, &dbi); err != nil {
+				return err
+			}
+			cur, err := txn.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+			prev := prev
+			for _, kv := range chunk {
+				var flags uint
+				if appending {
+					flags = lmdb.Append
+					if dupSort && prev != nil && bytes.Equal(kv.Key, prev) {
+						flags = lmdb.AppendDup
+					}
+				}
+				if err := cur.Put(kv.Key, kv.Value, flags); err != nil {
+					return err
+				}
+				if err := db.recordWrite(txn, OpPut, hdr.name, kv.Key, kv.Value); err != nil {
+					return err
+				}
+				db.addEvent(events, OpPut, hdr.name, kv.Key, kv.Value)
+				prev = kv.Key
+			}
+			return nil
+		}, events)
+	})
+}
+
+// dumpHeader is the header of one section of a dump.
+type dumpHeader struct {
+	name      string
+	printable bool
+	flags     uint
+}
+
+// dumpReader reads a dump line by line.
+type dumpReader struct {
+	r    *bufio.Reader
+	line int // number of the last line read
+}
+
+// next returns the next line without its newline, or io.EOF at the end of
+// the input.
+func (d *dumpReader) next() ([]byte, error) {
+	line, err := d.r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.line++
+	return bytes.TrimSuffix(line, []byte("\n")), nil
+}
+
+func (d *dumpReader) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %s", ErrInvalidDump, d.line, fmt.Sprintf(format, args...))
+}
+
+// header reads the header of the next section, it returns io.EOF if there is
+// none.
+func (d *dumpReader) header() (dumpHeader, error) {
+	var hdr dumpHeader
+	named, first := false, true
+	for {
+		line, err := d.next()
+		if err == io.EOF && first {
+			return hdr, io.EOF
+		}
+		if err == io.EOF {
+			return hdr, d.errorf("unexpected end of input in header")
+		}
+		if err != nil {
+			return hdr, err
+		}
+		first = false
+		kw, val, ok := strings.Cut(string(line), "=")
+		if !ok {
+			return hdr, d.errorf("unexpected line in header: %q", line)
+		}
+		switch kw {
+		case "HEADER":
+			if val != "END" {
+				return hdr, d.errorf("unexpected line in header: %q", line)
+			}
+			if !named {
+				return hdr, d.errorf("dump of the main database is not supported")
+			}
+			return hdr, nil
+		case "VERSION":
+			if v, err := strconv.Atoi(val); err != nil || v < 1 || v > dumpVersion {
+				return hdr, d.errorf("unsupported VERSION %q", val)
+			}
+		case "format":
+			switch val {
+			case "print":
+				hdr.printable = true
+			case "bytevalue":
+				hdr.printable = false
+			default:
+				return hdr, d.errorf("unsupported format %q", val)
+			}
+		case "database":
+			hdr.name, named = val, true
+		case "type":
+			if val != "btree" {
+				return hdr, d.errorf("unsupported type %q", val)
+			}
+		case "mapaddr", "mapsize", "maxreaders", "db_pagesize":
+			// environment settings, the DB keeps its own
+		case "duplicates":
+			if val == "1" {
+				hdr.flags |= lmdb.DupSort
+			}
+		default:
+			// unknown keywords are ignored, like mdb_load does
+			for _, f := range dumpFlags {
+				if kw == f.name && val == "1" {
+					hdr.flags |= f.flag
+				}
+			}
+		}
+	}
+}
+
+// record reads the next key and value of a section, it returns io.EOF at the
+// end of the section.
+func (d *dumpReader) record(printable bool) (KV, error) {
+	var kv KV
+	for i := 0; i < 2; i++ {
+		line, err := d.next()
+		if err == io.EOF {
+			return kv, d.errorf("unexpected end of input, missing DATA=END")
+		}
+		if err != nil {
+			return kv, err
+		}
+		if i == 0 && string(line) == "DATA=END" {
+			return kv, io.EOF
+		}
+		if len(line) == 0 || line[0] != ' ' {
+			return kv, d.errorf("unexpected line in data: %q", line)
+		}
+		b, ok := decodeDumpValue(line[1:], printable)
+		if !ok {
+			return kv, d.errorf("malformed escape")
+		}
+		if i == 0 {
+			if len(b) == 0 {
+				return kv, d.errorf("%v", ErrEmptyKey)
+			}
+			kv.Key = b
+		} else {
+			kv.Value = b
+		}
+	}
+	return kv, nil
+}
+
+// decodeDumpValue decodes a key or value line without its leading space.
+func decodeDumpValue(line []byte, printable bool) ([]byte, bool) {
+	out := make([]byte, 0, len(line))
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if printable && c != '\\' {
+			out = append(out, c)
+			continue
+		}
+		if printable {
+			// skip the backslash
+			if i++; i < len(line) && line[i] == '\\' {
+				out = append(out, '\\')
+				continue
+			}
+		}
+		if i+1 >= len(line) {
+			return nil, false
+		}
+		hi, ok1 := unhex(line[i])
+		lo, ok2 := unhex(line[i+1])
+		if !ok1 || !ok2 {
+			return nil, false
+		}
+		out = append(out, hi<<4|lo)
+		i++
+	}
+	return out, true
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
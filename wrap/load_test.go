@@ -0,0 +1,138 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDB_Load_roundTrip(t *testing.T) {
+	src, _, err := New(t.TempDir(), []string{"users", "tags"}, WithDBOptions("tags", DBOptions{DupSort: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for i := 0; i < 250; i++ {
+		key := []byte(fmt.Sprintf("user:%03d", i))
+		if err := src.Write("users", key, []byte{byte(i), '\\', 'x', 0xff}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 25; i++ {
+		for _, m := range []string{"a", "b", "c"} {
+			if err := src.AddValue("tags", []byte(fmt.Sprintf("k%02d", i)), []byte(m)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, printable := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := src.Dump(&buf, DumpOptions{Printable: printable}); err != nil {
+			t.Fatal(err)
+		}
+		dst := newTestDB(t)
+		n, err := dst.Load(bytes.NewReader(buf.Bytes()), LoadOptions{CreateDBs: true, Append: true, ChunkSize: 40})
+		if err != nil {
+			t.Fatalf("printable=%v: %v", printable, err)
+		}
+		if n != 250+75 {
+			t.Errorf("printable=%v: loaded %d records", printable, n)
+		}
+		for _, name := range []string{"users", "tags"} {
+			want, _ := src.Scan(name, nil, ScanOptions{})
+			got, err := dst.Scan(name, nil, ScanOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("printable=%v: %s differs after load", printable, name)
+			}
+		}
+		if members, _ := dst.Members("tags", []byte("k07"), 0); len(members) != 3 {
+			t.Errorf("printable=%v: %d duplicates", printable, len(members))
+		}
+
+		// loading again overwrites, which Append cannot do
+		if _, err := dst.Load(bytes.NewReader(buf.Bytes()), LoadOptions{DBNames: []string{"users"}}); err != nil {
+			t.Errorf("printable=%v: reload: %v", printable, err)
+		}
+	}
+}
+
+func TestDB_Load_mdbDump(t *testing.T) {
+	db := newTestDB(t, "users")
+	// as written by mdb_dump -p -s users, with an unknown keyword and
+	// uppercase hex
+	in := "VERSION=3\nformat=print\ndatabase=users\ntype=btree\nmapsize=1048576\nmaxreaders=126\nfuture=1\ndb_pagesize=4096\nHEADER=END\n" +
+		" a b\n x\\5C\\\\\\00\n" +
+		" c\n \n" +
+		"DATA=END\n"
+	n, err := db.Load(strings.NewReader(in), LoadOptions{})
+	if err != nil || n != 2 {
+		t.Fatalf("load: %d %v", n, err)
+	}
+	if v, err := db.Read("users", []byte("a b")); err != nil || string(v) != "x\\\\\x00" {
+		t.Errorf("a b: %q %v", v, err)
+	}
+	if v, err := db.Read("users", []byte("c")); err != nil || len(v) != 0 {
+		t.Errorf("c: %q %v", v, err)
+	}
+}
+
+func TestDB_Load_errors(t *testing.T) {
+	header := "VERSION=3\nformat=bytevalue\ndatabase=users\ntype=btree\nHEADER=END\n"
+	for _, tc := range []struct {
+		in   string
+		line int
+	}{
+		{"VERSION=4\n", 1},
+		{"VERSION=3\nformat=yaml\n", 2},
+		{"VERSION=3\nHEADER=END\n", 2},
+		{"VERSION=3\ndatabase=users\nnonsense\n", 3},
+		{"VERSION=3\ndatabase=users\n", 2},
+		{header + " 61\n 6\n", 7},
+		{header + " 6g\n 61\n", 6},
+		{header + "61\n", 6},
+		{header + " \n 61\n", 6},
+		{header + " 61\n 61\n", 7},
+	} {
+		db := newTestDB(t, "users")
+		_, err := db.Load(strings.NewReader(tc.in), LoadOptions{})
+		if !errors.Is(err, ErrInvalidDump) || !strings.Contains(err.Error(), fmt.Sprintf("line %d:", tc.line)) {
+			t.Errorf("%q: %v, want line %d", tc.in, err, tc.line)
+		}
+	}
+
+	db := newTestDB(t, "users")
+	if _, err := db.Load(strings.NewReader(header+"DATA=END\n"), LoadOptions{DBNames: []string{"users"}}); err != nil {
+		t.Errorf("empty section: %v", err)
+	}
+	other := strings.Replace(header, "users", "other", 1)
+	if _, err := db.Load(strings.NewReader(other+"DATA=END\n"), LoadOptions{}); !errors.Is(err, ErrDbNameNotFound) {
+		t.Errorf("unknown db: %v", err)
+	}
+	dup := strings.Replace(header, "HEADER", "dupsort=1\nHEADER", 1)
+	if _, err := db.Load(strings.NewReader(dup+"DATA=END\n"), LoadOptions{}); err == nil {
+		t.Errorf("flags mismatch loaded")
+	}
+}
+
+func TestDB_Load_partial(t *testing.T) {
+	db := newTestDB(t, "users")
+	var in strings.Builder
+	in.WriteString("VERSION=3\nformat=print\ndatabase=users\ntype=btree\nHEADER=END\n")
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(&in, " k%d\n v\n", i)
+	}
+	in.WriteString(" k5\n")
+	n, err := db.Load(strings.NewReader(in.String()), LoadOptions{ChunkSize: 2})
+	if !errors.Is(err, ErrInvalidDump) {
+		t.Fatalf("truncated dump: %v", err)
+	}
+	if c, _ := db.Count("users"); n != 4 || c != 4 {
+		t.Errorf("committed %d records, %d stored", n, c)
+	}
+}
@@ -0,0 +1,313 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// ErrDbNotEmpty is returned by RenameDB and CopyDB when the destination is a
+// database holding entries.
+var ErrDbNotEmpty = errors.New("database is not empty")
+
+// RenameDB renames the database oldName to newName. The entries are copied to
+// a new LMDB database and the old one is deleted in one write transaction, so
+// readers see either the old name or the new one. Afterwards operations using
+// oldName fail with ErrDbNameNotFound, including those that looked it up
+// before the rename, see DropDB.
+//
+// If a database called newName exists and holds entries RenameDB returns an
+// error wrapping ErrDbNotEmpty, unless force is set in which case the existing
+// database is deleted first. The deletion is part of the same transaction, so
+// if the rename fails both databases keep their names and entries.
+//
+// The settings in Options.DBs apply by name, so the renamed database uses
+// those registered for newName, if any, from now on. The DupSort and other
+// LMDB flags of oldName are carried over. Expiry deadlines and modification
+// times recorded for oldName are not, and the changelog keeps oldName for the
+// changes made before the rename.
+//
+// The copy runs on a writer goroutine and blocks every other write while it
+// runs, and the map must have room for a second copy of the database until
+// the transaction commits.
+func (db *DB) RenameDB(oldName, newName string, force bool) error {
+	return db.observeWrite(oldName, func() error {
+		if oldName == "" || newName == "" {
+			return ErrDbNameNotFound
+		}
+		if strings.HasPrefix(newName, ReservedPrefix) {
+			return ErrReservedDbName
+		}
+		src, err := db.getDBI(oldName)
+		if err != nil {
+			return err
+		}
+		if oldName == newName {
+			return nil
+		}
+		var dst lmdb.DBI
+		u := &updateOp{dbName: oldName}
+		u.op = func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(oldName, &src); err != nil {
+				return err
+			}
+			dst, err = db.renameDBI(u, txn, src, newName, force)
+			return err
+		}
+		u.commit = func() {
+			db.dbsMu.Lock()
+			db.dbs[newName] = dst
+			if db.probe == "" {
+				db.probe = newName
+			}
+			db.dbsMu.Unlock()
+		}
+		return db.submit(u)
+	})
+}
+
+// CloneDB copies every entry of srcName into dstName and returns the number
+// of entries copied. dstName is created with the flags of srcName and
+// registered if it does not exist. If it holds entries CloneDB returns an
+// error wrapping ErrDbNotEmpty, unless truncate is set in which case they are
+// deleted first.
+//
+// Unlike RenameDB the copy is written in chunks of 10,000 entries, each in
+// its own transaction, so the map never needs room for more than one chunk of
+// uncommitted pages and other writes go on between chunks. A clone built this
+// way can be promoted with RenameDB once complete. See CopyDB for copying
+// between environments.
+func (db *DB) CloneDB(srcName, dstName string, truncate bool) (int64, error) {
+	return CopyDB(db, srcName, db, dstName, CopyOpts{Overwrite: truncate, CreateDB: true})
+}
+
+// Truncate deletes every entry of the named database. The database stays
+// registered and usable. Like RenameDB, Truncate does not notify subscribers
+// or record the deletions in the changelog, and leaves expiry deadlines and
+// modification times recorded for the database in place.
+func (db *DB) Truncate(dbName string) error {
+	return db.observeWrite(dbName, func() error {
+		dbi, err := db.getDBI(dbName)
+		if err != nil {
+			return err
+		}
+		return db.truncate(dbName, dbi)
+	})
+}
+
+// truncate deletes every entry of dbi, the handle of dbName.
+func (db *DB) truncate(dbName string, dbi lmdb.DBI) error {
+	return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		return txn.Drop(dbi, false)
+	}, nil)
+}
+
+// DropDB deletes the named database from the environment and unregisters it,
+// afterwards operations using dbName fail with ErrDbNameNotFound. The name may
+// be registered again with AddDB. See Truncate for the records DropDB leaves
+// in place.
+//
+// LMDB hands the handle of a dropped database out again to the next database
+// opened, so the methods of DB look their handle up again in their
+// transaction, and operations that looked up dbName before the drop fail
+// with ErrDbNameNotFound too. Handles obtained with DBI or GetDBis and used
+// later are not looked up again: look them up inside the transaction using
+// them.
+func (db *DB) DropDB(dbName string) error {
+	return db.observeWrite(dbName, func() error {
+		dbi, err := db.getDBI(dbName)
+		if err != nil {
+			return err
+		}
+		u := &updateOp{dbName: dbName}
+		u.op = func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			return db.drop(u, txn, dbi)
+		}
+		return db.submit(u)
+	})
+}
+
+// drop deletes the database of dbi in txn, the transaction of u, and
+// unregisters it. LMDB closes the handle even if txn aborts, so the names
+// using it are unregistered right away rather than once txn commits, and no
+// later transaction can see them mapped to a handle reused for another
+// database. They are recorded in u.dropped for the writer to register again
+// if txn fails, see reregister.
+func (db *DB) drop(u *updateOp, txn *lmdb.Txn, dbi lmdb.DBI) error {
+	if err := txn.Drop(dbi, true); err != nil {
+		return err
+	}
+	db.dbsMu.Lock()
+	for name, h := range db.dbs {
+		if h == dbi {
+			delete(db.dbs, name)
+			if db.probe == name {
+				db.probe = ""
+			}
+			u.dropped = append(u.dropped, name)
+		}
+	}
+	db.dbsMu.Unlock()
+	return nil
+}
+
+// reregister opens and registers again the databases names, unregistered by
+// drop in a transaction that then failed. The databases are still in the
+// environment but LMDB closed their handles. It runs on the writer goroutine
+// of that transaction. If opening fails the names stay unregistered, as
+// after a DropDB, until the DB is reopened.
+func (db *DB) reregister(names []string) {
+	dbis := make([]lmdb.DBI, len(names))
+	err := db.env.UpdateLocked(func(txn *lmdb.Txn) (err error) {
+		for i, name := range names {
+			if dbis[i], err = txn.OpenDBI(name, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	db.dbsMu.Lock()
+	for i, name := range names {
+		db.dbs[name] = dbis[i]
+		if db.probe == "" {
+			db.probe = name
+		}
+	}
+	db.dbsMu.Unlock()
+}
+
+// AddDB creates the named database, if it does not exist yet, and registers
+// it as if its name had been passed to New. A name passed to New and later
+// dropped gets its settings in Options.DBs again. AddDB returns
+// ErrDuplicateDbName if dbName is already registered, and an error wrapping
+// ErrDBsFull if the environment already holds MaxNamedDBs open databases,
+// the internal ones included.
+func (db *DB) AddDB(dbName string) error {
+	return db.observeWrite(dbName, func() error {
+		return db.addDB(dbName, db.opts.DBs[dbName].flags())
+	})
+}
+
+// addDB is AddDB creating a missing database with flags.
+func (db *DB) addDB(dbName string, flags uint) error {
+	if dbName == "" {
+		return ErrDbNameNotFound
+	}
+	if strings.HasPrefix(dbName, ReservedPrefix) {
+		return ErrReservedDbName
+	}
+	if _, ok := db.lookup(dbName); ok {
+		return ErrDuplicateDbName
+	}
+	var dbi lmdb.DBI
+	u := &updateOp{dbName: dbName}
+	u.op = func(txn *lmdb.Txn) (err error) {
+		dbi, err = txn.OpenDBI(dbName, lmdb.Create|flags)
+		return db.dbsFull(err)
+	}
+	u.commit = func() {
+		db.dbsMu.Lock()
+		db.dbs[dbName] = dbi
+		if db.probe == "" {
+			db.probe = dbName
+		}
+		db.dbsMu.Unlock()
+	}
+	return db.submit(u)
+}
+
+// dbsFull replaces the DBsFull error of opening a database with one wrapping
+// ErrDBsFull.
+func (db *DB) dbsFull(err error) error {
+	if !lmdb.IsErrno(err, lmdb.DBsFull) {
+		return err
+	}
+	max, _ := db.env.MaxDBs()
+	return fmt.Errorf("%w: limit of %d", ErrDBsFull, max)
+}
+
+// renameDBI copies the entries of src into a new database called newName,
+// deletes src, and returns the handle of the new database, which the caller
+// registers once txn commits. u is the operation running txn, see drop.
+func (db *DB) renameDBI(u *updateOp, txn *lmdb.Txn, src lmdb.DBI, newName string, force bool) (lmdb.DBI, error) {
+	flags, err := txn.Flags(src)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := txn.OpenDBI(newName, 0)
+	switch {
+	case err == nil:
+		stat, err := txn.Stat(existing)
+		if err != nil {
+			return 0, err
+		}
+		if stat.Entries > 0 && !force {
+			return 0, fmt.Errorf("%w: %q", ErrDbNotEmpty, newName)
+		}
+		// recreate it so it gets the flags of src
+		if err := db.drop(u, txn, existing); err != nil {
+			return 0, err
+		}
+	case !lmdb.IsNotFound(err):
+		return 0, err
+	}
+	dst, err := txn.OpenDBI(newName, lmdb.Create|flags)
+	if err != nil {
+		return 0, err
+	}
+	if err := copyDBI(txn, src, dst, flags&lmdb.DupSort != 0); err != nil {
+		return 0, err
+	}
+	if err := db.drop(u, txn, src); err != nil {
+		return 0, err
+	}
+	return dst, nil
+}
+
+// copyDBI appends every entry of src to the empty database dst.
+func copyDBI(txn *lmdb.Txn, src, dst lmdb.DBI, dupSort bool) error {
+	// src is not written while copying so its pages stay valid
+	raw := txn.RawRead
+	txn.RawRead = true
+	defer func() { txn.RawRead = raw }()
+
+	scur, err := txn.OpenCursor(src)
+	if err != nil {
+		return err
+	}
+	defer scur.Close()
+	dcur, err := txn.OpenCursor(dst)
+	if err != nil {
+		return err
+	}
+	defer dcur.Close()
+
+	var prev []byte
+	k, v, err := scur.Get(nil, nil, lmdb.First)
+	for ; err == nil; k, v, err = scur.Get(nil, nil, lmdb.Next) {
+		flags := uint(lmdb.Append)
+		if dupSort && prev != nil && bytes.Equal(k, prev) {
+			flags = lmdb.AppendDup
+		}
+		if err := dcur.Put(k, v, flags); err != nil {
+			return err
+		}
+		prev = k
+	}
+	if lmdb.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,394 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_RenameDB(t *testing.T) {
+	dir := t.TempDir()
+	db, _, err := New(dir, []string{"old", "taken", "tags"}, WithDBOptions("tags", DBOptions{DupSort: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		mustWrite(t, db, "old", fmt.Sprintf("k%03d", i))
+	}
+
+	if err := db.RenameDB("old", "new", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read("old", []byte("k000")); err != ErrDbNameNotFound {
+		t.Errorf("old name: %v", err)
+	}
+	kvs, err := db.Scan("new", nil, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 100 || string(kvs[0].Key) != "k000" || string(kvs[99].Key) != "k099" {
+		t.Errorf("copied %d entries", len(kvs))
+	}
+	if err := db.RenameDB("old", "other", false); err != ErrDbNameNotFound {
+		t.Errorf("rename missing: %v", err)
+	}
+
+	mustWrite(t, db, "taken", "x")
+	if err := db.RenameDB("new", "taken", false); !errors.Is(err, ErrDbNotEmpty) {
+		t.Errorf("clobber: %v", err)
+	}
+	if n, _ := db.Count("new"); n != 100 {
+		t.Errorf("source changed on refusal: %d", n)
+	}
+	if err := db.RenameDB("new", "taken", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read("taken", []byte("x")); err == nil {
+		t.Errorf("forced rename kept old entries")
+	}
+	if n, _ := db.Count("taken"); n != 100 {
+		t.Errorf("forced rename: %d entries", n)
+	}
+
+	for _, m := range []string{"b", "a", "c"} {
+		if err := db.AddValue("tags", []byte("k"), []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite(t, db, "tags", "z")
+	if err := db.RenameDB("tags", "labels", false); err != nil {
+		t.Fatal(err)
+	}
+	members, err := db.Members("labels", []byte("k"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(bytes.Join(members, []byte(","))); got != "a,b,c" {
+		t.Errorf("dup members: %s", got)
+	}
+
+	if err := db.RenameDB("labels", ReservedPrefix+"x", false); err != ErrReservedDbName {
+		t.Errorf("reserved name: %v", err)
+	}
+	db.Close()
+
+	db, _, err = New(dir, []string{"taken", "labels"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if n, _ := db.Count("taken"); n != 100 {
+		t.Errorf("reopened: %d entries", n)
+	}
+}
+
+func TestDB_RenameDB_concurrent(t *testing.T) {
+	db := newTestDB(t, "old")
+	mustWrite(t, db, "old", "k")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// reads racing the commit may fail with an LMDB error instead
+		for {
+			if _, err := db.Read("old", []byte("k")); err == ErrDbNameNotFound {
+				return
+			}
+		}
+	}()
+	if err := db.RenameDB("old", "new", false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("old name still readable")
+	}
+}
+
+func TestDB_RenameDB_failed(t *testing.T) {
+	errHook := errors.New("hook failed")
+	fail := false
+	db, _, err := New(t.TempDir(), []string{"old", "taken"}, WithOnBeforeCommit(func(*NamedTxn) error {
+		if fail {
+			return errHook
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mustWrite(t, db, "old", "a", "b")
+	mustWrite(t, db, "taken", "x")
+
+	// the transaction fails after dropping both names, which stay registered
+	fail = true
+	if err := db.RenameDB("old", "taken", true); !errors.Is(err, errHook) {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := db.DropDB("old"); !errors.Is(err, errHook) {
+		t.Fatalf("drop: %v", err)
+	}
+	fail = false
+	if v, err := db.Read("taken", []byte("x")); err != nil || string(v) != "v:x" {
+		t.Errorf("taken after failure: %q %v", v, err)
+	}
+	if n, err := db.Count("old"); err != nil || n != 2 {
+		t.Errorf("old after failure: %d %v", n, err)
+	}
+	mustWrite(t, db, "taken", "y")
+	if err := db.RenameDB("old", "taken", true); err != nil {
+		t.Fatal(err)
+	}
+	if kvs, _ := db.Scan("taken", nil, ScanOptions{}); len(kvs) != 2 || string(kvs[0].Key) != "a" {
+		t.Errorf("renamed: %v", kvs)
+	}
+}
+
+func TestDB_Truncate(t *testing.T) {
+	db := newTestDB(t, "cache", "keep")
+	mustWrite(t, db, "cache", "a", "b", "c")
+	mustWrite(t, db, "keep", "a")
+
+	if err := db.Truncate("cache"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.Count("cache"); err != nil || n != 0 {
+		t.Errorf("truncated: %d %v", n, err)
+	}
+	if n, _ := db.Count("keep"); n != 1 {
+		t.Errorf("other database: %d entries", n)
+	}
+	mustWrite(t, db, "cache", "d")
+	if n, _ := db.Count("cache"); n != 1 {
+		t.Errorf("write after truncate: %d entries", n)
+	}
+	if err := db.Truncate("nope"); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+}
+
+func TestDB_DropDB(t *testing.T) {
+	db := newTestDB(t, "cache")
+	mustWrite(t, db, "cache", "a", "b")
+
+	if err := db.DropDB("cache"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read("cache", []byte("a")); err != ErrDbNameNotFound {
+		t.Errorf("dropped: %v", err)
+	}
+	if err := db.DropDB("cache"); err != ErrDbNameNotFound {
+		t.Errorf("drop twice: %v", err)
+	}
+	if err := db.HealthCheck(); err != nil {
+		t.Errorf("health after drop: %v", err)
+	}
+
+	if err := db.AddDB("cache"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.Count("cache"); err != nil || n != 0 {
+		t.Errorf("re-added: %d %v", n, err)
+	}
+	mustWrite(t, db, "cache", "c")
+	if _, err := db.Read("cache", []byte("c")); err != nil {
+		t.Errorf("write after add: %v", err)
+	}
+	if err := db.AddDB("cache"); err != ErrDuplicateDbName {
+		t.Errorf("add twice: %v", err)
+	}
+	if err := db.AddDB(ReservedPrefix + "x"); err != ErrReservedDbName {
+		t.Errorf("reserved name: %v", err)
+	}
+}
+
+func TestDB_DropDB_staleHandle(t *testing.T) {
+	db := newTestDB(t, "cache", "users")
+	mustWrite(t, db, "cache", "a")
+	stale, _ := db.DBI("cache")
+	if err := db.DropDB("cache"); err != nil {
+		t.Fatal(err)
+	}
+	// LMDB hands the freed handle to the next database opened
+	if err := db.AddDB("other"); err != nil {
+		t.Fatal(err)
+	}
+	if dbi, _ := db.DBI("other"); dbi != stale {
+		t.Fatalf("handle not reused: %d != %d", dbi, stale)
+	}
+	mustWrite(t, db, "other", "a")
+
+	if err := db.put("cache", stale, []byte("a"), []byte("stale")); err != ErrDbNameNotFound {
+		t.Errorf("stale write: %v", err)
+	}
+	if _, err := db.get("cache", stale, []byte("a")); err != ErrDbNameNotFound {
+		t.Errorf("stale read: %v", err)
+	}
+	if err := db.del("cache", stale, []byte("a")); err != ErrDbNameNotFound {
+		t.Errorf("stale delete: %v", err)
+	}
+	if err := db.truncate("cache", stale); err != ErrDbNameNotFound {
+		t.Errorf("stale truncate: %v", err)
+	}
+	if v, err := db.Read("other", []byte("a")); err != nil || string(v) != "v:a" {
+		t.Errorf("other: %q %v", v, err)
+	}
+
+	// a handle replaced while waiting, as by Compact, is looked up again
+	src, _ := db.DBI("users")
+	if err := db.put("other", src, []byte("b"), []byte("v:b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read("other", []byte("b")); err != nil {
+		t.Errorf("refreshed write: %v", err)
+	}
+	if _, err := db.Read("users", []byte("b")); !lmdb.IsNotFound(err) {
+		t.Errorf("written through the replaced handle: %v", err)
+	}
+
+	// a rename frees the handle of the old name the same way
+	if err := db.RenameDB("users", "accounts", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddDB("more"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.put("users", src, []byte("a"), []byte("stale")); err != ErrDbNameNotFound {
+		t.Errorf("stale write after rename: %v", err)
+	}
+	if n, err := db.Count("more"); err != nil || n != 0 {
+		t.Errorf("more: %d %v", n, err)
+	}
+}
+
+func TestDB_AddDB_full(t *testing.T) {
+	db := newTestDB(t, "a")
+	added := 0
+	var err error
+	for ; added < MaxNamedDBs; added++ {
+		if err = db.AddDB(fmt.Sprintf("db%03d", added)); err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, ErrDBsFull) {
+		t.Fatalf("after %d databases: %v", added, err)
+	}
+	// "a" and the internal databases take the other slots
+	if want := MaxNamedDBs - 1 - 5; added != want {
+		t.Errorf("added %d databases (!= %d)", added, want)
+	}
+	if err := db.DropDB("db000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddDB("again"); err != nil {
+		t.Errorf("after a drop: %v", err)
+	}
+}
+
+func TestDB_CloneDB(t *testing.T) {
+	db := newTestDB(t, "live", "taken")
+	for i := 0; i < 50; i++ {
+		mustWrite(t, db, "live", fmt.Sprintf("k%02d", i))
+	}
+
+	n, err := db.CloneDB("live", "staging", false)
+	if err != nil || n != 50 {
+		t.Fatalf("clone: %d %v", n, err)
+	}
+	want, _ := db.Scan("live", nil, ScanOptions{})
+	got, err := db.Scan("staging", nil, ScanOptions{})
+	if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("clone differs: %v", err)
+	}
+	if _, ok := db.GetDBis()["staging"]; !ok {
+		t.Errorf("clone not registered")
+	}
+
+	mustWrite(t, db, "taken", "x")
+	if _, err := db.CloneDB("live", "taken", false); !errors.Is(err, ErrDbNotEmpty) {
+		t.Errorf("clobber: %v", err)
+	}
+	if n, err := db.CloneDB("live", "taken", true); err != nil || n != 50 {
+		t.Errorf("truncate: %d %v", n, err)
+	}
+	if _, err := db.Read("taken", []byte("x")); err == nil {
+		t.Errorf("truncating clone kept old entries")
+	}
+
+	if _, err := db.CloneDB("live", "live", true); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("clone onto itself: %v", err)
+	}
+	if _, err := db.CloneDB("live", ReservedPrefix+"x", false); err != ErrReservedDbName {
+		t.Errorf("reserved name: %v", err)
+	}
+	if _, err := db.CloneDB("nope", "other", false); err != ErrDbNameNotFound {
+		t.Errorf("unknown source: %v", err)
+	}
+}
+
+func TestDB_CloneDB_compact(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"live"}, WithCompactWait(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for i := 0; i < 500; i++ {
+		mustWrite(t, db, "live", fmt.Sprintf("k%03d", i))
+	}
+
+	stop := make(chan struct{})
+	running := make(chan struct{})
+	compacted := make(chan error, 1)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				compacted <- nil
+				return
+			default:
+			}
+			if err := db.Compact(); err != nil {
+				compacted <- err
+				return
+			}
+			if i == 0 {
+				close(running)
+			}
+			// let the waiting writes in before compacting again
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	<-running
+	// CloneDB with small chunks, so Compact runs between them
+	copied := make(chan error, 1)
+	go func() {
+		n, err := CopyDB(db, "live", db, "staging", CopyOpts{CreateDB: true, ChunkSize: 10})
+		if err == nil && n != 500 {
+			err = fmt.Errorf("copied %d records", n)
+		}
+		copied <- err
+	}()
+	select {
+	case err := <-copied:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("clone and Compact deadlocked")
+	}
+	close(stop)
+	if err := <-compacted; err != nil {
+		t.Error(err)
+	}
+	want, _ := db.Scan("live", nil, ScanOptions{})
+	got, err := db.Scan("staging", nil, ScanOptions{})
+	if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("clone differs: %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package wrap
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// MergeOpts controls MergeFrom.
+type MergeOpts struct {
+	// Conflict is the policy for keys, or in DupSort databases key/value
+	// pairs, that already exist in the destination. Under ConflictFail the
+	// first conflict stops the merge.
+	Conflict ConflictPolicy
+
+	// CreateDBs creates and registers databases missing from the
+	// destination, with the flags of the source database, instead of
+	// failing with an error wrapping ErrDbNameNotFound. Existing databases
+	// must have the DupSort and other flags of their source.
+	CreateDBs bool
+
+	// ChunkSize is the number of records written per transaction. Zero
+	// means 10,000. In DupSort databases the values of a key are never
+	// split across transactions, so a chunk may be larger.
+	ChunkSize int
+
+	// After maps database names to the key after which their merge starts,
+	// to resume a failed merge from its MergeStats.Last. Databases not in
+	// After are merged from their first key.
+	After map[string][]byte
+}
+
+// MergeStats counts the records merged by MergeFrom.
+type MergeStats struct {
+	Inserted int64 // records written
+	Skipped  int64 // existing records kept by ConflictSkip
+
+	// Last maps each database to the last key of its last committed chunk.
+	// After a failure MergeOpts.After set to Last resumes the merge.
+	Last map[string][]byte
+}
+
+// MergeFrom copies the records of the named databases of src into db, for
+// example to fold the data of a satellite environment back into the primary.
+// An empty dbNames merges every database registered in src.
+//
+// src is read in one short read transaction per chunk, resuming after the
+// last key merged, and each chunk of opts.ChunkSize records is written to db
+// in a transaction of its own. No read transaction is held while writing, so
+// a Compact of src or db can run between chunks. Records written to src
+// during the merge are merged if they sort after the chunks already read.
+// The chunks committed before an error stay written; the error is returned
+// along with the stats of the committed chunks. Values are copied as stored,
+// including the version headers of Versioned databases.
+func (db *DB) MergeFrom(src *DB, dbNames []string, opts MergeOpts) (MergeStats, error) {
+	stats := MergeStats{Last: make(map[string][]byte)}
+	names, _, err := src.resolveDBs(dbNames)
+	if err != nil {
+		return stats, err
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = importChunkSize
+	}
+	for _, name := range names {
+		if err := db.mergeDB(src, name, chunkSize, opts, &stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// mergeDB merges the database name of src.
+func (db *DB) mergeDB(src *DB, name string, chunkSize int, opts MergeOpts, stats *MergeStats) error {
+	var flags uint
+	err := src.View(func(txn *lmdb.Txn) error {
+		dbi, err := src.getDBI(name)
+		if err != nil {
+			return fmt.Errorf("%w: %q", err, name)
+		}
+		flags, err = txn.Flags(dbi)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if dst, ok := db.lookup(name); ok {
+		var dstFlags uint
+		err := db.View(func(txn *lmdb.Txn) (err error) {
+			if err := db.refreshDBI(name, &dst); err != nil {
+				return err
+			}
+			dstFlags, err = txn.Flags(dst)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if dumpedFlags(dstFlags) != dumpedFlags(flags) {
+			return fmt.Errorf("database %q has flags %#x, the source has %#x", name, dumpedFlags(dstFlags), dumpedFlags(flags))
+		}
+	} else if !opts.CreateDBs {
+		return fmt.Errorf("%w: %q", ErrDbNameNotFound, name)
+	} else {
+		err := db.observeWrite(name, func() error {
+			return db.addDB(name, dumpedFlags(flags))
+		})
+		if err != nil && err != ErrDuplicateDbName {
+			return err
+		}
+	}
+	var putFlags uint
+	switch {
+	case opts.Conflict == ConflictOverwrite:
+	case flags&lmdb.DupSort != 0:
+		putFlags = lmdb.NoDupData
+	default:
+		putFlags = lmdb.NoOverwrite
+	}
+
+	var prev []byte // last key merged
+	if after := opts.After[name]; len(after) > 0 {
+		prev = after
+	}
+	for {
+		var chunk []KV
+		done := false
+		err := src.View(func(txn *lmdb.Txn) error {
+			// looked up again since Compact may reopen src between chunks
+			dbi, err := src.getDBI(name)
+			if err != nil {
+				return fmt.Errorf("%w: %q", err, name)
+			}
+			cur, err := txn.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+			chunk = make([]KV, 0, chunkSize)
+			// chunks end between keys, so resuming skips every value of prev
+			k, v, err := seekAfter(txn, cur, dbi, prev, nil, false)
+			for ; err == nil; k, v, err = cur.Get(nil, nil, lmdb.Next) {
+				if len(chunk) >= chunkSize && !bytes.Equal(k, chunk[len(chunk)-1].Key) {
+					return nil
+				}
+				chunk = append(chunk, KV{Key: k, Value: v})
+			}
+			if lmdb.IsNotFound(err) {
+				done, err = true, nil
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if len(chunk) > 0 {
+			inserted, err := db.mergeChunk(name, chunk, putFlags, opts.Conflict)
+			if err != nil {
+				return err
+			}
+			stats.Inserted += inserted
+			stats.Skipped += int64(len(chunk)) - inserted
+			prev = chunk[len(chunk)-1].Key
+			stats.Last[name] = prev
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// mergeChunk writes chunk to dbName in one transaction and returns the number
+// of records written, the others were skipped.
+func (db *DB) mergeChunk(dbName string, chunk []KV, flags uint, policy ConflictPolicy) (int64, error) {
+	var inserted int64
+	err := db.observeWrite(dbName, func() error {
+		dbi, err := db.getDBI(dbName)
+		if err != nil {
+			return err
+		}
+		var events *[]KeyEvent
+		if db.watched(dbName) {
+			events = &[]KeyEvent{}
+		}
+		return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			inserted = 0
+			for _, kv := range chunk {
+				err := txn.Put(dbi, kv.Key, kv.Value, flags)
+				if lmdb.IsErrno(err, lmdb.KeyExist) && policy == ConflictSkip {
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("%s: key %q: %w", dbName, kv.Key, err)
+				}
+				if err := db.recordWrite(txn, OpPut, dbName, kv.Key, kv.Value); err != nil {
+					return err
+				}
+				db.addEvent(events, OpPut, dbName, kv.Key, kv.Value)
+				inserted++
+			}
+			return nil
+		}, events)
+	})
+	return inserted, err
+}
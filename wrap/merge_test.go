@@ -0,0 +1,159 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_MergeFrom(t *testing.T) {
+	src, _, err := New(t.TempDir(), []string{"users", "tags"}, WithDBOptions("tags", DBOptions{DupSort: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for i := 0; i < 25; i++ {
+		mustWrite(t, src, "users", fmt.Sprintf("k%02d", i))
+	}
+	for _, m := range []string{"a", "b", "c"} {
+		if err := src.AddValue("tags", []byte("t"), []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := newTestDB(t, "users")
+	if err := dst.Write("users", []byte("k03"), []byte("local")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dst.MergeFrom(src, nil, MergeOpts{}); !errors.Is(err, ErrDbNameNotFound) {
+		t.Errorf("missing db: %v", err)
+	}
+	st, err := dst.MergeFrom(src, nil, MergeOpts{Conflict: ConflictSkip, CreateDBs: true, ChunkSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Inserted != 27 || st.Skipped != 1 || string(st.Last["users"]) != "k24" || string(st.Last["tags"]) != "t" {
+		t.Errorf("stats: %+v", st)
+	}
+	if v, _ := dst.Read("users", []byte("k03")); string(v) != "local" {
+		t.Errorf("skipped key overwritten: %q", v)
+	}
+	if members, _ := dst.Members("tags", []byte("t"), 0); len(members) != 3 {
+		t.Errorf("duplicates: %q", members)
+	}
+
+	st, err = dst.MergeFrom(src, []string{"users"}, MergeOpts{})
+	if err != nil || st.Inserted != 25 {
+		t.Errorf("overwrite: %+v %v", st, err)
+	}
+	if v, _ := dst.Read("users", []byte("k03")); string(v) != "v:k03" {
+		t.Errorf("overwritten value: %q", v)
+	}
+}
+
+func TestDB_MergeFrom_resume(t *testing.T) {
+	src := newTestDB(t, "users")
+	for i := 0; i < 10; i++ {
+		mustWrite(t, src, "users", fmt.Sprintf("k%d", i))
+	}
+	dst := newTestDB(t, "users")
+	mustWrite(t, dst, "users", "k6")
+
+	opts := MergeOpts{Conflict: ConflictFail, ChunkSize: 4}
+	st, err := dst.MergeFrom(src, nil, opts)
+	var opErr *lmdb.OpError
+	if !errors.As(err, &opErr) || opErr.Errno != lmdb.KeyExist {
+		t.Fatalf("conflict: %v", err)
+	}
+	// the chunk k4..k7 holding the conflict is not committed
+	if st.Inserted != 4 || string(st.Last["users"]) != "k3" {
+		t.Errorf("stats: %+v", st)
+	}
+	if n, _ := dst.Count("users"); n != 5 {
+		t.Errorf("%d keys after failure", n)
+	}
+
+	if err := dst.Delete("users", []byte("k6")); err != nil {
+		t.Fatal(err)
+	}
+	opts.After = st.Last
+	st, err = dst.MergeFrom(src, nil, opts)
+	if err != nil || st.Inserted != 6 {
+		t.Errorf("resume: %+v %v", st, err)
+	}
+	if n, _ := dst.Count("users"); n != 10 {
+		t.Errorf("%d keys after resume", n)
+	}
+
+	tags, _, err := New(t.TempDir(), []string{"users"}, WithDBOptions("users", DBOptions{DupSort: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tags.Close()
+	if _, err := tags.MergeFrom(src, nil, MergeOpts{}); err == nil {
+		t.Errorf("flags mismatch merged")
+	}
+}
+
+func TestDB_MergeFrom_compact(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"live"}, WithCompactWait(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	kvs := make([]KV, 2000)
+	for i := range kvs {
+		kvs[i] = KV{Key: []byte(fmt.Sprintf("k%04d", i)), Value: []byte("v")}
+	}
+	if err := db.BatchAppend("live", kvs); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	running := make(chan struct{})
+	compacted := make(chan error, 1)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				compacted <- nil
+				return
+			default:
+			}
+			if err := db.Compact(); err != nil {
+				compacted <- err
+				return
+			}
+			if i == 0 {
+				close(running)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	<-running
+	// merging db into itself writes while src is read, with small chunks so
+	// Compact runs between them
+	merged := make(chan error, 1)
+	go func() {
+		st, err := db.MergeFrom(db, nil, MergeOpts{Conflict: ConflictSkip, ChunkSize: 10})
+		if err == nil && (st.Skipped != 2000 || st.Inserted != 0) {
+			err = fmt.Errorf("stats: %+v", st)
+		}
+		merged <- err
+	}()
+	select {
+	case err := <-merged:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("merge and Compact deadlocked")
+	}
+	close(stop)
+	if err := <-compacted; err != nil {
+		t.Error(err)
+	}
+}
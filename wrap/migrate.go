@@ -0,0 +1,103 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// schemaVersionKey is the key of the schema version in the meta database.
+var schemaVersionKey = []byte("schemaVersion")
+
+// ErrMigrationOrder is returned by Migrate when the versions of the
+// migrations are not consecutive or do not continue the current version.
+var ErrMigrationOrder = errors.New("migration versions must be consecutive")
+
+// Migration is a schema change applied by Migrate.
+type Migration struct {
+	// Version is the schema version after the migration, starting at 1.
+	Version uint64
+
+	// Apply runs inside the write transaction that records Version.
+	Apply func(tx *NamedTxn) error
+}
+
+// Migrate applies, in order, each migration whose version is above the
+// current schema version and returns how many were applied. Each migration
+// runs in its own write transaction that also stores its version, so a failed
+// migration leaves the schema at the version of the last successful one and
+// Migrate returns its error. Running Migrate again with migrations that were
+// already applied does nothing.
+//
+// The versions must increase by one from one migration to the next, and the
+// first one must not be above the current version plus one, otherwise
+// Migrate applies nothing and returns ErrMigrationOrder. Migrations that
+// were applied long ago may be removed from the start of the list.
+func (db *DB) Migrate(migrations []Migration) (applied int, err error) {
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	for i, m := range migrations {
+		if m.Apply == nil {
+			return 0, fmt.Errorf("wrap: migration %d has no Apply function", m.Version)
+		}
+		if m.Version == 0 || (i > 0 && m.Version != migrations[i-1].Version+1) {
+			return 0, fmt.Errorf("%w: version %d at index %d", ErrMigrationOrder, m.Version, i)
+		}
+	}
+	if first := migrations[0].Version; first > current+1 {
+		return 0, fmt.Errorf("%w: first version %d after current version %d", ErrMigrationOrder, first, current)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		ran := false
+		err := db.UpdateNamed(func(tx *NamedTxn) error {
+			// another Migrate may have run since the version was read
+			v, err := schemaVersion(tx.txn, db.meta)
+			if err != nil || v >= m.Version {
+				return err
+			}
+			if v != m.Version-1 {
+				return fmt.Errorf("%w: schema version changed to %d", ErrMigrationOrder, v)
+			}
+			if err := m.Apply(tx); err != nil {
+				return err
+			}
+			var b [8]byte
+			binary.BigEndian.PutUint64(b[:], m.Version)
+			ran = true
+			return tx.txn.Put(db.meta, schemaVersionKey, b[:], 0)
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d: %w", m.Version, err)
+		}
+		if ran {
+			applied++
+		}
+		current = m.Version
+	}
+	return applied, nil
+}
+
+// SchemaVersion returns the version of the last migration applied by
+// Migrate, or zero if none was.
+func (db *DB) SchemaVersion() (v uint64, err error) {
+	err = db.view(func(txn *lmdb.Txn) (err error) {
+		v, err = schemaVersion(txn, db.meta)
+		return err
+	})
+	return v, err
+}
+
+func schemaVersion(txn *lmdb.Txn, dbi lmdb.DBI) (uint64, error) {
+	return getSequence(txn, dbi, string(schemaVersionKey))
+}
@@ -0,0 +1,75 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_Migrate(t *testing.T) {
+	db := newTestDB(t, "users")
+	var runs []uint64
+	migration := func(v uint64) Migration {
+		return Migration{Version: v, Apply: func(tx *NamedTxn) error {
+			runs = append(runs, v)
+			return tx.Put("users", []byte(fmt.Sprintf("m%d", v)), nil, 0)
+		}}
+	}
+	migrations := []Migration{migration(1), migration(2), migration(3)}
+
+	n, err := db.Migrate(migrations[:2])
+	if err != nil || n != 2 {
+		t.Fatalf("migrate: %d %v", n, err)
+	}
+	if n, err = db.Migrate(migrations); err != nil || n != 1 {
+		t.Fatalf("migrate again: %d %v", n, err)
+	}
+	if n, err = db.Migrate(migrations); err != nil || n != 0 {
+		t.Fatalf("rerun: %d %v", n, err)
+	}
+	// old migrations may be dropped
+	if n, err = db.Migrate(migrations[2:]); err != nil || n != 0 {
+		t.Fatalf("rerun last: %d %v", n, err)
+	}
+	if fmt.Sprint(runs) != "[1 2 3]" {
+		t.Errorf("runs: %v", runs)
+	}
+	if v, err := db.SchemaVersion(); err != nil || v != 3 {
+		t.Errorf("version: %d %v", v, err)
+	}
+
+	for name, bad := range map[string][]Migration{
+		"gap":       {migration(4), migration(6)},
+		"duplicate": {migration(4), migration(4)},
+		"order":     {migration(5), migration(4)},
+		"zero":      {migration(0)},
+		"ahead":     {migration(5)},
+	} {
+		if n, err := db.Migrate(bad); !errors.Is(err, ErrMigrationOrder) || n != 0 {
+			t.Errorf("%s: %d %v", name, n, err)
+		}
+	}
+	if len(runs) != 3 {
+		t.Errorf("invalid migrations ran: %v", runs)
+	}
+
+	// a failure keeps the version of the last successful migration
+	errFail := errors.New("fail")
+	failing := []Migration{migration(4), {Version: 5, Apply: func(tx *NamedTxn) error {
+		if err := tx.Put("users", []byte("m5"), nil, 0); err != nil {
+			return err
+		}
+		return errFail
+	}}, migration(6)}
+	if n, err := db.Migrate(failing); !errors.Is(err, errFail) || n != 1 {
+		t.Errorf("failing migration: %d %v", n, err)
+	}
+	if v, _ := db.SchemaVersion(); v != 4 {
+		t.Errorf("version after failure: %d", v)
+	}
+	if _, err := db.Read("users", []byte("m5")); !lmdb.IsNotFound(err) {
+		t.Errorf("failed migration committed: %v", err)
+	}
+}
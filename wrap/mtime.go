@@ -0,0 +1,139 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+	"github.com/Data-Corruption/lmdb-go/wrap/keys"
+)
+
+// The modification time database holds two kinds of records. Time records map
+// a key to the time it was last written, index records order the keys of
+// each database by that time.
+//
+//	't' dbName key       -> mtime [tombstone]
+//	'i' dbName mtime key -> nil
+//
+// mtime is the nanosecond Unix time encoded with keys.AppendUint64 and dbName
+// is encoded with keys.AppendString. A time record of a deleted key written
+// with DBOptions.ModTimeTombstones has a trailing 1 byte.
+const (
+	mtimeTimeTag  = 't'
+	mtimeIndexTag = 'i'
+)
+
+var (
+	// ErrNoModTime is returned by ModTime and ModifiedSince when the database
+	// was opened without DBOptions.TrackModTime.
+	ErrNoModTime = errors.New("database does not track modification times")
+
+	errBadModTime = errors.New("malformed modification time record")
+)
+
+// ModTime returns the time key was last written or, with
+// DBOptions.ModTimeTombstones, deleted. If no time was recorded the returned
+// error satisfies lmdb.IsNotFound.
+func (db *DB) ModTime(dbName string, key []byte) (t time.Time, err error) {
+	err = db.observeRead(dbName, func() error {
+		if _, err := db.validateArgs(dbName, key); err != nil {
+			return err
+		}
+		if !db.opts.DBs[dbName].TrackModTime {
+			return fmt.Errorf("%w: %q", ErrNoModTime, dbName)
+		}
+		return db.view(func(txn *lmdb.Txn) error {
+			ns, err := modTime(txn, db.mtimes, mtimeKey(dbName, key))
+			if err == nil {
+				t = time.Unix(0, int64(ns))
+			}
+			return err
+		})
+	})
+	return t, err
+}
+
+// ModifiedSince returns up to limit keys written or, with
+// DBOptions.ModTimeTombstones, deleted at or after t, ordered by their
+// modification time. Zero or less means no limit.
+func (db *DB) ModifiedSince(dbName string, t time.Time, limit int) (modified [][]byte, err error) {
+	err = db.observeRead(dbName, func() error {
+		if _, err := db.getDBI(dbName); err != nil {
+			return err
+		}
+		if !db.opts.DBs[dbName].TrackModTime {
+			return fmt.Errorf("%w: %q", ErrNoModTime, dbName)
+		}
+		prefix := keys.AppendString([]byte{mtimeIndexTag}, dbName)
+		start := keys.AppendUint64(append([]byte(nil), prefix...), uint64(t.UnixNano()))
+		return db.view(func(txn *lmdb.Txn) error {
+			return scan(txn, db.mtimes, start, prefixEnd(prefix), ScanOptions{Limit: limit}, func(k, _ []byte) error {
+				if len(k) < len(prefix)+8 {
+					return errBadModTime
+				}
+				modified = append(modified, k[len(prefix)+8:])
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return modified, nil
+}
+
+// trackModTime records the time of a write made in txn if dbName tracks
+// modification times.
+func (db *DB) trackModTime(txn *lmdb.Txn, op Op, dbName string, key, val []byte) error {
+	o := db.opts.DBs[dbName]
+	if !o.TrackModTime {
+		return nil
+	}
+	tk := mtimeKey(dbName, key)
+	old, err := modTime(txn, db.mtimes, tk)
+	if err == nil {
+		if err := txn.Del(db.mtimes, mtimeIndexKey(dbName, old, key), nil); err != nil && !lmdb.IsNotFound(err) {
+			return err
+		}
+	} else if !lmdb.IsNotFound(err) {
+		return err
+	}
+
+	// removing a member of a DupSort set, which passes the member as val,
+	// modifies the key rather than deleting it
+	deleted := op == OpDelete && val == nil
+	if deleted && !o.ModTimeTombstones {
+		return del(txn, db.mtimes, tk)
+	}
+	now := uint64(time.Now().UnixNano())
+	rec := keys.AppendUint64(nil, now)
+	if deleted {
+		rec = append(rec, 1)
+	}
+	if err := txn.Put(db.mtimes, tk, rec, 0); err != nil {
+		return err
+	}
+	return txn.Put(db.mtimes, mtimeIndexKey(dbName, now, key), nil, 0)
+}
+
+// modTime reads the time record tk.
+func modTime(txn *lmdb.Txn, dbi lmdb.DBI, tk []byte) (uint64, error) {
+	v, err := txn.Get(dbi, tk)
+	if err != nil {
+		return 0, err
+	}
+	if len(v) < 8 {
+		return 0, errBadModTime
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func mtimeKey(dbName string, key []byte) []byte {
+	return append(keys.AppendString([]byte{mtimeTimeTag}, dbName), key...)
+}
+
+func mtimeIndexKey(dbName string, ns uint64, key []byte) []byte {
+	return append(keys.AppendUint64(keys.AppendString([]byte{mtimeIndexTag}, dbName), ns), key...)
+}
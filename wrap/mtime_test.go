@@ -0,0 +1,71 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_ModTime(t *testing.T) {
+	for _, tombstones := range []bool{false, true} {
+		t.Run(fmt.Sprintf("tombstones=%v", tombstones), func(t *testing.T) {
+			opts := DBOptions{TrackModTime: true, ModTimeTombstones: tombstones}
+			db, _, err := New(t.TempDir(), []string{"users", "plain"}, WithDBOptions("users", opts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			start := time.Now()
+			mustWrite(t, db, "users", "a", "b", "c")
+			mid := time.Now()
+			mustWrite(t, db, "users", "a")
+			if err := db.Delete("users", []byte("b")); err != nil {
+				t.Fatal(err)
+			}
+
+			ta, err := db.ModTime("users", []byte("a"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ta.Before(mid) || ta.After(time.Now()) {
+				t.Errorf("mtime of a: %v, not after %v", ta, mid)
+			}
+			if tc, err := db.ModTime("users", []byte("c")); err != nil || tc.Before(start) || !tc.Before(mid) {
+				t.Errorf("mtime of c: %v %v", tc, err)
+			}
+			_, err = db.ModTime("users", []byte("b"))
+			if tombstones && err != nil {
+				t.Errorf("mtime of deleted b: %v", err)
+			}
+			if !tombstones && !lmdb.IsNotFound(err) {
+				t.Errorf("mtime of deleted b: %v", err)
+			}
+
+			want := "[c a]"
+			if tombstones {
+				want = "[c a b]"
+			}
+			modified, err := db.ModifiedSince("users", start, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%s", modified); got != want {
+				t.Errorf("modified since start: %s (!= %s)", got, want)
+			}
+			if modified, _ := db.ModifiedSince("users", mid, 1); fmt.Sprintf("%s", modified) != "[a]" {
+				t.Errorf("modified since mid, limit 1: %s", modified)
+			}
+			if modified, _ := db.ModifiedSince("users", time.Now(), 0); len(modified) != 0 {
+				t.Errorf("modified since now: %s", modified)
+			}
+
+			if _, err := db.ModTime("plain", []byte("a")); !errors.Is(err, ErrNoModTime) {
+				t.Errorf("plain database: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package wrap
+
+import (
+	"fmt"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// NamedView is a read-only transaction that addresses databases by the names
+// passed to New. All methods of a NamedView read from the same transaction and
+// so observe the same committed state. A NamedView is only valid inside the
+// function passed to ViewNamed.
+type NamedView struct {
+	db  *DB
+	txn *lmdb.Txn
+}
+
+// ViewNamed runs fn in a read-only transaction spanning every named database.
+// Use it when related data stored in different databases must be read
+// consistently.
+//
+// Usage:
+//
+//	err := db.ViewNamed(func(v *wrap.NamedView) error {
+//		user, err := v.Get("users", []byte("user:123"))
+//		if err != nil {
+//			return err
+//		}
+//		sessions, err := v.Scan("sessions", []byte("user:123:"), wrap.ScanOptions{})
+//		if err != nil {
+//			return err
+//		}
+//		process(user, sessions)
+//		return nil
+//	})
+func (db *DB) ViewNamed(fn func(v *NamedView) error) error {
+	return db.View(func(txn *lmdb.Txn) error {
+		return fn(&NamedView{db: db, txn: txn})
+	})
+}
+
+// Txn returns the underlying transaction.
+func (v *NamedView) Txn() *lmdb.Txn {
+	return v.txn
+}
+
+// DBI returns the handle of the named database. Unknown names return an error
+// wrapping ErrDbNameNotFound.
+func (v *NamedView) DBI(dbName string) (lmdb.DBI, error) {
+	dbi, err := v.db.getDBI(dbName)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", err, dbName)
+	}
+	return dbi, nil
+}
+
+// Get retrieves a value from the named database.
+func (v *NamedView) Get(dbName string, key []byte) ([]byte, error) {
+	dbi, err := v.DBI(dbName)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+	val, err := v.txn.Get(dbi, key)
+	if err != nil {
+		return nil, err
+	}
+	return v.db.decodeValue(dbName, key, val)
+}
+
+// Has reports whether key is present in the named database.
+func (v *NamedView) Has(dbName string, key []byte) (bool, error) {
+	_, err := v.Get(dbName, key)
+	if lmdb.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Scan is like DB.Scan but reads from the view's transaction.
+func (v *NamedView) Scan(dbName string, prefix []byte, opts ScanOptions) ([]KV, error) {
+	return v.collect(dbName, prefix, prefixEnd(prefix), opts)
+}
+
+// Range is like DB.Range but reads from the view's transaction.
+func (v *NamedView) Range(dbName string, start, end []byte, opts ScanOptions) ([]KV, error) {
+	return v.collect(dbName, start, end, opts)
+}
+
+// ForEach is like DB.ForEach but reads from the view's transaction.
+func (v *NamedView) ForEach(dbName string, prefix []byte, opts ScanOptions, fn func(key, val []byte) error) error {
+	dbi, err := v.DBI(dbName)
+	if err != nil {
+		return err
+	}
+	raw := v.txn.RawRead
+	v.txn.RawRead = opts.RawRead
+	defer func() { v.txn.RawRead = raw }()
+	return scan(v.txn, dbi, prefix, prefixEnd(prefix), opts, v.db.decoding(dbName, fn))
+}
+
+func (v *NamedView) collect(dbName string, lo, hi []byte, opts ScanOptions) ([]KV, error) {
+	dbi, err := v.DBI(dbName)
+	if err != nil {
+		return nil, err
+	}
+	var kvs []KV
+	err = scan(v.txn, dbi, lo, hi, opts, v.db.decoding(dbName, func(k, val []byte) error {
+		kvs = append(kvs, KV{Key: k, Value: val})
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+// NamedTxn is a write transaction that addresses databases by the names passed
+// to New. It offers the read methods of NamedView, which observe the writes
+// made earlier in the same transaction. A NamedTxn is only valid inside the
+// function passed to UpdateNamed.
+type NamedTxn struct {
+	NamedView
+	events  *[]KeyEvent
+	touched *[]string // names of the databases written, may be nil
+}
+
+// UpdateNamed runs fn in a write transaction spanning every named database.
+// The transaction commits if fn returns nil and aborts otherwise. Unlike
+// Update, writes made through Put and Del are reported to subscribers.
+//
+// Usage:
+//
+//	err := db.UpdateNamed(func(tx *wrap.NamedTxn) error {
+//		data, err := tx.Get("users", []byte("user:123"))
+//		if err != nil {
+//			return err
+//		}
+//		return tx.Put("audit", []byte("user:123"), data, 0)
+//	})
+func (db *DB) UpdateNamed(fn func(tx *NamedTxn) error) error {
+	u := &updateOp{events: &[]KeyEvent{}, touched: &[]string{}}
+	u.op = func(txn *lmdb.Txn) error {
+		return fn(&NamedTxn{NamedView: NamedView{db: db, txn: txn}, events: u.events, touched: u.touched})
+	}
+	return db.observeWrite("", func() error { return db.submit(u) })
+}
+
+// Put writes a key/value pair into the named database. flags are passed to
+// lmdb.Txn.Put.
+func (tx *NamedTxn) Put(dbName string, key, val []byte, flags uint) error {
+	dbi, err := tx.DBI(dbName)
+	if err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+	stored, err := tx.db.encodeValue(tx.txn, dbi, dbName, key, val)
+	if err != nil {
+		return err
+	}
+	if err := tx.txn.Put(dbi, key, stored, flags); err != nil {
+		return err
+	}
+	tx.touch(dbName)
+	tx.db.addEvent(tx.events, OpPut, dbName, key, val)
+	return tx.db.recordWrite(tx.txn, OpPut, dbName, key, stored)
+}
+
+// Del deletes key from the named database.
+func (tx *NamedTxn) Del(dbName string, key []byte) error {
+	dbi, err := tx.DBI(dbName)
+	if err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+	if err := tx.txn.Del(dbi, key, nil); err != nil {
+		return err
+	}
+	tx.touch(dbName)
+	tx.db.addEvent(tx.events, OpDelete, dbName, key, nil)
+	return tx.db.recordWrite(tx.txn, OpDelete, dbName, key, nil)
+}
+
+// touch records that the named database was written.
+func (tx *NamedTxn) touch(dbName string) {
+	if tx.touched == nil {
+		return
+	}
+	for _, n := range *tx.touched {
+		if n == dbName {
+			return
+		}
+	}
+	*tx.touched = append(*tx.touched, dbName)
+}
+
+// Cursor opens a cursor on the named database. The cursor may be closed before
+// the transaction ends and is closed by LMDB when it ends, it must not be used
+// afterwards. Writes made through the cursor are not reported to subscribers.
+func (tx *NamedTxn) Cursor(dbName string) (*lmdb.Cursor, error) {
+	dbi, err := tx.DBI(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return tx.txn.OpenCursor(dbi)
+}
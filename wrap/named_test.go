@@ -0,0 +1,140 @@
+package wrap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_ViewNamed(t *testing.T) {
+	db := newTestDB(t, "users", "sessions")
+	mustWrite(t, db, "users", "u1")
+	mustWrite(t, db, "sessions", "u1:a", "u1:b", "u2:a")
+
+	err := db.ViewNamed(func(v *NamedView) error {
+		user, err := v.Get("users", []byte("u1"))
+		if err != nil {
+			return err
+		}
+		if string(user) != "v:u1" {
+			t.Errorf("user: %q", user)
+		}
+		ok, err := v.Has("users", []byte("u2"))
+		if err != nil || ok {
+			t.Errorf("has u2: %v %v", ok, err)
+		}
+		sessions, err := v.Scan("sessions", []byte("u1:"), ScanOptions{})
+		if err != nil {
+			return err
+		}
+		if len(sessions) != 2 {
+			t.Errorf("sessions: %q", sessions)
+		}
+		n := 0
+		err = v.ForEach("sessions", nil, ScanOptions{RawRead: true}, func(k, val []byte) error {
+			n++
+			return nil
+		})
+		if n != 3 {
+			t.Errorf("foreach visited %d", n)
+		}
+		if v.Txn().RawRead {
+			t.Errorf("ForEach leaked RawRead")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDB_ViewNamed_unknown(t *testing.T) {
+	db := newTestDB(t, "a")
+	err := db.ViewNamed(func(v *NamedView) error {
+		_, err := v.Get("nope", []byte("k"))
+		return err
+	})
+	if !errors.Is(err, ErrDbNameNotFound) {
+		t.Fatalf("error: %v", err)
+	}
+	if !strings.Contains(err.Error(), `"nope"`) {
+		t.Errorf("error does not name the database: %v", err)
+	}
+
+	err = db.ViewNamed(func(v *NamedView) error {
+		_, err := v.Get("a", []byte("k"))
+		return err
+	})
+	if !lmdb.IsNotFound(err) {
+		t.Errorf("missing key: %v", err)
+	}
+}
+
+func TestDB_UpdateNamed(t *testing.T) {
+	db := newTestDB(t, "users", "audit")
+	mustWrite(t, db, "users", "u1", "u2")
+	ch := make(chan KeyEvent, 10)
+	db.Subscribe("audit", ch)
+
+	err := db.UpdateNamed(func(tx *NamedTxn) error {
+		data, err := tx.Get("users", []byte("u1"))
+		if err != nil {
+			return err
+		}
+		if err := tx.Put("audit", []byte("u1"), data, 0); err != nil {
+			return err
+		}
+		// reads observe the transaction's own writes
+		if ok, err := tx.Has("audit", []byte("u1")); !ok || err != nil {
+			t.Errorf("has own write: %v %v", ok, err)
+		}
+		if err := tx.Del("users", []byte("u2")); err != nil {
+			return err
+		}
+		cur, err := tx.Cursor("users")
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		k, _, err := cur.Get(nil, nil, lmdb.Last)
+		if err != nil || string(k) != "u1" {
+			t.Errorf("cursor last: %q %v", k, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, err := db.Read("audit", []byte("u1")); err != nil || string(val) != "v:u1" {
+		t.Errorf("audit: %q %v", val, err)
+	}
+	if ev := <-ch; ev.Op != OpPut || string(ev.Key) != "u1" {
+		t.Errorf("event: %v %q", ev.Op, ev.Key)
+	}
+}
+
+func TestDB_UpdateNamed_abort(t *testing.T) {
+	db := newTestDB(t, "a")
+	ch := make(chan KeyEvent, 10)
+	db.Subscribe("a", ch)
+
+	err := db.UpdateNamed(func(tx *NamedTxn) error {
+		if err := tx.Put("a", []byte("k"), []byte("v"), 0); err != nil {
+			return err
+		}
+		return tx.Put("nope", []byte("k"), []byte("v"), 0)
+	})
+	if !errors.Is(err, ErrDbNameNotFound) || !strings.Contains(err.Error(), `"nope"`) {
+		t.Fatalf("unknown name: %v", err)
+	}
+	if _, err := db.Read("a", []byte("k")); !lmdb.IsNotFound(err) {
+		t.Errorf("aborted write visible: %v", err)
+	}
+	select {
+	case ev := <-ch:
+		t.Errorf("event from aborted transaction: %q", ev.Key)
+	default:
+	}
+}
@@ -0,0 +1,82 @@
+package wrap
+
+import "time"
+
+// Observer receives the latency and outcome of DB operations, typically to
+// export them as metrics. Observer methods are called synchronously on the
+// calling goroutine after each operation completes and must be safe for
+// concurrent use.
+//
+// Read, Write, and Delete report the database name they operate on. Update and
+// View, and the helpers built on them, report an empty name since a single
+// transaction may touch any number of databases. The error is passed through
+// unchanged, so a Read of a missing key reports an error satisfying
+// lmdb.IsNotFound.
+type Observer interface {
+	ObserveRead(dbName string, duration time.Duration, err error)
+	ObserveWrite(dbName string, duration time.Duration, err error)
+}
+
+// NoopObserver is an Observer that discards all observations.
+type NoopObserver struct{}
+
+func (NoopObserver) ObserveRead(string, time.Duration, error)  {}
+func (NoopObserver) ObserveWrite(string, time.Duration, error) {}
+
+// PrometheusObserver adapts DB observations to Prometheus-style collectors
+// without making this package depend on a metrics library. Each field is
+// optional and is usually bound to a labeled metric vector:
+//
+//	reads := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "lmdb_read_seconds"}, []string{"db"})
+//	errs := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "lmdb_errors_total"}, []string{"db", "op"})
+//	obs := &wrap.PrometheusObserver{
+//		ReadSeconds: func(db string, s float64) { reads.WithLabelValues(db).Observe(s) },
+//		Error:       func(db, op string) { errs.WithLabelValues(db, op).Inc() },
+//	}
+//	db, _, err := wrap.New(path, names, wrap.WithObserver(obs))
+type PrometheusObserver struct {
+	ReadSeconds  func(dbName string, seconds float64)
+	WriteSeconds func(dbName string, seconds float64)
+
+	// Error is called with op "read" or "write" for each failed operation.
+	Error func(dbName, op string)
+}
+
+func (p *PrometheusObserver) ObserveRead(dbName string, d time.Duration, err error) {
+	p.observe(p.ReadSeconds, "read", dbName, d, err)
+}
+
+func (p *PrometheusObserver) ObserveWrite(dbName string, d time.Duration, err error) {
+	p.observe(p.WriteSeconds, "write", dbName, d, err)
+}
+
+func (p *PrometheusObserver) observe(seconds func(string, float64), op, dbName string, d time.Duration, err error) {
+	if seconds != nil {
+		seconds(dbName, d.Seconds())
+	}
+	if err != nil && p.Error != nil {
+		p.Error(dbName, op)
+	}
+}
+
+// observeRead runs fn and reports it to the observer as a read of dbName.
+func (db *DB) observeRead(dbName string, fn func() error) error {
+	if db.opts.Observer == nil {
+		return fn()
+	}
+	start := time.Now()
+	err := fn()
+	db.opts.Observer.ObserveRead(dbName, time.Since(start), err)
+	return err
+}
+
+// observeWrite runs fn and reports it to the observer as a write to dbName.
+func (db *DB) observeWrite(dbName string, fn func() error) error {
+	if db.opts.Observer == nil {
+		return fn()
+	}
+	start := time.Now()
+	err := fn()
+	db.opts.Observer.ObserveWrite(dbName, time.Since(start), err)
+	return err
+}
@@ -0,0 +1,113 @@
+package wrap
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+type observation struct {
+	write  bool
+	dbName string
+	err    error
+}
+
+type captureObserver struct {
+	mu  sync.Mutex
+	obs []observation
+}
+
+func (c *captureObserver) ObserveRead(dbName string, d time.Duration, err error) {
+	c.add(observation{false, dbName, err}, d)
+}
+
+func (c *captureObserver) ObserveWrite(dbName string, d time.Duration, err error) {
+	c.add(observation{true, dbName, err}, d)
+}
+
+func (c *captureObserver) add(o observation, d time.Duration) {
+	if d < 0 {
+		panic("negative duration")
+	}
+	c.mu.Lock()
+	c.obs = append(c.obs, o)
+	c.mu.Unlock()
+}
+
+func TestDB_observer(t *testing.T) {
+	c := &captureObserver{}
+	db, _, err := New(t.TempDir(), []string{"a"}, WithObserver(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Write("a", []byte("k"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read("a", []byte("k")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read("a", []byte("missing")); !lmdb.IsNotFound(err) {
+		t.Fatal(err)
+	}
+	if err := db.Delete("a", []byte("k")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(txn *lmdb.Txn) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.View(func(txn *lmdb.Txn) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []observation{
+		{true, "a", nil},
+		{false, "a", nil},
+		{false, "a", nil}, // not found, checked below
+		{true, "a", nil},
+		{true, "", nil},
+		{false, "", nil},
+	}
+	if len(c.obs) != len(want) {
+		t.Fatalf("observations: %v", c.obs)
+	}
+	for i, o := range c.obs {
+		if i == 2 {
+			if !lmdb.IsNotFound(o.err) {
+				t.Errorf("observation %d: %v (not NotFound)", i, o.err)
+			}
+			o.err = nil
+		}
+		if o != want[i] {
+			t.Errorf("observation %d: %+v (!= %+v)", i, o, want[i])
+		}
+	}
+}
+
+func TestPrometheusObserver(t *testing.T) {
+	var reads, writes, errs int
+	p := &PrometheusObserver{
+		ReadSeconds:  func(string, float64) { reads++ },
+		WriteSeconds: func(string, float64) { writes++ },
+		Error:        func(dbName, op string) { errs++ },
+	}
+	db, _, err := New(t.TempDir(), []string{"a"}, WithObserver(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.Write("a", []byte("k"), []byte("v"))
+	db.Read("a", []byte("k"))
+	db.Read("a", []byte("missing"))
+	if reads != 2 || writes != 1 || errs != 1 {
+		t.Errorf("reads=%d writes=%d errs=%d", reads, writes, errs)
+	}
+
+	// a partially configured observer must not panic
+	var _ Observer = NoopObserver{}
+	(&PrometheusObserver{}).ObserveWrite("a", time.Second, ErrDBClosed)
+}
@@ -0,0 +1,266 @@
+package wrap
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// DefaultUpdateBufferSize is the capacity of the update queue when
+// Options.UpdateBufferSize is zero.
+const DefaultUpdateBufferSize = 1000
+
+// DefaultWatchBufferSize is the capacity of Watch channels when
+// Options.WatchBufferSize is zero.
+const DefaultWatchBufferSize = 64
+
+// DefaultBackupProgressInterval is the interval between calls to
+// Options.BackupProgress when Options.BackupProgressInterval is zero.
+const DefaultBackupProgressInterval = time.Second
+
+// Options configures a DB opened with New. The zero value is the default
+// configuration.
+type Options struct {
+	// UpdateBufferSize is the number of update operations that may be queued
+	// for the writer goroutine before callers of Update, Write, and Delete
+	// block. Zero means DefaultUpdateBufferSize.
+	//
+	// A larger buffer absorbs bursts of writes but can hide sustained
+	// backpressure and holds more pending operations in memory. A buffer of
+	// 1 admits a single waiting operation, so every other caller blocks until
+	// the operation ahead of it has been picked up by the writer.
+	UpdateBufferSize int
+
+	// Observer, if not nil, is notified of the latency and error of every
+	// read and write, see Observer.
+	Observer Observer
+
+	// CloseTimeout is how long DB.Close waits for open snapshots and
+	// iterators to be closed before failing with ErrHandlesOpen. Zero makes
+	// Close fail immediately if any are open.
+	CloseTimeout time.Duration
+
+	// CompactWait makes operations started while DB.Compact runs wait for it
+	// instead of failing with ErrCompacting. Writes are paused for the whole
+	// compaction and everything else only while the data file is replaced.
+	CompactWait bool
+
+	// WriteTimeout, if positive, bounds how long Update, Write, and Delete
+	// wait for their transaction to run before returning
+	// context.DeadlineExceeded. The timeout starts when the writer goroutine
+	// picks up the operation, time spent waiting behind other queued
+	// operations is not counted.
+	//
+	// A timeout only stops the caller from waiting. The transaction keeps
+	// running and may still commit or abort, so after a timeout its outcome
+	// is unknown. Operations queued behind it run normally once it finishes.
+	WriteTimeout time.Duration
+
+	// CopyTimeout, if positive, bounds how long CopyToWriter may spend
+	// writing a backup to a destination with a SetWriteDeadline method, such
+	// as a net.Conn or a pipe. Writes after the deadline fail.
+	CopyTimeout time.Duration
+
+	// BackupProgress, if not nil, is called from a separate goroutine every
+	// BackupProgressInterval while Backup, BackupCompact, BackupTo, or
+	// CopyToWriter runs. It is passed the bytes written so far and an
+	// estimate of the total, the size of the used part of the data file,
+	// which a compacting backup may not reach. It is never called after the
+	// backup method returns. BackupTo reports progress only for regular
+	// files.
+	BackupProgress func(written, total int64)
+
+	// BackupProgressInterval is the interval between calls to
+	// BackupProgress. Zero means DefaultBackupProgressInterval.
+	BackupProgressInterval time.Duration
+
+	// NumWriteWorkers is the number of writer goroutines, each with its own
+	// queue of UpdateBufferSize operations. Zero means 1.
+	//
+	// LMDB allows a single write transaction at a time, so additional
+	// workers do not commit in parallel. Instead each named database is
+	// assigned to one worker by a hash of its name, and the operations of
+	// Write, Delete, UpdateDB, and the other single-database helpers queue
+	// only behind operations on databases sharing that worker. A burst of
+	// writes to one database then fills only its own queue. Update and the
+	// other multi-database operations always run on the first worker. Key
+	// events of transactions run by different workers may be delivered out of
+	// commit order.
+	NumWriteWorkers int
+
+	// WatchBufferSize is the number of events buffered by each channel
+	// returned by Watch. Zero means DefaultWatchBufferSize.
+	WatchBufferSize int
+
+	// WatchOverflow selects what happens to events for a Watch channel whose
+	// buffer is full. The zero value is DropOldest.
+	WatchOverflow OverflowPolicy
+
+	// OnBeforeCommit, if not nil, runs inside every write transaction after
+	// the operation that started it succeeded, including the internal
+	// transactions of the expirer and TrimChangelog. Writes made through tx
+	// commit with the transaction and returning an error aborts it, so the
+	// hook can veto or augment changes. A panic in the hook aborts the
+	// transaction with an error instead of stopping the writer.
+	OnBeforeCommit func(tx *NamedTxn) error
+
+	// OnAfterCommit, if not nil, is called by the writer goroutine after
+	// every committed write transaction and never for failed ones. It delays
+	// the next queued write, so it should be quick. A panic in the hook is
+	// logged.
+	OnAfterCommit func(summary CommitSummary)
+
+	// DBs holds per-database options keyed by database name. Every key must
+	// be one of the names passed to New.
+	DBs map[string]DBOptions
+}
+
+// DBOptions configures a single named database.
+type DBOptions struct {
+	// Codec encodes the values passed to WriteAny and decodes the values read
+	// by ReadAny. A nil Codec only accepts []byte values.
+	Codec Codec
+
+	// Flags are passed to Txn.OpenDBI along with lmdb.Create when New opens
+	// the database, for example lmdb.DupSort. They only take effect when the
+	// database is created, see the lmdb package for valid combinations.
+	Flags uint
+
+	// DupSort adds lmdb.DupSort to Flags, which lets each key hold a sorted
+	// set of values managed with AddValue, RemoveValue, HasValue, and Members.
+	DupSort bool
+
+	// Versioned stores every value behind an 8-byte version header that is
+	// incremented on each write, see WriteVersioned. The methods writing
+	// values, like Write, Upsert, and Bucket.Write, increment the version
+	// without checking it, and the methods reading values, the scans and
+	// iterators included, strip the header. The *lmdb.Txn of Update, View,
+	// and NamedView.Txn sees the stored values with headers, and dumps,
+	// exports, copies, and merges carry them as stored. Versioned cannot be
+	// combined with DupSort.
+	Versioned bool
+
+	// Checksum stores a checksum behind every value, after the version
+	// header of a Versioned database, so that a value damaged on disk fails
+	// to read with a *ChecksumError rather than returning corrupt data. The
+	// methods writing values add it and the methods reading values, the
+	// scans and iterators included, verify and strip it, and Verify reports
+	// values not matching it. Like the headers of Versioned, checksums are
+	// seen by the *lmdb.Txn of Update, View, and NamedView.Txn and carried
+	// by dumps, exports, copies, and merges. Use AddChecksums to migrate an
+	// existing database. Checksum cannot be combined with DupSort.
+	Checksum ChecksumKind
+
+	// Changelog records every change made to the database by the DB methods
+	// and by NamedTxn in an internal log within the same transaction, see
+	// ReadChangelog. Changes made through the *lmdb.Txn of Update, UpdateDB,
+	// or NamedTxn.Txn cannot be intercepted and are not recorded, so
+	// replicated databases should be written with NamedTxn instead.
+	Changelog bool
+
+	// TrackModTime records the time every key was last written, by the same
+	// methods that record changes for Changelog, see ModTime and
+	// ModifiedSince. Deleting a key removes its time unless
+	// ModTimeTombstones is set, which keeps the time of the delete instead so
+	// ModifiedSince also reports deleted keys.
+	TrackModTime      bool
+	ModTimeTombstones bool
+}
+
+// flags returns the flags New opens the database with.
+func (o DBOptions) flags() uint {
+	if o.DupSort {
+		return o.Flags | lmdb.DupSort
+	}
+	return o.Flags
+}
+
+// Option sets a field of Options. Options are passed to New.
+type Option func(*Options)
+
+// WithCloseTimeout sets Options.CloseTimeout.
+func WithCloseTimeout(d time.Duration) Option {
+	return func(o *Options) { o.CloseTimeout = d }
+}
+
+// WithCompactWait sets Options.CompactWait.
+func WithCompactWait(wait bool) Option {
+	return func(o *Options) { o.CompactWait = wait }
+}
+
+// WithWriteTimeout sets Options.WriteTimeout.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(o *Options) { o.WriteTimeout = d }
+}
+
+// WithCopyTimeout sets Options.CopyTimeout.
+func WithCopyTimeout(d time.Duration) Option {
+	return func(o *Options) { o.CopyTimeout = d }
+}
+
+// WithBackupProgress sets Options.BackupProgress and
+// Options.BackupProgressInterval.
+func WithBackupProgress(interval time.Duration, fn func(written, total int64)) Option {
+	return func(o *Options) {
+		o.BackupProgress = fn
+		o.BackupProgressInterval = interval
+	}
+}
+
+// WithDBOptions sets the options of the named database in Options.DBs.
+func WithDBOptions(dbName string, dbOpts DBOptions) Option {
+	return func(o *Options) {
+		if o.DBs == nil {
+			o.DBs = make(map[string]DBOptions)
+		}
+		o.DBs[dbName] = dbOpts
+	}
+}
+
+// WithNumWriteWorkers sets Options.NumWriteWorkers.
+func WithNumWriteWorkers(n int) Option {
+	return func(o *Options) { o.NumWriteWorkers = n }
+}
+
+// WithUpdateBufferSize sets Options.UpdateBufferSize.
+func WithUpdateBufferSize(n int) Option {
+	return func(o *Options) { o.UpdateBufferSize = n }
+}
+
+// WithObserver sets Options.Observer.
+func WithObserver(obs Observer) Option {
+	return func(o *Options) { o.Observer = obs }
+}
+
+// buildOptions applies opts to the default Options and validates the result.
+func buildOptions(opts []Option) (Options, error) {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.UpdateBufferSize < 0 || o.CloseTimeout < 0 || o.NumWriteWorkers < 0 || o.WatchBufferSize < 0 || o.BackupProgressInterval < 0 {
+		return o, ErrInvalidOption
+	}
+	for name, d := range o.DBs {
+		if d.Checksum > ChecksumCRC32C || d.Checksum != ChecksumNone && d.flags()&lmdb.DupSort != 0 {
+			return o, fmt.Errorf("%w: checksum %s for %q", ErrInvalidOption, d.Checksum, name)
+		}
+		if d.Versioned && d.flags()&lmdb.DupSort != 0 {
+			return o, fmt.Errorf("%w: Versioned with DupSort for %q", ErrInvalidOption, name)
+		}
+	}
+	if o.UpdateBufferSize == 0 {
+		o.UpdateBufferSize = DefaultUpdateBufferSize
+	}
+	if o.NumWriteWorkers == 0 {
+		o.NumWriteWorkers = 1
+	}
+	if o.WatchBufferSize == 0 {
+		o.WatchBufferSize = DefaultWatchBufferSize
+	}
+	if o.BackupProgressInterval == 0 {
+		o.BackupProgressInterval = DefaultBackupProgressInterval
+	}
+	return o, nil
+}
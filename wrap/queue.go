@@ -0,0 +1,128 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// ErrQueueEmpty is returned by Queue.Pop and Queue.Peek when the queue holds
+// no values.
+var ErrQueueEmpty = errors.New("queue is empty")
+
+// Queue is a durable FIFO queue stored in a named database. Values are stored
+// under their 8-byte big-endian id, so the database must only be modified
+// through the Queue. A Queue is safe for concurrent use.
+type Queue struct {
+	db      *DB
+	name    string
+	seqName string
+}
+
+// NewQueue returns a Queue backed by the named database.
+func NewQueue(db *DB, dbName string) (*Queue, error) {
+	if _, err := db.getDBI(dbName); err != nil {
+		return nil, err
+	}
+	// ids come from an internal sequence, so they keep increasing even after
+	// the queue has been drained
+	return &Queue{db: db, name: dbName, seqName: "\x00queue\x00" + dbName}, nil
+}
+
+// Push appends val to the queue and returns its id. Ids are unique and
+// increase in the order values are committed, so concurrent pushers each get a
+// distinct id.
+func (q *Queue) Push(val []byte) (id uint64, err error) {
+	err = q.db.observeWrite(q.name, func() error {
+		dbi, err := q.db.getDBI(q.name)
+		if err != nil {
+			return err
+		}
+		events := &[]KeyEvent{}
+		return q.db.updateEvents(q.name, func(txn *lmdb.Txn) (err error) {
+			if err := q.db.refreshDBI(q.name, &dbi); err != nil {
+				return err
+			}
+			if id, err = q.db.incSequence(txn, q.seqName); err != nil {
+				return err
+			}
+			key := queueKey(id)
+			stored, err := q.db.encodeValue(txn, dbi, q.name, key, val)
+			if err != nil {
+				return err
+			}
+			if err := txn.Put(dbi, key, stored, lmdb.Append); err != nil {
+				return err
+			}
+			q.db.addEvent(events, OpPut, q.name, key, val)
+			return q.db.recordWrite(txn, OpPut, q.name, key, stored)
+		}, events)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Pop removes the oldest value from the queue and returns it. The read and
+// the delete happen in one transaction, so each value is popped once.
+func (q *Queue) Pop() (val []byte, err error) {
+	err = q.db.observeWrite(q.name, func() error {
+		dbi, err := q.db.getDBI(q.name)
+		if err != nil {
+			return err
+		}
+		events := &[]KeyEvent{}
+		return q.db.updateEvents(q.name, func(txn *lmdb.Txn) error {
+			if err := q.db.refreshDBI(q.name, &dbi); err != nil {
+				return err
+			}
+			cur, err := txn.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+			var key []byte
+			key, val, err = cur.Get(nil, nil, lmdb.First)
+			if lmdb.IsNotFound(err) {
+				return ErrQueueEmpty
+			}
+			if err == nil {
+				val, err = q.db.decodeValue(q.name, key, val)
+			}
+			if err != nil {
+				return err
+			}
+			if err := cur.Del(0); err != nil {
+				return err
+			}
+			q.db.addEvent(events, OpDelete, q.name, key, nil)
+			return q.db.recordWrite(txn, OpDelete, q.name, key, nil)
+		}, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// Peek returns the oldest value in the queue without removing it.
+func (q *Queue) Peek() ([]byte, error) {
+	_, val, err := q.db.First(q.name)
+	if lmdb.IsNotFound(err) {
+		return nil, ErrQueueEmpty
+	}
+	return val, err
+}
+
+// Len returns the number of values in the queue.
+func (q *Queue) Len() (uint64, error) {
+	return q.db.Count(q.name)
+}
+
+func queueKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
@@ -0,0 +1,108 @@
+package wrap
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	db := newTestDB(t, "jobs")
+	q, err := NewQueue(db, "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Pop(); err != ErrQueueEmpty {
+		t.Errorf("pop empty: %v", err)
+	}
+	if _, err := q.Peek(); err != ErrQueueEmpty {
+		t.Errorf("peek empty: %v", err)
+	}
+
+	for i, v := range []string{"a", "b", "c"} {
+		id, err := q.Push([]byte(v))
+		if err != nil || id != uint64(i+1) {
+			t.Errorf("push %q: %d %v", v, id, err)
+		}
+	}
+	if n, _ := q.Len(); n != 3 {
+		t.Errorf("len: %d", n)
+	}
+	if v, err := q.Peek(); err != nil || string(v) != "a" {
+		t.Errorf("peek: %q %v", v, err)
+	}
+	for _, want := range []string{"a", "b", "c"} {
+		v, err := q.Pop()
+		if err != nil || string(v) != want {
+			t.Errorf("pop: %q %v (!= %q)", v, err, want)
+		}
+	}
+	if _, err := q.Pop(); err != ErrQueueEmpty {
+		t.Errorf("pop drained: %v", err)
+	}
+
+	// ids keep increasing after the queue was drained
+	if id, _ := q.Push([]byte("d")); id != 4 {
+		t.Errorf("id after drain: %d (!= 4)", id)
+	}
+}
+
+func TestQueue_concurrent(t *testing.T) {
+	db := newTestDB(t, "jobs")
+	q, err := NewQueue(db, "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 50
+	ids := make([]uint64, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := q.Push([]byte(fmt.Sprint(i)))
+			if err != nil {
+				t.Error(err)
+			}
+			ids[i] = id
+		}(i)
+	}
+	wg.Wait()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for i, id := range ids {
+		if id != uint64(i+1) {
+			t.Fatalf("ids are not unique and dense: %v", ids)
+		}
+	}
+	if n, _ := q.Len(); n != 50 {
+		t.Errorf("len: %d", n)
+	}
+}
+
+func TestQueue_checksum(t *testing.T) {
+	db := newChecksumDB(t, t.TempDir())
+	defer db.Close()
+	for _, name := range []string{"users", "versions"} {
+		q, err := NewQueue(db, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range []string{"a", "b"} {
+			if _, err := q.Push([]byte(v)); err != nil {
+				t.Fatalf("%s: push %q: %v", name, v, err)
+			}
+		}
+		if v, err := q.Peek(); err != nil || string(v) != "a" {
+			t.Errorf("%s: peek: %q %v", name, v, err)
+		}
+		if r, err := db.Verify(VerifyOpts{}); err != nil || !r.OK() {
+			t.Errorf("%s: verify: %+v %v", name, r, err)
+		}
+		for _, want := range []string{"a", "b"} {
+			if v, err := q.Pop(); err != nil || string(v) != want {
+				t.Errorf("%s: pop: %q %v (!= %q)", name, v, err, want)
+			}
+		}
+	}
+}
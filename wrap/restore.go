@@ -0,0 +1,210 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// ErrEnvExists is returned by Restore when the destination already holds an
+// environment.
+var ErrEnvExists = errors.New("environment already exists")
+
+// ErrTruncated is returned by VerifyBackup when the data file is shorter than
+// its meta page says.
+var ErrTruncated = errors.New("data file truncated")
+
+// BackupReport describes a backup checked by VerifyBackup.
+type BackupReport struct {
+	Entries map[string]uint64 // entries per database
+	Bytes   map[string]uint64 // total size of the keys and values per database
+}
+
+// VerifyBackup checks the environment in dirPath, typically written by Backup,
+// by reading every key and value of the databases in dbNames. If dbNames is
+// empty every named database in the environment is checked, internal ones
+// included. The environment is opened readonly without locking, so dirPath
+// must not be in use by a writer.
+//
+// Damage found by LMDB is returned as an error satisfying lmdb.IsCorrupted or
+// lmdb.IsErrno(err, lmdb.PageNotFound). A data file shorter than its meta page
+// says is reported with an error wrapping ErrTruncated before any page is
+// read. A database missing from the backup is reported with an error wrapping
+// ErrDbNameNotFound.
+func VerifyBackup(dirPath string, dbNames []string) (BackupReport, error) {
+	report := BackupReport{Entries: make(map[string]uint64), Bytes: make(map[string]uint64)}
+	path := filepath.Join(dirPath, "data.mdb")
+	if _, err := os.Stat(path); err != nil {
+		return report, err
+	}
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		return report, err
+	}
+	defer env.Close()
+	if err := env.SetMaxDBs(MaxNamedDBs); err != nil {
+		return report, err
+	}
+	if err := env.SetMapSize(MapSize); err != nil {
+		return report, err
+	}
+	if err := env.Open(dirPath, lmdb.Readonly|lmdb.NoLock, fileMode); err != nil {
+		return report, err
+	}
+	if err := checkDataSize(env, path); err != nil {
+		return report, err
+	}
+	err = env.View(func(txn *lmdb.Txn) error {
+		txn.RawRead = true
+		if len(dbNames) == 0 {
+			if dbNames, err = namedDBs(txn); err != nil {
+				return err
+			}
+		}
+		for _, name := range dbNames {
+			dbi, err := txn.OpenDBI(name, 0)
+			if lmdb.IsNotFound(err) {
+				return fmt.Errorf("%w: %q", ErrDbNameNotFound, name)
+			}
+			if err != nil {
+				return err
+			}
+			n, size, err := verifyDBI(txn, dbi)
+			if err != nil {
+				return fmt.Errorf("database %q: %w", name, err)
+			}
+			report.Entries[name], report.Bytes[name] = n, size
+		}
+		return nil
+	})
+	return report, err
+}
+
+// namedDBs returns the names of the databases listed in the root database.
+func namedDBs(txn *lmdb.Txn) ([]string, error) {
+	root, err := txn.OpenRoot(0)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	err = scan(txn, root, nil, nil, ScanOptions{}, func(k, v []byte) error {
+		names = append(names, string(k))
+		return nil
+	})
+	return names, err
+}
+
+// verifyDBI reads every item of dbi and returns their number and size.
+func verifyDBI(txn *lmdb.Txn, dbi lmdb.DBI) (n, size uint64, err error) {
+	err = readMap(func() error {
+		// hashing reads every byte of every value
+		h := crc32.NewIEEE()
+		return scan(txn, dbi, nil, nil, ScanOptions{}, func(k, v []byte) error {
+			h.Write(v)
+			n++
+			size += uint64(len(k) + len(v))
+			return nil
+		})
+	})
+	return n, size, err
+}
+
+// checkDataSize returns an error wrapping ErrTruncated if the data file path
+// of env is shorter than the pages its meta page counts. Reading the map past
+// the end of the file raises SIGBUS, which inside LMDB crashes the process.
+// Opening env reads only the two meta pages, and fails unless both are in the
+// file.
+func checkDataSize(env *lmdb.Env, path string) error {
+	info, err := env.Info()
+	if err != nil {
+		return err
+	}
+	stat, err := env.Stat()
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if want := (info.LastPNO + 1) * int64(stat.PSize); fi.Size() < want {
+		return fmt.Errorf("%w: %q holds %d bytes, its meta page counts %d", ErrTruncated, path, fi.Size(), want)
+	}
+	return nil
+}
+
+// readMap runs fn, which reads the memory map, and reports a fault raised by
+// the Go code of fn as an error instead of crashing. Faults raised inside
+// LMDB, in C, are not caught; checkDataSize rules out the truncated data
+// file that would cause them.
+func readMap(fn func() error) (err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("reading the memory map failed: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// Restore checks the backup in srcDir with VerifyBackup and copies its data
+// file into destDir, creating the directory if needed. If destDir already
+// holds an environment Restore returns an error wrapping ErrEnvExists, unless
+// force is set in which case the environment is replaced. The data file is
+// written under a temporary name and renamed into place, so an interrupted
+// Restore leaves any previous environment intact. destDir must not be opened
+// by any process while Restore runs.
+func Restore(srcDir, destDir string, force bool) error {
+	if _, err := VerifyBackup(srcDir, nil); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(destDir, dirMode); err != nil {
+		return err
+	}
+	dest := filepath.Join(destDir, "data.mdb")
+	if _, err := os.Stat(dest); err == nil && !force {
+		return fmt.Errorf("%w: %q", ErrEnvExists, destDir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp := dest + ".restore"
+	if err := copyFile(filepath.Join(srcDir, "data.mdb"), tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// the lock file of a replaced environment describes stale readers
+	if err := os.Remove(filepath.Join(destDir, "lock.mdb")); err != nil && !os.IsNotExist(err) {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// copyFile copies the file src to dst and syncs dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,117 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestBackup writes a backup of two databases to a new directory.
+func newTestBackup(t *testing.T) string {
+	t.Helper()
+	db := newTestDB(t, "users", "empty")
+	for i := 0; i < 50; i++ {
+		mustWrite(t, db, "users", fmt.Sprintf("user:%03d", i))
+	}
+	if err := db.Write("users", []byte("big"), bytes.Repeat([]byte("x"), 100000)); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "backup")
+	if err := db.Backup(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestVerifyBackup(t *testing.T) {
+	dir := newTestBackup(t)
+
+	report, err := VerifyBackup(dir, []string{"users", "empty"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Entries["users"] != 51 || report.Entries["empty"] != 0 {
+		t.Errorf("entries: %v", report.Entries)
+	}
+	if report.Bytes["users"] < 100000 {
+		t.Errorf("bytes: %v", report.Bytes)
+	}
+
+	report, err = VerifyBackup(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := report.Entries[sequencesDbName]; !ok || report.Entries["users"] != 51 {
+		t.Errorf("all databases: %v", report.Entries)
+	}
+
+	if _, err := VerifyBackup(dir, []string{"nope"}); !errors.Is(err, ErrDbNameNotFound) {
+		t.Errorf("missing database: %v", err)
+	}
+	if _, err := VerifyBackup(t.TempDir(), nil); !os.IsNotExist(err) {
+		t.Errorf("empty directory: %v", err)
+	}
+
+	bad := t.TempDir()
+	garbage := bytes.Repeat([]byte("not lmdb"), 4096)
+	if err := os.WriteFile(filepath.Join(bad, "data.mdb"), garbage, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyBackup(bad, nil); err == nil {
+		t.Errorf("garbage data file verified")
+	}
+
+	// one page short: walking it would crash inside LMDB
+	data := filepath.Join(dir, "data.mdb")
+	fi, err := os.Stat(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(data, fi.Size()-int64(os.Getpagesize())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyBackup(dir, nil); !errors.Is(err, ErrTruncated) {
+		t.Errorf("truncated data file: %v", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	src := newTestBackup(t)
+	dest := filepath.Join(t.TempDir(), "restored")
+
+	if err := Restore(src, dest, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "data.mdb.restore")); !os.IsNotExist(err) {
+		t.Errorf("temporary file left: %v", err)
+	}
+	if err := Restore(src, dest, false); !errors.Is(err, ErrEnvExists) {
+		t.Errorf("existing environment: %v", err)
+	}
+
+	db, _, err := New(dest, []string{"users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, db, "users", "extra")
+	db.Close()
+
+	if err := Restore(src, dest, true); err != nil {
+		t.Fatal(err)
+	}
+	db, _, err = New(dest, []string{"users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if n, err := db.Count("users"); err != nil || n != 51 {
+		t.Errorf("restored count %d %v", n, err)
+	}
+
+	if err := Restore(t.TempDir(), dest, true); err == nil {
+		t.Errorf("restored from an empty directory")
+	}
+}
@@ -0,0 +1,39 @@
+package wrap
+
+import "github.com/Data-Corruption/lmdb-go/lmdb"
+
+// ViewResult runs fn in a read-only transaction with DB.View and returns the
+// value it computes. On error the zero value of T is returned.
+//
+// Transactions start with RawRead disabled, so slices returned by txn.Get
+// are copies that may safely be part of the result. If fn enables
+// txn.RawRead, it must copy any []byte that ends up in the result since such
+// slices point into the memory map and are invalid once fn returns.
+func ViewResult[T any](db *DB, fn func(txn *lmdb.Txn) (T, error)) (T, error) {
+	var res T
+	err := db.View(func(txn *lmdb.Txn) (err error) {
+		res, err = fn(txn)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return res, nil
+}
+
+// UpdateResult runs fn in a write transaction with DB.Update and returns the
+// value it computes. On error, including a failed commit, the zero value of T
+// is returned. The caveats of ViewResult about RawRead apply.
+func UpdateResult[T any](db *DB, fn func(txn *lmdb.Txn) (T, error)) (T, error) {
+	var res T
+	err := db.Update(func(txn *lmdb.Txn) (err error) {
+		res, err = fn(txn)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return res, nil
+}
@@ -0,0 +1,51 @@
+package wrap
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestViewResult(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "k")
+	dbi := db.GetDBis()["a"]
+
+	val, err := ViewResult(db, func(txn *lmdb.Txn) ([]byte, error) {
+		return txn.Get(dbi, []byte("k"))
+	})
+	if err != nil || string(val) != "v:k" {
+		t.Errorf("view: %q %v", val, err)
+	}
+
+	n, err := ViewResult(db, func(txn *lmdb.Txn) (int, error) {
+		return 42, errors.New("fail")
+	})
+	if err == nil || n != 0 {
+		t.Errorf("error result: %d %v", n, err)
+	}
+}
+
+func TestUpdateResult(t *testing.T) {
+	db := newTestDB(t, "a")
+	dbi := db.GetDBis()["a"]
+
+	old, err := UpdateResult(db, func(txn *lmdb.Txn) (string, error) {
+		return "none", txn.Put(dbi, []byte("k"), []byte("v"), 0)
+	})
+	if err != nil || old != "none" {
+		t.Errorf("update: %q %v", old, err)
+	}
+
+	// a failing put aborts and yields the zero value
+	s, err := UpdateResult(db, func(txn *lmdb.Txn) (string, error) {
+		return "partial", txn.Put(dbi, []byte("k"), []byte("v2"), lmdb.NoOverwrite)
+	})
+	if !lmdb.IsErrno(err, lmdb.KeyExist) || s != "" {
+		t.Errorf("failed update: %q %v", s, err)
+	}
+	if val, _ := db.Read("a", []byte("k")); string(val) != "v" {
+		t.Errorf("value: %q", val)
+	}
+}
@@ -0,0 +1,108 @@
+package wrap
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// sampleTailLen is the number of random bytes appended to a sampling target
+// after the byte where the first and last keys diverge.
+const sampleTailLen = 8
+
+// SampleKeys returns up to n distinct keys chosen pseudo-randomly from the
+// database without scanning it. If the database holds n entries or fewer all
+// of its keys are returned.
+//
+// Each sample seeks to a random target between the first and last keys and
+// takes the first key not less than the target, so sampling costs O(log N) per
+// key and only holds a read transaction for the duration of n seeks. The
+// sample is not uniform: a key is picked with probability proportional to the
+// size of the gap in key space preceding it, which favors keys that follow
+// sparsely populated ranges. Fewer than n keys may be returned when many
+// targets land on the same key.
+func (db *DB) SampleKeys(dbName string, n int) ([][]byte, error) {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var keys [][]byte
+	err = db.View(func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		stat, err := txn.Stat(dbi)
+		if err != nil {
+			return err
+		}
+		if stat.Entries <= uint64(n) {
+			return scan(txn, dbi, nil, nil, ScanOptions{}, func(k, v []byte) error {
+				keys = append(keys, k)
+				return nil
+			})
+		}
+
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		first, _, err := cur.Get(nil, nil, lmdb.First)
+		if err != nil {
+			return err
+		}
+		last, _, err := cur.Get(nil, nil, lmdb.Last)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[string]struct{}, n)
+		for attempts := 0; len(keys) < n && attempts < 4*n; attempts++ {
+			k, _, err := cur.Get(sampleTarget(rnd, first, last), nil, lmdb.SetRange)
+			if lmdb.IsNotFound(err) {
+				// the target fell after the last key
+				k, err = last, nil
+			}
+			if err != nil {
+				return err
+			}
+			if _, ok := seen[string(k)]; ok {
+				continue
+			}
+			seen[string(k)] = struct{}{}
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// sampleTarget returns a random key that shares the common prefix of first
+// and last, with the following byte drawn between their bytes at that
+// position and a random tail.
+func sampleTarget(rnd *rand.Rand, first, last []byte) []byte {
+	i := 0
+	for i < len(first) && i < len(last) && first[i] == last[i] {
+		i++
+	}
+	lo, hi := 0, 0xFF
+	if i < len(first) {
+		lo = int(first[i])
+	}
+	if i < len(last) {
+		hi = int(last[i])
+	}
+	target := make([]byte, i+1+sampleTailLen)
+	copy(target, first[:i])
+	target[i] = byte(lo + rnd.Intn(hi-lo+1))
+	rnd.Read(target[i+1:])
+	return target
+}
@@ -0,0 +1,56 @@
+package wrap
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDB_SampleKeys(t *testing.T) {
+	db := newTestDB(t, "a")
+	for i := 0; i < 1000; i++ {
+		mustWrite(t, db, "a", fmt.Sprintf("user:%04d", i))
+	}
+
+	keys, err := db.SampleKeys("a", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) == 0 || len(keys) > 20 {
+		t.Fatalf("sampled %d keys", len(keys))
+	}
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if !strings.HasPrefix(string(k), "user:") {
+			t.Errorf("unexpected key: %q", k)
+		}
+		if seen[string(k)] {
+			t.Errorf("duplicate key: %q", k)
+		}
+		seen[string(k)] = true
+		if _, err := db.Read("a", k); err != nil {
+			t.Errorf("sampled key %q: %v", k, err)
+		}
+	}
+}
+
+func TestDB_SampleKeys_small(t *testing.T) {
+	db := newTestDB(t, "a")
+
+	keys, err := db.SampleKeys("a", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("sampled %d keys from an empty database", len(keys))
+	}
+
+	mustWrite(t, db, "a", "a", "b", "c")
+	keys, err = db.SampleKeys("a", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Errorf("sampled %d keys (!= 3)", len(keys))
+	}
+}
@@ -0,0 +1,316 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+var (
+	// ErrStopScan may be returned by a ForEach callback to end the iteration
+	// early without causing ForEach to return an error.
+	ErrStopScan = errors.New("stop scan")
+
+	// ErrInvalidPageToken is returned when a page token is malformed or was
+	// issued for a different prefix or direction.
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+// ErrNotFound is returned when a lookup matches no key. It satisfies
+// lmdb.IsNotFound and errors.Is(err, lmdb.NotFound), like the errors LMDB
+// reports for missing keys.
+var ErrNotFound error = &lmdb.OpError{Op: "mdb_cursor_get", Errno: lmdb.NotFound}
+
+// pageTokenVersion is the first byte of every page token so the encoding can
+// change without misinterpreting tokens issued by older versions.
+const pageTokenVersion = 1
+
+// KV is a key/value pair returned by the scanning methods.
+type KV struct {
+	Key   []byte
+	Value []byte
+}
+
+// ScanOptions controls the iteration performed by Scan, Range, and ForEach.
+type ScanOptions struct {
+	Limit   int  // Maximum number of entries visited, zero or less means no limit.
+	Reverse bool // Visit entries in descending key order.
+
+	// RawRead passes slices pointing directly into the memory map to
+	// callbacks instead of copies. Such slices are readonly and only valid
+	// until the callback returns. Methods returning pairs to the caller, like
+	// Scan and Range, always copy and ignore RawRead.
+	RawRead bool
+}
+
+// Scan returns all key/value pairs whose key starts with prefix. An empty
+// prefix matches every key in the database.
+func (db *DB) Scan(dbName string, prefix []byte, opts ScanOptions) ([]KV, error) {
+	return db.collect(dbName, prefix, prefixEnd(prefix), opts)
+}
+
+// Range returns all key/value pairs with keys in the half-open interval
+// [start, end). An empty start begins at the first key and an empty end
+// continues through the last key.
+func (db *DB) Range(dbName string, start, end []byte, opts ScanOptions) ([]KV, error) {
+	return db.collect(dbName, start, end, opts)
+}
+
+// ForEach calls fn for each key/value pair whose key starts with prefix. The
+// slices passed to fn are copies owned by the caller unless opts.RawRead is
+// set. Iteration stops at the first error returned by fn, which ForEach
+// returns unless it is ErrStopScan.
+func (db *DB) ForEach(dbName string, prefix []byte, opts ScanOptions, fn func(key, val []byte) error) error {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return err
+	}
+	return db.forEach(dbName, dbi, prefix, opts, db.decoding(dbName, fn))
+}
+
+func (db *DB) forEach(dbName string, dbi lmdb.DBI, prefix []byte, opts ScanOptions, fn func(key, val []byte) error) error {
+	return db.View(func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		txn.RawRead = opts.RawRead
+		return scan(txn, dbi, prefix, prefixEnd(prefix), opts, fn)
+	})
+}
+
+// First returns the pair with the smallest key in the database, for example
+// as the lower boundary of a range query. The slices are copies owned by the
+// caller. If the database is empty First returns ErrNotFound.
+func (db *DB) First(dbName string) (key, val []byte, err error) {
+	return db.edge(dbName, nil, false)
+}
+
+// Last returns the pair with the largest key in the database. The slices are
+// copies owned by the caller. If the database is empty Last returns
+// ErrNotFound.
+func (db *DB) Last(dbName string) (key, val []byte, err error) {
+	return db.edge(dbName, nil, true)
+}
+
+// FirstPrefix returns the pair with the smallest key starting with prefix. If
+// no key matches the returned error satisfies lmdb.IsNotFound.
+func (db *DB) FirstPrefix(dbName string, prefix []byte) (key, val []byte, err error) {
+	return db.edge(dbName, prefix, false)
+}
+
+// LastPrefix returns the pair with the largest key starting with prefix. If no
+// key matches the returned error satisfies lmdb.IsNotFound.
+func (db *DB) LastPrefix(dbName string, prefix []byte) (key, val []byte, err error) {
+	return db.edge(dbName, prefix, true)
+}
+
+// edge is a helper for First, Last, FirstPrefix, and LastPrefix.
+func (db *DB) edge(dbName string, prefix []byte, reverse bool) (key, val []byte, err error) {
+	kvs, err := db.Scan(dbName, prefix, ScanOptions{Limit: 1, Reverse: reverse})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(kvs) == 0 {
+		return nil, nil, ErrNotFound
+	}
+	return kvs[0].Key, kvs[0].Value, nil
+}
+
+// ScanPage returns up to limit key/value pairs whose key starts with prefix,
+// continuing after the page that produced token. Pass a nil token to fetch the
+// first page. The returned nextToken is nil once the scan is complete.
+//
+// Tokens are plain encodings of the last key returned, so they remain valid
+// across transactions and process restarts. If the last key of a page is
+// deleted before the next page is requested the scan resumes at the next key
+// after it.
+func (db *DB) ScanPage(dbName string, prefix []byte, limit int, token []byte) (kvs []KV, nextToken []byte, err error) {
+	return db.scanPage(dbName, prefix, limit, token, false)
+}
+
+// ScanPageReverse behaves like ScanPage but returns pages in descending key
+// order. Tokens issued by ScanPage and ScanPageReverse are not
+// interchangeable.
+func (db *DB) ScanPageReverse(dbName string, prefix []byte, limit int, token []byte) (kvs []KV, nextToken []byte, err error) {
+	return db.scanPage(dbName, prefix, limit, token, true)
+}
+
+func (db *DB) scanPage(dbName string, prefix []byte, limit int, token []byte, reverse bool) ([]KV, []byte, error) {
+	lo, hi := prefix, prefixEnd(prefix)
+	if token != nil {
+		last, err := decodePageToken(token, prefix, reverse)
+		if err != nil {
+			return nil, nil, err
+		}
+		if reverse {
+			hi = last
+		} else {
+			// the smallest key strictly greater than last
+			lo = append(last, 0)
+		}
+	}
+	opts := ScanOptions{Reverse: reverse}
+	if limit > 0 {
+		// fetch one extra pair to learn whether another page exists
+		opts.Limit = limit + 1
+	}
+	kvs, err := db.collect(dbName, lo, hi, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if limit <= 0 || len(kvs) <= limit {
+		return kvs, nil, nil
+	}
+	kvs = kvs[:limit]
+	return kvs, encodePageToken(kvs[limit-1].Key, reverse), nil
+}
+
+// encodePageToken encodes the last key of a page and the scan direction.
+func encodePageToken(last []byte, reverse bool) []byte {
+	token := make([]byte, 2, 2+len(last))
+	token[0] = pageTokenVersion
+	if reverse {
+		token[1] = 1
+	}
+	return append(token, last...)
+}
+
+// decodePageToken returns the last key encoded in token after verifying it was
+// issued for a scan over prefix in the same direction.
+func decodePageToken(token, prefix []byte, reverse bool) ([]byte, error) {
+	if len(token) < 3 || token[0] != pageTokenVersion || token[1] > 1 || (token[1] == 1) != reverse {
+		return nil, ErrInvalidPageToken
+	}
+	last := make([]byte, len(token)-2)
+	copy(last, token[2:])
+	if !bytes.HasPrefix(last, prefix) {
+		return nil, ErrInvalidPageToken
+	}
+	return last, nil
+}
+
+// collect is a helper for Scan and Range gathering the visited pairs.
+func (db *DB) collect(dbName string, lo, hi []byte, opts ScanOptions) ([]KV, error) {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return nil, err
+	}
+	var kvs []KV
+	err = db.View(func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		return scan(txn, dbi, lo, hi, opts, db.decoding(dbName, func(k, v []byte) error {
+			kvs = append(kvs, KV{Key: k, Value: v})
+			return nil
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+// scan walks the keys of dbi in the half-open interval [lo, hi), in descending
+// order if opts.Reverse is set. A nil hi leaves the interval unbounded above.
+func scan(txn *lmdb.Txn, dbi lmdb.DBI, lo, hi []byte, opts ScanOptions, fn func(k, v []byte) error) error {
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return err
+	}
+	defer cur.Close()
+
+	var k, v []byte
+	var next uint = lmdb.Next
+	if opts.Reverse {
+		next = lmdb.Prev
+		k, v, err = seekLast(cur, hi)
+	} else {
+		k, v, err = seekFirst(cur, lo)
+	}
+	for n := 0; opts.Limit <= 0 || n < opts.Limit; n++ {
+		if lmdb.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if opts.Reverse && bytes.Compare(k, lo) < 0 {
+			return nil
+		}
+		if !opts.Reverse && len(hi) > 0 && bytes.Compare(k, hi) >= 0 {
+			return nil
+		}
+		if err = fn(k, v); err != nil {
+			if err == ErrStopScan {
+				return nil
+			}
+			return err
+		}
+		k, v, err = cur.Get(nil, nil, next)
+	}
+	return nil
+}
+
+// seekFirst positions cur at the first key not less than lo.
+func seekFirst(cur *lmdb.Cursor, lo []byte) (k, v []byte, err error) {
+	if len(lo) == 0 {
+		return cur.Get(nil, nil, lmdb.First)
+	}
+	return cur.Get(lo, nil, lmdb.SetRange)
+}
+
+// seekLast positions cur at the last key strictly less than hi.
+func seekLast(cur *lmdb.Cursor, hi []byte) (k, v []byte, err error) {
+	if len(hi) == 0 {
+		return cur.Get(nil, nil, lmdb.Last)
+	}
+	_, _, err = cur.Get(hi, nil, lmdb.SetRange)
+	if lmdb.IsNotFound(err) {
+		// every key is below hi
+		return cur.Get(nil, nil, lmdb.Last)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return cur.Get(nil, nil, lmdb.Prev)
+}
+
+// seekAfter positions cur of dbi at the entry following prevK and prevV, in
+// the order of dbi, or at the first entry if prevK is nil. prevV is only used
+// in DupSort databases. It lets a walk split over several transactions
+// resume where the previous one stopped.
+func seekAfter(txn *lmdb.Txn, cur *lmdb.Cursor, dbi lmdb.DBI, prevK, prevV []byte, dupSort bool) (k, v []byte, err error) {
+	if prevK == nil {
+		return cur.Get(nil, nil, lmdb.First)
+	}
+	if dupSort {
+		k, v, err = cur.Get(prevK, prevV, lmdb.GetBothRange)
+		if err == nil && txn.DCmp(dbi, v, prevV) == 0 {
+			return cur.Get(nil, nil, lmdb.Next)
+		}
+		if !lmdb.IsNotFound(err) {
+			return k, v, err
+		}
+	}
+	k, v, err = cur.Get(prevK, nil, lmdb.SetRange)
+	if err == nil && txn.Cmp(dbi, k, prevK) == 0 {
+		return cur.NextNoDup()
+	}
+	return k, v, err
+}
+
+// prefixEnd returns the smallest key greater than every key starting with
+// prefix, or nil if no such key exists (prefix is empty or all 0xFF bytes).
+func prefixEnd(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xFF {
+			end := make([]byte, i+1)
+			copy(end, prefix)
+			end[i]++
+			return end
+		}
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func scanKeys(kvs []KV) []string {
+	keys := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		keys = append(keys, string(kv.Key))
+	}
+	return keys
+}
+
+func TestDB_Scan(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a", "b1", "b2", "b3", "c")
+
+	for _, test := range []struct {
+		prefix string
+		opts   ScanOptions
+		keys   []string
+	}{
+		{"", ScanOptions{}, []string{"a", "b1", "b2", "b3", "c"}},
+		{"", ScanOptions{Reverse: true}, []string{"c", "b3", "b2", "b1", "a"}},
+		{"b", ScanOptions{}, []string{"b1", "b2", "b3"}},
+		{"b", ScanOptions{Reverse: true}, []string{"b3", "b2", "b1"}},
+		{"b", ScanOptions{Limit: 2}, []string{"b1", "b2"}},
+		{"b", ScanOptions{Limit: 2, Reverse: true}, []string{"b3", "b2"}},
+		{"c", ScanOptions{Reverse: true}, []string{"c"}},
+		{"d", ScanOptions{}, []string{}},
+		{"d", ScanOptions{Reverse: true}, []string{}},
+	} {
+		kvs, err := db.Scan("a", []byte(test.prefix), test.opts)
+		if err != nil {
+			t.Errorf("scan %q %+v: %v", test.prefix, test.opts, err)
+			continue
+		}
+		if keys := scanKeys(kvs); !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("scan %q %+v: %q (!= %q)", test.prefix, test.opts, keys, test.keys)
+		}
+	}
+}
+
+func TestDB_Scan_prefix0xFF(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a",
+		"\x01\xfe", "\x01\xff", "\x01\xff\x00", "\x01\xff\xff", "\x02",
+		"\xff", "\xff\xff", "\xff\xff\x01",
+	)
+
+	for _, test := range []struct {
+		prefix string
+		keys   []string
+	}{
+		{"\x01\xff", []string{"\x01\xff\xff", "\x01\xff\x00", "\x01\xff"}},
+		{"\x01", []string{"\x01\xff\xff", "\x01\xff\x00", "\x01\xff", "\x01\xfe"}},
+		{"\xff", []string{"\xff\xff\x01", "\xff\xff", "\xff"}},
+		{"\xff\xff", []string{"\xff\xff\x01", "\xff\xff"}},
+		{"\xff\xff\xff", []string{}},
+	} {
+		kvs, err := db.Scan("a", []byte(test.prefix), ScanOptions{Reverse: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := scanKeys(kvs); !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("reverse scan %q: %q (!= %q)", test.prefix, keys, test.keys)
+		}
+	}
+}
+
+func TestDB_Range(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a", "b", "c", "d")
+
+	for _, test := range []struct {
+		start, end string
+		opts       ScanOptions
+		keys       []string
+	}{
+		{"b", "d", ScanOptions{}, []string{"b", "c"}},
+		{"b", "d", ScanOptions{Reverse: true}, []string{"c", "b"}},
+		{"", "c", ScanOptions{Reverse: true}, []string{"b", "a"}},
+		{"bb", "", ScanOptions{}, []string{"c", "d"}},
+		{"bb", "", ScanOptions{Reverse: true}, []string{"d", "c"}},
+		{"d", "b", ScanOptions{}, []string{}},
+		{"d", "b", ScanOptions{Reverse: true}, []string{}},
+	} {
+		kvs, err := db.Range("a", []byte(test.start), []byte(test.end), test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := scanKeys(kvs); !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("range [%q, %q) %+v: %q (!= %q)", test.start, test.end, test.opts, keys, test.keys)
+		}
+	}
+}
+
+func TestDB_ForEach(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a", "b", "c")
+
+	var keys []string
+	err := db.ForEach("a", nil, ScanOptions{Reverse: true}, func(k, v []byte) error {
+		keys = append(keys, string(k))
+		if string(v) != "v:"+string(k) {
+			t.Errorf("value %q: %q", k, v)
+		}
+		if len(keys) == 2 {
+			return ErrStopScan
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"c", "b"}) {
+		t.Errorf("keys: %q", keys)
+	}
+
+	errTest := errors.New("test")
+	err = db.ForEach("a", nil, ScanOptions{}, func(k, v []byte) error { return errTest })
+	if err != errTest {
+		t.Errorf("error: %v", err)
+	}
+	if err := db.ForEach("b", nil, ScanOptions{}, nil); err != ErrDbNameNotFound {
+		t.Errorf("unknown name: %v", err)
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	for _, test := range []struct{ prefix, end []byte }{
+		{nil, nil},
+		{[]byte{0xff, 0xff}, nil},
+		{[]byte{0x01}, []byte{0x02}},
+		{[]byte{0x01, 0xff}, []byte{0x02}},
+		{[]byte{0x01, 0xfe, 0xff}, []byte{0x01, 0xff}},
+	} {
+		if end := prefixEnd(test.prefix); !reflect.DeepEqual(end, test.end) {
+			t.Errorf("prefixEnd(%x): %x (!= %x)", test.prefix, end, test.end)
+		}
+	}
+}
+
+func TestDB_ScanPage(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a", "p1", "p2", "p3", "p4", "p5", "q")
+
+	for _, reverse := range []bool{false, true} {
+		page := db.ScanPage
+		want := []string{"p1", "p2", "p3", "p4", "p5"}
+		if reverse {
+			page = db.ScanPageReverse
+			want = []string{"p5", "p4", "p3", "p2", "p1"}
+		}
+
+		var keys []string
+		var token []byte
+		var pages int
+		for {
+			kvs, next, err := page("a", []byte("p"), 2, token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages++
+			keys = append(keys, scanKeys(kvs)...)
+			if next == nil {
+				break
+			}
+			token = next
+		}
+		if pages != 3 {
+			t.Errorf("reverse=%v: %d pages (!= 3)", reverse, pages)
+		}
+		if !reflect.DeepEqual(keys, want) {
+			t.Errorf("reverse=%v: %q (!= %q)", reverse, keys, want)
+		}
+	}
+}
+
+func TestDB_ScanPage_boundaryDeleted(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "p1", "p2", "p3", "p4")
+
+	kvs, token, err := db.ScanPage("a", []byte("p"), 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := scanKeys(kvs); !reflect.DeepEqual(keys, []string{"p1", "p2"}) {
+		t.Fatalf("first page: %q", keys)
+	}
+	if err := db.Delete("a", []byte("p2")); err != nil {
+		t.Fatal(err)
+	}
+	kvs, token, err = db.ScanPage("a", []byte("p"), 2, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := scanKeys(kvs); !reflect.DeepEqual(keys, []string{"p3", "p4"}) {
+		t.Errorf("second page: %q", keys)
+	}
+	if token != nil {
+		t.Errorf("unexpected token: %q", token)
+	}
+}
+
+func TestDB_ScanPage_invalidToken(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "p1", "p2", "p3")
+
+	_, token, err := db.ScanPage("a", []byte("p"), 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := db.ScanPageReverse("a", []byte("p"), 1, token); err != ErrInvalidPageToken {
+		t.Errorf("direction mismatch: %v", err)
+	}
+	if _, _, err := db.ScanPage("a", []byte("q"), 1, token); err != ErrInvalidPageToken {
+		t.Errorf("prefix mismatch: %v", err)
+	}
+	if _, _, err := db.ScanPage("a", []byte("p"), 1, []byte("garbage")); err != ErrInvalidPageToken {
+		t.Errorf("garbage: %v", err)
+	}
+}
+
+func TestDB_FirstLast(t *testing.T) {
+	db := newTestDB(t, "a")
+
+	if _, _, err := db.First("a"); !lmdb.IsNotFound(err) {
+		t.Errorf("first on empty database: %v", err)
+	}
+	if _, _, err := db.Last("a"); !lmdb.IsNotFound(err) {
+		t.Errorf("last on empty database: %v", err)
+	}
+
+	mustWrite(t, db, "a", "m", "b", "x", "p1", "p2", "q")
+
+	for _, test := range []struct {
+		name string
+		fn   func() ([]byte, []byte, error)
+		key  string
+	}{
+		{"first", func() ([]byte, []byte, error) { return db.First("a") }, "b"},
+		{"last", func() ([]byte, []byte, error) { return db.Last("a") }, "x"},
+		{"first prefix", func() ([]byte, []byte, error) { return db.FirstPrefix("a", []byte("p")) }, "p1"},
+		{"last prefix", func() ([]byte, []byte, error) { return db.LastPrefix("a", []byte("p")) }, "p2"},
+	} {
+		k, v, err := test.fn()
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if string(k) != test.key || string(v) != "v:"+test.key {
+			t.Errorf("%s: %q=%q (!= %q)", test.name, k, v, test.key)
+		}
+	}
+
+	if _, _, err := db.FirstPrefix("a", []byte("z")); !lmdb.IsNotFound(err) {
+		t.Errorf("first prefix without match: %v", err)
+	}
+	if _, _, err := db.LastPrefix("a", []byte("c")); !lmdb.IsNotFound(err) {
+		t.Errorf("last prefix without match: %v", err)
+	}
+}
+
+func TestDB_FirstLast_shuffled(t *testing.T) {
+	db := newTestDB(t, "a")
+	if _, _, err := db.First("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("first on empty database: %v", err)
+	}
+	if _, _, err := db.Last("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("last on empty database: %v", err)
+	}
+
+	for _, i := range rand.New(rand.NewSource(1)).Perm(100) {
+		mustWrite(t, db, "a", fmt.Sprintf("k%03d", i))
+	}
+	k, v, err := db.First("a")
+	if err != nil || string(k) != "k000" || string(v) != "v:k000" {
+		t.Fatalf("first: %q=%q, %v", k, v, err)
+	}
+	// the slices are the caller's
+	k[0], v[0] = 'x', 'x'
+	if k, v, err = db.First("a"); err != nil || string(k) != "k000" || string(v) != "v:k000" {
+		t.Errorf("first after modifying the result: %q=%q, %v", k, v, err)
+	}
+	if k, v, err = db.Last("a"); err != nil || string(k) != "k099" || string(v) != "v:k099" {
+		t.Errorf("last: %q=%q, %v", k, v, err)
+	}
+}
@@ -0,0 +1,65 @@
+//go:build go1.23
+
+package wrap
+
+import "iter"
+
+// All returns a sequence of every key/value pair in the database in ascending
+// key order, for use with range:
+//
+//	for k, v := range db.All("users") {
+//		process(k, v)
+//	}
+//
+// The read transaction is opened when iteration starts and released when it
+// ends, including when the loop exits early. The yielded slices are copies.
+// Errors end the sequence silently, use Seq when they must be observed.
+func (db *DB) All(dbName string) iter.Seq2[[]byte, []byte] {
+	seq, _ := db.Seq(dbName, nil, ScanOptions{})
+	return seq
+}
+
+// AllReverse is like All but yields pairs in descending key order.
+func (db *DB) AllReverse(dbName string) iter.Seq2[[]byte, []byte] {
+	seq, _ := db.Seq(dbName, nil, ScanOptions{Reverse: true})
+	return seq
+}
+
+// Prefix returns a sequence of the key/value pairs whose key starts with
+// prefix in ascending key order. See All for details.
+func (db *DB) Prefix(dbName string, prefix []byte) iter.Seq2[[]byte, []byte] {
+	seq, _ := db.Seq(dbName, prefix, ScanOptions{})
+	return seq
+}
+
+// PrefixReverse is like Prefix but yields pairs in descending key order.
+func (db *DB) PrefixReverse(dbName string, prefix []byte) iter.Seq2[[]byte, []byte] {
+	seq, _ := db.Seq(dbName, prefix, ScanOptions{Reverse: true})
+	return seq
+}
+
+// Seq returns a sequence of the key/value pairs whose key starts with prefix,
+// visited according to opts, along with a function returning the error that
+// ended the most recent iteration, if any. With opts.RawRead the yielded
+// slices point into the memory map and are only valid until the loop body
+// for that pair completes.
+//
+//	seq, errf := db.Seq("events", []byte("2024-"), wrap.ScanOptions{Reverse: true, Limit: 10})
+//	for k, v := range seq {
+//		process(k, v)
+//	}
+//	if err := errf(); err != nil {
+//		return err
+//	}
+func (db *DB) Seq(dbName string, prefix []byte, opts ScanOptions) (iter.Seq2[[]byte, []byte], func() error) {
+	var err error
+	seq := func(yield func([]byte, []byte) bool) {
+		err = db.ForEach(dbName, prefix, opts, func(k, v []byte) error {
+			if !yield(k, v) {
+				return ErrStopScan
+			}
+			return nil
+		})
+	}
+	return seq, func() error { return err }
+}
@@ -0,0 +1,89 @@
+//go:build go1.23
+
+package wrap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDB_All(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a", "p1", "p2", "q")
+
+	for _, test := range []struct {
+		name string
+		seq  func(yield func([]byte, []byte) bool)
+		keys []string
+	}{
+		{"all", db.All("a"), []string{"a", "p1", "p2", "q"}},
+		{"all reverse", db.AllReverse("a"), []string{"q", "p2", "p1", "a"}},
+		{"prefix", db.Prefix("a", []byte("p")), []string{"p1", "p2"}},
+		{"prefix reverse", db.PrefixReverse("a", []byte("p")), []string{"p2", "p1"}},
+	} {
+		keys := []string{}
+		for k, v := range test.seq {
+			if string(v) != "v:"+string(k) {
+				t.Errorf("%s: value %q=%q", test.name, k, v)
+			}
+			keys = append(keys, string(k))
+		}
+		if !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("%s: %q (!= %q)", test.name, keys, test.keys)
+		}
+	}
+}
+
+func TestDB_All_break(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a", "b", "c")
+
+	var keys []string
+	for k := range db.All("a") {
+		keys = append(keys, string(k))
+		break
+	}
+	if !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Errorf("keys: %q", keys)
+	}
+
+	// the transaction must have been released so a reader slot is free for
+	// every subsequent iteration.
+	info, err := db.env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < int(info.MaxReaders)+1; i++ {
+		for range db.All("a") {
+			break
+		}
+	}
+	if _, err := db.Read("a", []byte("a")); err != nil {
+		t.Errorf("read after iterations: %v", err)
+	}
+}
+
+func TestDB_Seq(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "a", "b", "c")
+
+	seq, errf := db.Seq("a", nil, ScanOptions{Limit: 2, Reverse: true, RawRead: true})
+	var keys []string
+	for k := range seq {
+		keys = append(keys, string(k))
+	}
+	if err := errf(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"c", "b"}) {
+		t.Errorf("keys: %q", keys)
+	}
+
+	seq, errf = db.Seq("b", nil, ScanOptions{})
+	for range seq {
+		t.Errorf("unknown database yielded a pair")
+	}
+	if err := errf(); err != ErrDbNameNotFound {
+		t.Errorf("unknown name: %v", err)
+	}
+}
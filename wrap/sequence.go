@@ -0,0 +1,140 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// errBadSequence is returned when a stored sequence is not 8 bytes long.
+var errBadSequence = errors.New("malformed sequence value")
+
+// NextSequence increments the named sequence and returns its new value. A
+// sequence starts at 1 and, after reaching the largest uint64, wraps around
+// to 1 so that 0 always means the sequence was never used. Sequences are
+// persisted in an internal database, independent of the named databases.
+func (db *DB) NextSequence(seqName string) (uint64, error) {
+	if seqName == "" {
+		return 0, ErrEmptyKey
+	}
+	var next uint64
+	err := db.Update(func(txn *lmdb.Txn) (err error) {
+		next, err = db.incSequence(txn, seqName)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// incSequence increments the named sequence in txn, see NextSequence.
+func (db *DB) incSequence(txn *lmdb.Txn, seqName string) (uint64, error) {
+	cur, err := getSequence(txn, db.seqs, seqName)
+	if err != nil {
+		return 0, err
+	}
+	next := cur + 1
+	if next == 0 {
+		next = 1
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], next)
+	return next, txn.Put(db.seqs, []byte(seqName), b[:], 0)
+}
+
+// PeekSequence returns the current value of the named sequence without
+// changing it, or 0 if NextSequence was never called for it.
+func (db *DB) PeekSequence(seqName string) (uint64, error) {
+	if seqName == "" {
+		return 0, ErrEmptyKey
+	}
+	var cur uint64
+	err := db.View(func(txn *lmdb.Txn) (err error) {
+		cur, err = getSequence(txn, db.seqs, seqName)
+		return err
+	})
+	return cur, err
+}
+
+// getSequence reads the value of a sequence, a missing sequence is 0.
+func getSequence(txn *lmdb.Txn, dbi lmdb.DBI, seqName string) (uint64, error) {
+	return parseSequence(txn.Get(dbi, []byte(seqName)))
+}
+
+// parseSequence returns the sequence read as v, or 0 if err reports it
+// missing.
+func parseSequence(v []byte, err error) (uint64, error) {
+	if lmdb.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(v) != 8 {
+		return 0, errBadSequence
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// ErrIDOverflow is returned by NextID and NextIDBatch when allocating would
+// exceed the largest uint64.
+var ErrIDOverflow = errors.New("id counter overflow")
+
+// NextID increments the counter stored under seqName in the named database and
+// returns its new value, starting at 1. The counter is stored as 8 big-endian
+// bytes, so counters and the ids they produce sort numerically. Unlike
+// NextSequence, NextID never wraps around and returns ErrIDOverflow instead.
+func (db *DB) NextID(dbName string, seqName []byte) (uint64, error) {
+	return db.NextIDBatch(dbName, seqName, 1)
+}
+
+// NextIDBatch reserves n consecutive ids from the counter stored under seqName
+// in a single transaction and returns the first one. The caller owns the ids
+// start through start+n-1.
+func (db *DB) NextIDBatch(dbName string, seqName []byte, n int) (start uint64, err error) {
+	if n <= 0 {
+		return 0, errors.New("wrap: id batch size must be positive")
+	}
+	err = db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, seqName)
+		if err != nil {
+			return err
+		}
+		events := &[]KeyEvent{}
+		return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			stored, err := txn.Get(dbi, seqName)
+			if err == nil {
+				stored, err = db.decodeValue(dbName, seqName, stored)
+			}
+			cur, err := parseSequence(stored, err)
+			if err != nil {
+				return err
+			}
+			if cur > math.MaxUint64-uint64(n) {
+				return ErrIDOverflow
+			}
+			start = cur + 1
+			var b [8]byte
+			binary.BigEndian.PutUint64(b[:], cur+uint64(n))
+			stored, err = db.encodeValue(txn, dbi, dbName, seqName, b[:])
+			if err != nil {
+				return err
+			}
+			if err := txn.Put(dbi, seqName, stored, 0); err != nil {
+				return err
+			}
+			db.addEvent(events, OpPut, dbName, seqName, b[:])
+			return db.recordWrite(txn, OpPut, dbName, seqName, stored)
+		}, events)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return start, nil
+}
@@ -0,0 +1,159 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_NextSequence(t *testing.T) {
+	dir := t.TempDir()
+	db, _, err := New(dir, []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.PeekSequence("orders"); err != nil || v != 0 {
+		t.Errorf("unused sequence: %d %v", v, err)
+	}
+	for want := uint64(1); want <= 3; want++ {
+		v, err := db.NextSequence("orders")
+		if err != nil || v != want {
+			t.Errorf("next: %d %v (!= %d)", v, err, want)
+		}
+	}
+	if v, _ := db.NextSequence("users"); v != 1 {
+		t.Errorf("independent sequence: %d", v)
+	}
+	if v, _ := db.PeekSequence("orders"); v != 3 {
+		t.Errorf("peek: %d", v)
+	}
+	if _, err := db.NextSequence(""); err != ErrEmptyKey {
+		t.Errorf("empty name: %v", err)
+	}
+	db.Close()
+
+	// sequences survive a restart
+	db, _, err = New(dir, []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if v, _ := db.NextSequence("orders"); v != 4 {
+		t.Errorf("after reopen: %d", v)
+	}
+}
+
+func TestDB_NextSequence_wrap(t *testing.T) {
+	db := newTestDB(t, "a")
+	err := db.Update(func(txn *lmdb.Txn) error {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.MaxUint64)
+		return txn.Put(db.seqs, []byte("s"), b, 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.NextSequence("s"); err != nil || v != 1 {
+		t.Errorf("overflow: %d %v (!= 1)", v, err)
+	}
+}
+
+func TestDB_NextSequence_concurrent(t *testing.T) {
+	db := newTestDB(t, "a")
+	const n = 50
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := db.NextSequence("s")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			seen[v] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for v := uint64(1); v <= n; v++ {
+		if !seen[v] {
+			t.Errorf("missing value %d", v)
+		}
+	}
+}
+
+func TestNew_reservedName(t *testing.T) {
+	if _, _, err := New(t.TempDir(), []string{"a", "__sequences__"}); err != ErrReservedDbName {
+		t.Errorf("reserved name: %v", err)
+	}
+}
+
+func TestDB_NextID(t *testing.T) {
+	db := newTestDB(t, "ids")
+	for want := uint64(1); want <= 3; want++ {
+		id, err := db.NextID("ids", []byte("order"))
+		if err != nil || id != want {
+			t.Errorf("next: %d %v (!= %d)", id, err, want)
+		}
+	}
+	start, err := db.NextIDBatch("ids", []byte("order"), 100)
+	if err != nil || start != 4 {
+		t.Errorf("batch: %d %v (!= 4)", start, err)
+	}
+	if id, _ := db.NextID("ids", []byte("order")); id != 104 {
+		t.Errorf("after batch: %d (!= 104)", id)
+	}
+	raw, err := db.Read("ids", []byte("order"))
+	if err != nil || binary.BigEndian.Uint64(raw) != 104 {
+		t.Errorf("stored counter: %x %v", raw, err)
+	}
+	if _, err := db.NextIDBatch("ids", []byte("order"), 0); err == nil {
+		t.Errorf("zero batch succeeded")
+	}
+}
+
+func TestDB_NextID_overflow(t *testing.T) {
+	db := newTestDB(t, "ids")
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.MaxUint64-2)
+	if err := db.Write("ids", []byte("s"), b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NextIDBatch("ids", []byte("s"), 3); err != ErrIDOverflow {
+		t.Errorf("overflowing batch: %v", err)
+	}
+	start, err := db.NextIDBatch("ids", []byte("s"), 2)
+	if err != nil || start != math.MaxUint64-1 {
+		t.Errorf("last batch: %d %v", start, err)
+	}
+	if _, err := db.NextID("ids", []byte("s")); err != ErrIDOverflow {
+		t.Errorf("exhausted: %v", err)
+	}
+}
+
+func TestDB_NextID_checksum(t *testing.T) {
+	db := newChecksumDB(t, t.TempDir())
+	defer db.Close()
+	for _, name := range []string{"users", "versions"} {
+		for want := uint64(1); want <= 2; want++ {
+			id, err := db.NextID(name, []byte("order"))
+			if err != nil || id != want {
+				t.Errorf("%s: next: %d %v (!= %d)", name, id, err, want)
+			}
+		}
+		raw, err := db.Read(name, []byte("order"))
+		if err != nil || len(raw) != 8 || binary.BigEndian.Uint64(raw) != 2 {
+			t.Errorf("%s: stored counter: %x %v", name, raw, err)
+		}
+	}
+	if r, err := db.Verify(VerifyOpts{}); err != nil || !r.OK() {
+		t.Errorf("verify: %+v %v", r, err)
+	}
+}
@@ -0,0 +1,145 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// ErrNotDupSort is returned by the set methods when the database was not
+// created with DBOptions.DupSort.
+var ErrNotDupSort = errors.New("database is not DupSort")
+
+// AddValue adds member to the set of values stored under key. Adding a
+// member that is already present is not an error.
+func (db *DB) AddValue(dbName string, key, member []byte) error {
+	return db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		events := &[]KeyEvent{}
+		return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			if err := checkDupSort(txn, dbName, dbi); err != nil {
+				return err
+			}
+			err := txn.Put(dbi, key, member, lmdb.NoDupData)
+			if lmdb.IsErrno(err, lmdb.KeyExist) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			db.addEvent(events, OpPut, dbName, key, member)
+			return db.recordWrite(txn, OpPut, dbName, key, member)
+		}, events)
+	})
+}
+
+// RemoveValue removes member from the set of values stored under key. If
+// member is not in the set the returned error satisfies lmdb.IsNotFound.
+func (db *DB) RemoveValue(dbName string, key, member []byte) error {
+	return db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			if err := checkDupSort(txn, dbName, dbi); err != nil {
+				return err
+			}
+			if err := txn.Del(dbi, key, member); err != nil {
+				return err
+			}
+			return db.recordWrite(txn, OpDelete, dbName, key, member)
+		}, db.event(OpDelete, dbName, key, nil))
+	})
+}
+
+// HasValue reports whether member is in the set of values stored under key.
+func (db *DB) HasValue(dbName string, key, member []byte) (found bool, err error) {
+	err = db.observeRead(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		return db.view(func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			if err := checkDupSort(txn, dbName, dbi); err != nil {
+				return err
+			}
+			cur, err := txn.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+			txn.RawRead = true
+			_, _, err = cur.Get(key, member, lmdb.GetBoth)
+			if lmdb.IsNotFound(err) {
+				return nil
+			}
+			found = err == nil
+			return err
+		})
+	})
+	return found, err
+}
+
+// Members returns up to limit values of the set stored under key in
+// ascending order, zero or less means no limit. A missing key has no members.
+func (db *DB) Members(dbName string, key []byte, limit int) (members [][]byte, err error) {
+	err = db.observeRead(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		return db.view(func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			if err := checkDupSort(txn, dbName, dbi); err != nil {
+				return err
+			}
+			cur, err := txn.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+			_, v, err := cur.Get(key, nil, lmdb.Set)
+			for ; err == nil && (limit <= 0 || len(members) < limit); _, v, err = cur.Get(nil, nil, lmdb.NextDup) {
+				members = append(members, v)
+			}
+			if lmdb.IsNotFound(err) {
+				return nil
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// checkDupSort returns ErrNotDupSort unless dbi was created with DupSort.
+// The persisted flags are checked because they win over the ones requested
+// when the database already existed.
+func checkDupSort(txn *lmdb.Txn, dbName string, dbi lmdb.DBI) error {
+	flags, err := txn.Flags(dbi)
+	if err != nil {
+		return err
+	}
+	if flags&lmdb.DupSort == 0 {
+		return fmt.Errorf("%w: %q", ErrNotDupSort, dbName)
+	}
+	return nil
+}
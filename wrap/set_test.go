@@ -0,0 +1,60 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_sets(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"tags", "plain"}, WithDBOptions("tags", DBOptions{DupSort: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	key := []byte("go")
+	for _, m := range []string{"doc3", "doc1", "doc2", "doc1"} {
+		if err := db.AddValue("tags", key, []byte(m)); err != nil {
+			t.Fatalf("add %q: %v", m, err)
+		}
+	}
+	members, err := db.Members("tags", key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(bytes.Join(members, []byte(","))); got != "doc1,doc2,doc3" {
+		t.Errorf("members: %s", got)
+	}
+	if members, _ := db.Members("tags", key, 2); len(members) != 2 {
+		t.Errorf("limited members: %d", len(members))
+	}
+	if members, err := db.Members("tags", []byte("rust"), 0); err != nil || len(members) != 0 {
+		t.Errorf("missing key: %q %v", members, err)
+	}
+
+	if ok, err := db.HasValue("tags", key, []byte("doc2")); err != nil || !ok {
+		t.Errorf("has doc2: %v %v", ok, err)
+	}
+	if err := db.RemoveValue("tags", key, []byte("doc2")); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := db.HasValue("tags", key, []byte("doc2")); err != nil || ok {
+		t.Errorf("has removed doc2: %v %v", ok, err)
+	}
+	if err := db.RemoveValue("tags", key, []byte("doc2")); !lmdb.IsNotFound(err) {
+		t.Errorf("remove missing: %v", err)
+	}
+	if ok, err := db.HasValue("tags", []byte("rust"), []byte("doc1")); err != nil || ok {
+		t.Errorf("has on missing key: %v %v", ok, err)
+	}
+
+	if err := db.AddValue("plain", key, []byte("doc1")); !errors.Is(err, ErrNotDupSort) {
+		t.Errorf("add to plain: %v", err)
+	}
+	if _, err := db.Members("plain", key, 0); !errors.Is(err, ErrNotDupSort) {
+		t.Errorf("members of plain: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package wrap
+
+import (
+	"log"
+	"runtime"
+	"sync"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// Snapshot is a read-only view of the DB fixed at the moment it was created.
+// Every read made through a Snapshot observes the same committed state, no
+// matter how many writes are committed in the meantime.
+//
+// A Snapshot holds a read transaction, and with it a slot in the environment's
+// reader table, until Close is called. While it is open LMDB cannot reuse the
+// pages freed by later writes, so a long-lived Snapshot makes the data file
+// grow under a steady write load. Snapshots should be closed as soon as the
+// consistent view is no longer needed, and DB.Close fails with ErrHandlesOpen
+// while any are open.
+//
+// The methods of a Snapshot are safe for concurrent use. They fail with
+// ErrDBClosed, which here means the Snapshot itself is closed, after Close.
+type Snapshot struct {
+	db  *DB
+	mu  sync.Mutex // LMDB transactions must not be used concurrently
+	txn *lmdb.Txn
+}
+
+// Snapshot begins a Snapshot of the current committed state of the DB.
+func (db *DB) Snapshot() (*Snapshot, error) {
+	txn, err := db.beginSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{db: db, txn: txn}
+	runtime.SetFinalizer(s, func(s *Snapshot) {
+		log.Printf("wrap: closing unreachable snapshot %p, call Close when done", s)
+		s.Close()
+	})
+	return s, nil
+}
+
+// Read retrieves a value as of the snapshot.
+func (s *Snapshot) Read(dbName string, key []byte) ([]byte, error) {
+	dbi, err := s.db.validateArgs(dbName, key)
+	if err != nil {
+		return nil, err
+	}
+	var val []byte
+	err = s.view(func(txn *lmdb.Txn) (err error) {
+		if val, err = txn.Get(dbi, key); err == nil {
+			val, err = s.db.decodeValue(dbName, key, val)
+		}
+		return err
+	})
+	return val, err
+}
+
+// Has reports whether key was present as of the snapshot.
+func (s *Snapshot) Has(dbName string, key []byte) (bool, error) {
+	_, err := s.Read(dbName, key)
+	if lmdb.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Scan is like DB.Scan but reads from the snapshot.
+func (s *Snapshot) Scan(dbName string, prefix []byte, opts ScanOptions) ([]KV, error) {
+	return s.collect(dbName, prefix, prefixEnd(prefix), opts)
+}
+
+// Range is like DB.Range but reads from the snapshot.
+func (s *Snapshot) Range(dbName string, start, end []byte, opts ScanOptions) ([]KV, error) {
+	return s.collect(dbName, start, end, opts)
+}
+
+// ForEach is like DB.ForEach but reads from the snapshot.
+func (s *Snapshot) ForEach(dbName string, prefix []byte, opts ScanOptions, fn func(key, val []byte) error) error {
+	dbi, err := s.db.getDBI(dbName)
+	if err != nil {
+		return err
+	}
+	return s.view(func(txn *lmdb.Txn) error {
+		txn.RawRead = opts.RawRead
+		defer func() { txn.RawRead = false }()
+		return scan(txn, dbi, prefix, prefixEnd(prefix), opts, s.db.decoding(dbName, fn))
+	})
+}
+
+// Close releases the snapshot's read transaction. Close is idempotent.
+func (s *Snapshot) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.txn == nil {
+		return nil
+	}
+	runtime.SetFinalizer(s, nil)
+	s.db.endSnapshot(s.txn)
+	s.txn = nil
+	return nil
+}
+
+func (s *Snapshot) collect(dbName string, lo, hi []byte, opts ScanOptions) ([]KV, error) {
+	dbi, err := s.db.getDBI(dbName)
+	if err != nil {
+		return nil, err
+	}
+	var kvs []KV
+	err = s.view(func(txn *lmdb.Txn) error {
+		return scan(txn, dbi, lo, hi, opts, s.db.decoding(dbName, func(k, v []byte) error {
+			kvs = append(kvs, KV{Key: k, Value: v})
+			return nil
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+// view runs fn on the snapshot's transaction.
+func (s *Snapshot) view(fn func(txn *lmdb.Txn) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.txn == nil {
+		return ErrDBClosed
+	}
+	return fn(s.txn)
+}
+
+// beginSnapshot begins a read transaction that outlives a single call and
+// counts it as an open handle until endSnapshot.
+func (db *DB) beginSnapshot() (*lmdb.Txn, error) {
+	if err := db.acquire(&db.handles); err != nil {
+		return nil, err
+	}
+	txn, err := db.env.BeginTxn(nil, lmdb.Readonly)
+	if err != nil {
+		db.release(&db.handles)
+		return nil, err
+	}
+	return txn, nil
+}
+
+func (db *DB) endSnapshot(txn *lmdb.Txn) {
+	txn.Abort()
+	db.release(&db.handles)
+}
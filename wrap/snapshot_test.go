@@ -0,0 +1,113 @@
+package wrap
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	db := newTestDB(t, "a")
+	mustWrite(t, db, "a", "k1", "k2")
+
+	snap, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Close()
+
+	// changes committed after the snapshot began are not visible to it
+	if err := db.Write("a", []byte("k1"), []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete("a", []byte("k2")); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, db, "a", "k3")
+
+	val, err := snap.Read("a", []byte("k1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(val, []byte("v:k1")) {
+		t.Errorf("value: %q", val)
+	}
+	ok, err := snap.Has("a", []byte("k2"))
+	if err != nil || !ok {
+		t.Errorf("has k2: %v %v", ok, err)
+	}
+	ok, err = snap.Has("a", []byte("k3"))
+	if err != nil || ok {
+		t.Errorf("has k3: %v %v", ok, err)
+	}
+	kvs, err := snap.Scan("a", []byte("k"), ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 2 || string(kvs[1].Key) != "k2" {
+		t.Errorf("scan: %q", kvs)
+	}
+	if _, err := snap.Read("missing", []byte("k1")); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+
+	// the DB itself sees the latest state
+	if val, _ := db.Read("a", []byte("k1")); !bytes.Equal(val, []byte("new")) {
+		t.Errorf("db value: %q", val)
+	}
+}
+
+func TestSnapshot_Close(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// DB.Close refuses while the snapshot is open
+	if err := db.Close(); err != ErrHandlesOpen {
+		t.Fatalf("close with open snapshot: %v", err)
+	}
+	if _, err := db.Snapshot(); err != ErrDBClosed {
+		t.Errorf("snapshot after close: %v", err)
+	}
+	if _, err := snap.Has("a", []byte("k")); err != nil {
+		t.Errorf("outstanding snapshot: %v", err)
+	}
+
+	if err := snap.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.Close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+	if _, err := snap.Read("a", []byte("k")); err != ErrDBClosed {
+		t.Errorf("read after close: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("close: %v", err)
+	}
+}
+
+func TestSnapshot_closeTimeout(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"a"}, WithCloseTimeout(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	it, err := db.NewIterator("a", IterOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(50*time.Millisecond, func() { it.Close() })
+
+	start := time.Now()
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Errorf("close did not wait for the iterator")
+	}
+}
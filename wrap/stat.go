@@ -0,0 +1,300 @@
+package wrap
+
+import (
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// freeDBI is the handle of LMDB's free page database, always open.
+const freeDBI lmdb.DBI = 0
+
+// Count returns the number of entries in the database. Count reads the entry
+// count maintained by LMDB so it takes constant time regardless of size.
+func (db *DB) Count(dbName string) (uint64, error) {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	err = db.View(func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		stat, err := txn.Stat(dbi)
+		if err != nil {
+			return err
+		}
+		n = stat.Entries
+		return nil
+	})
+	return n, err
+}
+
+// Len returns the number of entries in every named database, keyed by name.
+// The counts are read in one transaction so they form a consistent snapshot
+// across databases. Internal databases are not included.
+func (db *DB) Len() (map[string]uint64, error) {
+	counts := make(map[string]uint64)
+	err := db.View(func(txn *lmdb.Txn) error {
+		// looked up in txn, see refreshDBI
+		for name, dbi := range db.GetDBis() {
+			stat, err := txn.Stat(dbi)
+			if err != nil {
+				return err
+			}
+			counts[name] = stat.Entries
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// CountPrefix returns the number of keys starting with prefix. Unlike Count it
+// walks the matching keys so it takes time linear in the number of matches. If
+// limit is greater than zero counting stops after limit keys, a result equal
+// to limit then means "at least limit".
+func (db *DB) CountPrefix(dbName string, prefix []byte, limit int) (uint64, error) {
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	err = db.View(func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		// nothing is retained so there is no need to copy from the map
+		txn.RawRead = true
+		return scan(txn, dbi, prefix, prefixEnd(prefix), ScanOptions{Limit: limit}, func(k, v []byte) error {
+			n++
+			return nil
+		})
+	})
+	return n, err
+}
+
+// QueueDepth returns the number of update operations waiting for the writer
+// goroutines. A depth approaching QueueCapacity means writers are about to
+// block. The value is advisory, it may change before the caller acts on it.
+func (db *DB) QueueDepth() int {
+	n := 0
+	for _, ops := range db.uOps {
+		n += len(ops)
+	}
+	return n
+}
+
+// QueueCapacity returns the total capacity of the update queues, see
+// Options.UpdateBufferSize and Options.NumWriteWorkers. Like QueueDepth the
+// value is advisory.
+func (db *DB) QueueCapacity() int {
+	return len(db.uOps) * db.opts.UpdateBufferSize
+}
+
+// EnvInfo returns information about the environment, see lmdb.Env.Info.
+func (db *DB) EnvInfo() (*lmdb.EnvInfo, error) {
+	if err := db.acquire(&db.active); err != nil {
+		return nil, err
+	}
+	defer db.release(&db.active)
+	return db.env.Info()
+}
+
+// EnvStat returns statistics about the environment, see lmdb.Env.Stat. The
+// entry count includes the records naming each database, internal ones
+// included.
+func (db *DB) EnvStat() (*lmdb.Stat, error) {
+	if err := db.acquire(&db.active); err != nil {
+		return nil, err
+	}
+	defer db.release(&db.active)
+	return db.env.Stat()
+}
+
+// FreeStats describes the pages recorded in LMDB's freelist, the pages
+// released by past write transactions that later writes reuse before growing
+// the file.
+type FreeStats struct {
+	Records  uint64 // freelist records, one per transaction that released pages
+	Pages    uint64 // pages in the freelist
+	PageSize uint
+	Bytes    uint64 // Pages times PageSize
+}
+
+// FreeStats reads the freelist in a read transaction. Pages released by a
+// transaction newer than the oldest open reader cannot be reused until that
+// reader ends, so a freelist growing under a long-lived reader shows as bloat
+// here. Freed pages are never returned to the file system, only Compact
+// shrinks it.
+func (db *DB) FreeStats() (FreeStats, error) {
+	var fs FreeStats
+	err := db.View(func(txn *lmdb.Txn) (err error) {
+		fs, err = freeStats(txn)
+		return err
+	})
+	return fs, err
+}
+
+func freeStats(txn *lmdb.Txn) (FreeStats, error) {
+	var fs FreeStats
+	stat, err := txn.Stat(freeDBI)
+	if err != nil {
+		return fs, err
+	}
+	fs.PageSize = stat.PSize
+	txn.RawRead = true
+	cur, err := txn.OpenCursor(freeDBI)
+	if err != nil {
+		return fs, err
+	}
+	defer cur.Close()
+	_, v, err := cur.Get(nil, nil, lmdb.First)
+	for ; err == nil; _, v, err = cur.Get(nil, nil, lmdb.Next) {
+		fs.Records++
+		fs.Pages += freePageCount(v)
+	}
+	if !lmdb.IsNotFound(err) {
+		return fs, err
+	}
+	fs.Bytes = fs.Pages * uint64(fs.PageSize)
+	return fs, nil
+}
+
+// freePageCount returns the length of a freelist record, an array of page
+// numbers of type size_t preceded by its length.
+func freePageCount(v []byte) uint64 {
+	var n uintptr
+	if len(v) < int(unsafe.Sizeof(n)) {
+		return 0
+	}
+	// copied as the record is not necessarily aligned
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&n)), unsafe.Sizeof(n)), v)
+	return uint64(n)
+}
+
+// SpaceInfo describes the use of the memory map, see DB.SpaceInfo.
+type SpaceInfo struct {
+	MapSize   int64 // size of the memory map in bytes, the limit of the file
+	PageSize  uint
+	MapPages  uint64 // pages fitting in the map
+	PagesUsed uint64 // pages written to the file so far, free ones included
+	PagesFree uint64 // pages of PagesUsed in the freelist, see FreeStats
+
+	// DBs maps every named database to its use of the file. Internal
+	// databases are not included.
+	DBs map[string]DBSpace
+}
+
+// DBSpace is the share of the file used by one database.
+type DBSpace struct {
+	Entries uint64
+	Pages   uint64 // branch, leaf, and overflow pages
+	Bytes   uint64 // Pages times the page size, an estimate of the live data
+}
+
+// Available returns an estimate of the bytes that can still be written before
+// writes fail with MapFull: the pages of the map not used yet and the free
+// ones. Free pages held by open readers are counted although they cannot be
+// reused until the readers end.
+func (s SpaceInfo) Available() int64 {
+	return int64(s.MapPages-s.PagesUsed+s.PagesFree) * int64(s.PageSize)
+}
+
+// SpaceInfo combines the environment information, the freelist, and the
+// statistics of every named database into one report, for example to alert
+// well before writes fail with MapFull. The databases and the freelist are
+// read in one transaction; the map size and the used pages come from the
+// environment and may include a write committed since, so the figures are
+// estimates on a busy DB.
+func (db *DB) SpaceInfo() (SpaceInfo, error) {
+	var si SpaceInfo
+	err := db.View(func(txn *lmdb.Txn) error {
+		dbis := db.GetDBis()
+		fs, err := freeStats(txn)
+		if err != nil {
+			return err
+		}
+		info, err := db.env.Info()
+		if err != nil {
+			return err
+		}
+		si.MapSize, si.PageSize, si.PagesFree = info.MapSize, fs.PageSize, fs.Pages
+		si.MapPages = uint64(info.MapSize) / uint64(fs.PageSize)
+		si.PagesUsed = uint64(info.LastPNO) + 1
+		si.DBs = make(map[string]DBSpace, len(dbis))
+		for name, dbi := range dbis {
+			stat, err := txn.Stat(dbi)
+			if err != nil {
+				return err
+			}
+			pages := stat.BranchPages + stat.LeafPages + stat.OverflowPages
+			si.DBs[name] = DBSpace{Entries: stat.Entries, Pages: pages, Bytes: pages * uint64(stat.PSize)}
+		}
+		return nil
+	})
+	if err != nil {
+		return SpaceInfo{}, err
+	}
+	return si, nil
+}
+
+// Usage describes how close the environment is to filling its memory map or
+// its volume, see DB.Usage.
+type Usage struct {
+	FileSize int64 // length of data.mdb
+	MapSize  int64 // size of the memory map, the limit of FileSize
+	PageSize uint
+	LastPage int64 // high-water page number, see lmdb.EnvInfo.LastPNO
+
+	// MapUsed is the percentage of the map below the high-water page. Free
+	// pages below it are reused before the file grows, see SpaceInfo.
+	MapUsed float64
+
+	// DiskFree is the number of bytes available to the process on the
+	// volume holding the environment, or -1 if the platform offers no way
+	// to know.
+	DiskFree int64
+}
+
+// Usage reports the size of the environment relative to its memory map and
+// the free space of its volume in one call cheap enough to poll, for example
+// from a health endpoint watching for MapFull or ENOSPC before writes fail.
+func (db *DB) Usage() (Usage, error) {
+	if err := db.acquire(&db.active); err != nil {
+		return Usage{}, err
+	}
+	defer db.release(&db.active)
+	info, err := db.env.Info()
+	if err != nil {
+		return Usage{}, err
+	}
+	stat, err := db.env.Stat()
+	if err != nil {
+		return Usage{}, err
+	}
+	fi, err := os.Stat(filepath.Join(db.path, "data.mdb"))
+	if err != nil {
+		return Usage{}, err
+	}
+	free, err := diskFree(db.path)
+	if err != nil {
+		return Usage{}, err
+	}
+	u := Usage{
+		FileSize: fi.Size(),
+		MapSize:  info.MapSize,
+		PageSize: stat.PSize,
+		LastPage: info.LastPNO,
+		DiskFree: free,
+	}
+	if info.MapSize > 0 {
+		u.MapUsed = float64(info.LastPNO+1) * float64(stat.PSize) / float64(info.MapSize) * 100
+	}
+	return u, nil
+}
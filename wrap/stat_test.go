@@ -0,0 +1,261 @@
+package wrap
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_Count(t *testing.T) {
+	db := newTestDB(t, "a", "b")
+	for i := 0; i < 10; i++ {
+		mustWrite(t, db, "a", fmt.Sprintf("p%d", i))
+	}
+	mustWrite(t, db, "a", "q0", "q1")
+
+	n, err := db.Count("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 12 {
+		t.Errorf("count: %d (!= 12)", n)
+	}
+	n, err = db.Count("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("count empty: %d (!= 0)", n)
+	}
+	if _, err := db.Count("c"); err != ErrDbNameNotFound {
+		t.Errorf("unknown name: %v", err)
+	}
+}
+
+func TestDB_Len(t *testing.T) {
+	db := newTestDB(t, "a", "b", "c")
+	for i := 0; i < 5; i++ {
+		mustWrite(t, db, "a", fmt.Sprintf("k%d", i))
+	}
+	mustWrite(t, db, "b", "k0", "k1")
+
+	counts, err := db.Len()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]uint64{"a": 5, "b": 2, "c": 0}
+	if len(counts) != len(want) {
+		t.Errorf("counts: %v (!= %v)", counts, want)
+	}
+	for name, n := range want {
+		if got, ok := counts[name]; !ok || got != n {
+			t.Errorf("count %q: %d %v (!= %d)", name, got, ok, n)
+		}
+	}
+
+	db.Close()
+	if _, err := db.Len(); err != ErrDBClosed {
+		t.Errorf("closed: %v", err)
+	}
+}
+
+func TestDB_CountPrefix(t *testing.T) {
+	db := newTestDB(t, "a")
+	for i := 0; i < 10; i++ {
+		mustWrite(t, db, "a", fmt.Sprintf("p%d", i))
+	}
+	mustWrite(t, db, "a", "q0", "q1")
+
+	for _, test := range []struct {
+		prefix string
+		limit  int
+		n      uint64
+	}{
+		{"p", 0, 10},
+		{"q", 0, 2},
+		{"r", 0, 0},
+		{"", 0, 12},
+		{"p", 4, 4},
+		{"q", 4, 2},
+	} {
+		n, err := db.CountPrefix("a", []byte(test.prefix), test.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != test.n {
+			t.Errorf("count %q limit %d: %d (!= %d)", test.prefix, test.limit, n, test.n)
+		}
+	}
+}
+
+func TestDB_QueueDepth(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"a"}, WithUpdateBufferSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if c := db.QueueCapacity(); c != 4 {
+		t.Errorf("capacity: %d (!= 4)", c)
+	}
+	if d := db.QueueDepth(); d != 0 {
+		t.Errorf("idle depth: %d (!= 0)", d)
+	}
+
+	// hold the writer so further operations queue up behind it
+	release := make(chan struct{})
+	running := make(chan struct{})
+	done := make(chan error, 3)
+	go func() {
+		done <- db.Update(func(txn *lmdb.Txn) error {
+			close(running)
+			<-release
+			return nil
+		})
+	}()
+	<-running
+	for i := 0; i < 2; i++ {
+		go func(i int) { done <- db.Write("a", []byte(fmt.Sprint(i)), nil) }(i)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for db.QueueDepth() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if d := db.QueueDepth(); d != 2 {
+		t.Errorf("depth: %d (!= 2)", d)
+	}
+	close(release)
+	for i := 0; i < 3; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func BenchmarkDB_QueueDepth(b *testing.B) {
+	db := newTestDB(b, "a")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = db.QueueDepth()
+	}
+}
+
+func TestDB_EnvInfo(t *testing.T) {
+	db := newTestDB(t, "a")
+	before, err := db.EnvInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, db, "a", "k")
+	after, err := db.EnvInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.LastTxnID != before.LastTxnID+1 {
+		t.Errorf("last txn id: %d (!= %d)", after.LastTxnID, before.LastTxnID+1)
+	}
+}
+
+func TestDB_EnvStat(t *testing.T) {
+	db := newTestDB(t, "a", "b")
+	stat, err := db.EnvStat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.PSize == 0 {
+		t.Errorf("page size: %d", stat.PSize)
+	}
+	// the root database holds one record per named database
+	if stat.Entries < 2 {
+		t.Errorf("entries: %d", stat.Entries)
+	}
+}
+
+func TestDB_FreeStats(t *testing.T) {
+	db := newTestDB(t, "a", "b")
+	val := make([]byte, 100)
+	for i := 0; i < 500; i++ {
+		if err := db.Write("a", []byte(fmt.Sprintf("k%03d", i)), val); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Truncate("a"); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, db, "b", "k0", "k1")
+
+	fs, err := db.FreeStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the truncated pages alone exceed ten
+	if fs.Records == 0 || fs.Pages < 10 || fs.PageSize == 0 || fs.Bytes != fs.Pages*uint64(fs.PageSize) {
+		t.Errorf("free stats: %+v", fs)
+	}
+
+	si, err := db.SpaceInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := db.EnvInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if si.MapSize != info.MapSize || si.PageSize != fs.PageSize || si.PagesFree != fs.Pages {
+		t.Errorf("space info: %+v, free stats %+v", si, fs)
+	}
+	if si.PagesFree > si.PagesUsed || si.PagesUsed > si.MapPages {
+		t.Errorf("pages: %+v", si)
+	}
+	if a := si.Available(); a <= 0 || a > si.MapSize {
+		t.Errorf("available: %d", a)
+	}
+	if len(si.DBs) != 2 || si.DBs["a"].Entries != 0 || si.DBs["a"].Pages != 0 {
+		t.Errorf("a: %+v", si.DBs)
+	}
+	if b := si.DBs["b"]; b.Entries != 2 || b.Pages != 1 || b.Bytes != uint64(si.PageSize) {
+		t.Errorf("b: %+v", b)
+	}
+
+	db.Close()
+	if _, err := db.SpaceInfo(); err != ErrDBClosed {
+		t.Errorf("closed: %v", err)
+	}
+}
+
+func TestDB_Usage(t *testing.T) {
+	db := newTestDB(t, "a")
+	before, err := db.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	val := make([]byte, 1000)
+	for i := 0; i < 200; i++ {
+		if err := db.Write("a", []byte(fmt.Sprintf("k%03d", i)), val); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u, err := db.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.MapSize != MapSize || u.PageSize == 0 || u.LastPage <= before.LastPage {
+		t.Errorf("usage: %+v, before %+v", u, before)
+	}
+	if u.FileSize < (u.LastPage+1)*int64(u.PageSize) {
+		t.Errorf("file size %d below the high-water page %d", u.FileSize, u.LastPage)
+	}
+	want := float64(u.LastPage+1) * float64(u.PageSize) / float64(u.MapSize) * 100
+	if u.MapUsed != want || u.MapUsed <= before.MapUsed || u.MapUsed >= 100 {
+		t.Errorf("map used: %v%% (!= %v%%)", u.MapUsed, want)
+	}
+	if u.DiskFree == 0 {
+		t.Errorf("disk free: %d", u.DiskFree)
+	}
+
+	db.Close()
+	if _, err := db.Usage(); err != ErrDBClosed {
+		t.Errorf("closed: %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// KeyCodec converts keys of type K to and from their stored form. Encodings
+// used for keys should preserve order if the store is scanned, see the keys
+// package.
+type KeyCodec[K any] interface {
+	Encode(K) ([]byte, error)
+	Decode([]byte) (K, error)
+}
+
+// ValueCodec converts values of type V to and from their stored form.
+type ValueCodec[V any] interface {
+	Encode(V) ([]byte, error)
+	Decode([]byte) (V, error)
+}
+
+// DecodeError is returned by Store methods when a stored key or value cannot
+// be decoded. Len is the length of the raw data, which helps tell truncated or
+// corrupted records apart from records written with a different schema.
+type DecodeError struct {
+	DBName string
+	Key    bool // the key failed to decode, rather than the value
+	Len    int
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	what := "value"
+	if e.Key {
+		what = "key"
+	}
+	return fmt.Sprintf("%s: decoding %d byte %s: %v", e.DBName, e.Len, what, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Pair is a decoded key/value pair returned by Store.Scan.
+type Pair[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// Store is a typed view of a named database that encodes keys and values with
+// the codecs it was created with. A Store is safe for concurrent use.
+type Store[K, V any] struct {
+	b  *Bucket
+	kc KeyCodec[K]
+	vc ValueCodec[V]
+}
+
+// NewStore returns a Store over the named database.
+func NewStore[K, V any](db *DB, dbName string, kc KeyCodec[K], vc ValueCodec[V]) (*Store[K, V], error) {
+	if kc == nil || vc == nil {
+		return nil, errors.New("wrap: nil codec")
+	}
+	b, err := db.Bucket(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return &Store[K, V]{b: b, kc: kc, vc: vc}, nil
+}
+
+// Get retrieves the value stored under key.
+func (s *Store[K, V]) Get(key K) (V, error) {
+	var zero V
+	k, err := s.kc.Encode(key)
+	if err != nil {
+		return zero, err
+	}
+	raw, err := s.b.Read(k)
+	if err != nil {
+		return zero, err
+	}
+	return s.decodeValue(raw)
+}
+
+// Put stores val under key.
+func (s *Store[K, V]) Put(key K, val V) error {
+	k, err := s.kc.Encode(key)
+	if err != nil {
+		return err
+	}
+	v, err := s.vc.Encode(val)
+	if err != nil {
+		return err
+	}
+	return s.b.Write(k, v)
+}
+
+// Delete removes key from the store.
+func (s *Store[K, V]) Delete(key K) error {
+	k, err := s.kc.Encode(key)
+	if err != nil {
+		return err
+	}
+	return s.b.Delete(k)
+}
+
+// Scan returns up to limit pairs whose encoded key starts with the encoding of
+// prefix, in key order. A limit of zero or less returns every match.
+func (s *Store[K, V]) Scan(prefix K, limit int) ([]Pair[K, V], error) {
+	p, err := s.kc.Encode(prefix)
+	if err != nil {
+		return nil, err
+	}
+	kvs, err := s.b.Scan(p, ScanOptions{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]Pair[K, V], len(kvs))
+	for i, kv := range kvs {
+		if pairs[i].Key, err = s.kc.Decode(kv.Key); err != nil {
+			return nil, &DecodeError{DBName: s.b.name, Key: true, Len: len(kv.Key), Err: err}
+		}
+		if pairs[i].Value, err = s.decodeValue(kv.Value); err != nil {
+			return nil, err
+		}
+	}
+	return pairs, nil
+}
+
+func (s *Store[K, V]) decodeValue(raw []byte) (V, error) {
+	v, err := s.vc.Decode(raw)
+	if err != nil {
+		var zero V
+		return zero, &DecodeError{DBName: s.b.name, Len: len(raw), Err: err}
+	}
+	return v, nil
+}
+
+// StringCodec stores strings as their raw bytes.
+type StringCodec struct{}
+
+func (StringCodec) Encode(s string) ([]byte, error) { return []byte(s), nil }
+func (StringCodec) Decode(b []byte) (string, error) { return string(b), nil }
+
+// Uint64Codec stores integers as 8 big-endian bytes so they sort numerically.
+type Uint64Codec struct{}
+
+func (Uint64Codec) Encode(v uint64) ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b, nil
+}
+
+func (Uint64Codec) Decode(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("uint64 requires 8 bytes, got %d", len(b))
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// JSONCodec stores values as JSON using encoding/json.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(v T) ([]byte, error) { return json.Marshal(v) }
+
+func (JSONCodec[T]) Decode(b []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
@@ -0,0 +1,94 @@
+package wrap
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+type testUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestStore(t *testing.T) {
+	db := newTestDB(t, "users")
+	users, err := NewStore[string, testUser](db, "users", StringCodec{}, JSONCodec[testUser]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []testUser{{"ann", 30}, {"bob", 40}, {"cat", 50}} {
+		if err := users.Put("user:"+u.Name, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u, err := users.Get("user:bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != (testUser{"bob", 40}) {
+		t.Errorf("get: %+v", u)
+	}
+	if err := users.Delete("user:bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Get("user:bob"); !lmdb.IsNotFound(err) {
+		t.Errorf("get deleted: %v", err)
+	}
+	pairs, err := users.Scan("user:", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 || pairs[0].Key != "user:ann" || pairs[1].Value.Age != 50 {
+		t.Errorf("scan: %+v", pairs)
+	}
+	if pairs, _ := users.Scan("user:", 1); len(pairs) != 1 {
+		t.Errorf("scan limit: %+v", pairs)
+	}
+
+	if _, err := NewStore[string, testUser](db, "nope", StringCodec{}, JSONCodec[testUser]{}); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+}
+
+func TestStore_decodeError(t *testing.T) {
+	db := newTestDB(t, "a")
+	s, err := NewStore[uint64, uint64](db, "a", Uint64Codec{}, Uint64Codec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(1, 100); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get(1); err != nil || v != 100 {
+		t.Errorf("get: %d %v", v, err)
+	}
+
+	// a truncated value written around the store
+	k, _ := Uint64Codec{}.Encode(2)
+	if err := db.Write("a", k, []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Get(2)
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		t.Fatalf("error: %v", err)
+	}
+	if derr.Len != 3 || derr.Key || derr.DBName != "a" {
+		t.Errorf("decode error: %+v", derr)
+	}
+	if v != 0 {
+		t.Errorf("value on error: %d", v)
+	}
+
+	// a key of the wrong length matching the encoded prefix
+	bad := append(make([]byte, 8), 'x')
+	if err := db.Write("a", bad, k); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Scan(0, 0)
+	if !errors.As(err, &derr) || !derr.Key || derr.Len != 9 {
+		t.Errorf("scan key error: %v", err)
+	}
+}
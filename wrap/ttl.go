@@ -0,0 +1,205 @@
+package wrap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+	"github.com/Data-Corruption/lmdb-go/wrap/keys"
+)
+
+// ErrInvalidTTL is returned by PutWithTTL for a ttl that is not positive.
+var ErrInvalidTTL = errors.New("ttl must be positive")
+
+// The expiry database holds two kinds of records. Index records sort by expiry
+// time so the expirer finds due keys with a single cursor walk, and deadline
+// records map a key back to its current expiry so that rewriting a key with a
+// new TTL replaces its index record.
+//
+//	'x' expiry dbName key -> nil
+//	'k' dbName key        -> expiry
+//
+// expiry is the nanosecond Unix time encoded with keys.AppendUint64 and dbName
+// is encoded with keys.AppendString.
+const (
+	expiryIndexTag    = 'x'
+	expiryDeadlineTag = 'k'
+)
+
+// expireBatchSize bounds the number of keys removed in one write transaction
+// so the expirer never holds the writer for long.
+const expireBatchSize = 1000
+
+// PutWithTTL writes a key/value pair that is deleted by the expirer once ttl
+// has elapsed. The value and its expiry are written in one transaction.
+// Writing the key again with PutWithTTL replaces its expiry.
+//
+// Expired keys remain readable until the expirer started by StartExpirer
+// removes them. A plain Write or Delete of the key does not cancel its TTL.
+func (db *DB) PutWithTTL(dbName string, key, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+	return db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		at := uint64(time.Now().Add(ttl).UnixNano())
+		return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+			if err := db.refreshDBI(dbName, &dbi); err != nil {
+				return err
+			}
+			if err := db.clearExpiry(txn, dbName, key); err != nil {
+				return err
+			}
+			deadline := expiryDeadlineKey(dbName, key)
+			idx := expiryIndexKey(at, deadline[1:])
+			if err := txn.Put(db.expiry, idx, nil, 0); err != nil {
+				return err
+			}
+			if err := txn.Put(db.expiry, deadline, keys.AppendUint64(nil, at), 0); err != nil {
+				return err
+			}
+			stored, err := db.encodeValue(txn, dbi, dbName, key, value)
+			if err != nil {
+				return err
+			}
+			if err := txn.Put(dbi, key, stored, 0); err != nil {
+				return err
+			}
+			return db.recordWrite(txn, OpPut, dbName, key, stored)
+		}, db.event(OpPut, dbName, key, value))
+	})
+}
+
+// StartExpirer starts a goroutine that deletes expired keys every interval
+// and returns a function that stops it. The stop function waits for the
+// goroutine to exit and may be called more than once. The expirer also stops
+// on its own once the DB is closed.
+func (db *DB) StartExpirer(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if _, err := db.expire(time.Now()); err == ErrDBClosed {
+				return
+			} else if err != nil && err != ErrCompacting {
+				log.Printf("wrap: expirer: %v", err)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		wg.Wait()
+	}
+}
+
+// expire deletes every key that expired at or before now and returns how many
+// were deleted.
+func (db *DB) expire(now time.Time) (int, error) {
+	end := expiryIndexKey(uint64(now.UnixNano())+1, nil)
+	total := 0
+	for {
+		n := 0
+		events := &[]KeyEvent{}
+		err := db.updateEvents("", func(txn *lmdb.Txn) error {
+			cur, err := txn.OpenCursor(db.expiry)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+			k, _, err := cur.Get([]byte{expiryIndexTag}, nil, lmdb.SetRange)
+			for ; err == nil && n < expireBatchSize && bytes.Compare(k, end) < 0; n++ {
+				if err = db.expireKey(txn, k[9:], events); err != nil {
+					return err
+				}
+				if err = cur.Del(0); err != nil {
+					return err
+				}
+				k, _, err = cur.Get(nil, nil, lmdb.Next)
+			}
+			if lmdb.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}, events)
+		total += n
+		if err != nil || n < expireBatchSize {
+			return total, err
+		}
+	}
+}
+
+// expireKey deletes the key identified by name, the dbName and key portion of
+// an index record, along with its deadline record.
+func (db *DB) expireKey(txn *lmdb.Txn, name []byte, events *[]KeyEvent) error {
+	deadline := append([]byte{expiryDeadlineTag}, name...)
+	if err := del(txn, db.expiry, deadline); err != nil {
+		return err
+	}
+	dbName, key, err := keys.String(name)
+	if err != nil {
+		return err
+	}
+	dbi, err := db.getDBI(dbName)
+	if err != nil {
+		// the database is no longer registered, nothing to delete
+		return nil
+	}
+	db.addEvent(events, OpDelete, dbName, key, nil)
+	if err := del(txn, dbi, key); err != nil {
+		return err
+	}
+	return db.recordWrite(txn, OpDelete, dbName, key, nil)
+}
+
+// clearExpiry removes the expiry records of a key, if it has any.
+func (db *DB) clearExpiry(txn *lmdb.Txn, dbName string, key []byte) error {
+	deadline := expiryDeadlineKey(dbName, key)
+	at, err := txn.Get(db.expiry, deadline)
+	if lmdb.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(at) != 8 {
+		return errors.New("malformed expiry record")
+	}
+	if err := del(txn, db.expiry, expiryIndexKey(binary.BigEndian.Uint64(at), deadline[1:])); err != nil {
+		return err
+	}
+	return txn.Del(db.expiry, deadline, nil)
+}
+
+func expiryDeadlineKey(dbName string, key []byte) []byte {
+	return append(keys.AppendString([]byte{expiryDeadlineTag}, dbName), key...)
+}
+
+func expiryIndexKey(at uint64, name []byte) []byte {
+	return append(keys.AppendUint64([]byte{expiryIndexTag}, at), name...)
+}
+
+// del deletes key from dbi, a missing key is not an error.
+func del(txn *lmdb.Txn, dbi lmdb.DBI, key []byte) error {
+	err := txn.Del(dbi, key, nil)
+	if lmdb.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package wrap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_PutWithTTL(t *testing.T) {
+	db := newTestDB(t, "a", "b")
+	if err := db.PutWithTTL("a", []byte("k1"), []byte("v"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutWithTTL("b", []byte("k1"), []byte("v"), 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, db, "a", "forever")
+	if err := db.PutWithTTL("a", []byte("k"), nil, 0); err != ErrInvalidTTL {
+		t.Errorf("zero ttl: %v", err)
+	}
+
+	if n, err := db.expire(time.Now()); err != nil || n != 0 {
+		t.Errorf("nothing due: %d %v", n, err)
+	}
+	n, err := db.expire(time.Now().Add(90 * time.Minute))
+	if err != nil || n != 1 {
+		t.Errorf("expire: %d %v", n, err)
+	}
+	if _, err := db.Read("a", []byte("k1")); !lmdb.IsNotFound(err) {
+		t.Errorf("expired key: %v", err)
+	}
+	if _, err := db.Read("b", []byte("k1")); err != nil {
+		t.Errorf("key due later: %v", err)
+	}
+	if _, err := db.Read("a", []byte("forever")); err != nil {
+		t.Errorf("key without ttl: %v", err)
+	}
+	if n, _ := db.expire(time.Now().Add(3 * time.Hour)); n != 1 {
+		t.Errorf("expire rest: %d", n)
+	}
+}
+
+func TestDB_PutWithTTL_extend(t *testing.T) {
+	db := newTestDB(t, "a")
+	if err := db.PutWithTTL("a", []byte("k"), []byte("v1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutWithTTL("a", []byte("k"), []byte("v2"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := db.expire(time.Now().Add(2 * time.Minute)); n != 0 {
+		t.Errorf("replaced expiry still indexed: %d", n)
+	}
+	if n, _ := db.expire(time.Now().Add(2 * time.Hour)); n != 1 {
+		t.Errorf("new expiry: %d", n)
+	}
+	var entries uint64
+	db.View(func(txn *lmdb.Txn) error {
+		stat, err := txn.Stat(db.expiry)
+		if err == nil {
+			entries = stat.Entries
+		}
+		return err
+	})
+	if entries != 0 {
+		t.Errorf("expiry records left: %d", entries)
+	}
+}
+
+func TestDB_StartExpirer(t *testing.T) {
+	db := newTestDB(t, "a")
+	if err := db.PutWithTTL("a", []byte("k"), []byte("v"), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	stop := db.StartExpirer(5 * time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := db.Read("a", []byte("k")); lmdb.IsNotFound(err) {
+			stop()
+			stop()
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("key was not expired")
+}
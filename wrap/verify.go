@@ -0,0 +1,192 @@
+package wrap
+
+import (
+	"hash/crc32"
+	"path/filepath"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// VerifyOpts controls Verify.
+type VerifyOpts struct {
+	// DBNames lists the databases to verify. Empty means every registered
+	// database in name order.
+	DBNames []string
+
+	// ChunkSize is the number of entries read per read transaction. Zero
+	// means 10,000.
+	ChunkSize int
+
+	// MaxIssues is the maximum number of issues recorded per database. Zero
+	// means 10, a negative value records none. The counts of a DBVerify are
+	// exact regardless.
+	MaxIssues int
+}
+
+// VerifyReport is the result of Verify.
+type VerifyReport struct {
+	DBs []DBVerify // one per database verified, in the order verified
+}
+
+// OK reports whether no issues were found.
+func (r VerifyReport) OK() bool {
+	for _, d := range r.DBs {
+		if d.Failed != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// DBVerify describes one database checked by Verify.
+type DBVerify struct {
+	DB      string
+	Entries uint64 // entries read
+	Bytes   uint64 // total size of their keys and values
+	Failed  uint64 // entries with an issue
+
+	// Issues holds the first issues found, in key order, up to
+	// VerifyOpts.MaxIssues.
+	Issues []VerifyIssue
+}
+
+// VerifyIssue is an entry found faulty by Verify.
+type VerifyIssue struct {
+	Key     []byte
+	Problem string
+}
+
+// Verify reads every key and value of the databases selected by opts, so that
+// damage to the pages holding them surfaces, and checks that the keys, and the
+// values of DupSort databases, are in strictly ascending order according to
+// the database's comparison. In databases opened with DBOptions.Checksum it
+// also checks every value against its checksum. Damage found by LMDB stops
+// the walk and is returned as an error satisfying lmdb.IsCorrupted or
+// lmdb.IsErrno(err, lmdb.PageNotFound), along with the report of what was
+// read before it. A data file shorter than its meta page says, which LMDB
+// would crash on, is reported with an error wrapping ErrTruncated before the
+// walk. Entries out of order or failing their checksum are recorded in the
+// report and the walk goes on.
+//
+// The walk reads opts.ChunkSize entries per read transaction, so it does not
+// hold a reader slot, and old pages, for the whole walk of a large database.
+// Writes committed between chunks are seen by the later chunks, so the
+// counts describe no single snapshot unless the DB is idle.
+func (db *DB) Verify(opts VerifyOpts) (VerifyReport, error) {
+	var report VerifyReport
+	names, dbis, err := db.resolveDBs(opts.DBNames)
+	if err != nil {
+		return report, err
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = importChunkSize
+	}
+	maxIssues := opts.MaxIssues
+	if maxIssues == 0 {
+		maxIssues = 10
+	}
+	// in a transaction, so that Compact does not swap the env meanwhile
+	err = db.View(func(*lmdb.Txn) error {
+		return checkDataSize(db.env, filepath.Join(db.path, "data.mdb"))
+	})
+	if err != nil {
+		return report, err
+	}
+	for i, name := range names {
+		report.DBs = append(report.DBs, DBVerify{DB: name})
+		v := &dbVerifier{dbi: dbis[i], report: &report.DBs[i], max: maxIssues, checksum: db.checksummed(name)}
+		for done := false; !done; {
+			err := db.View(func(txn *lmdb.Txn) (err error) {
+				if err := db.refreshDBI(name, &dbis[i]); err != nil {
+					return err
+				}
+				done, err = v.chunk(txn, chunkSize)
+				return err
+			})
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// dbVerifier walks one database for Verify, chunk by chunk.
+type dbVerifier struct {
+	dbi      lmdb.DBI
+	report   *DBVerify
+	max      int
+	checksum bool // check the checksums of DBOptions.Checksum
+
+	started      bool
+	prevK, prevV []byte // copies of the last entry read, nil before the first
+}
+
+// chunk reads up to n entries following the last one read, it reports
+// whether the end of the database was reached.
+func (v *dbVerifier) chunk(txn *lmdb.Txn, n int) (done bool, err error) {
+	txn.RawRead = true
+	flags, err := txn.Flags(v.dbi)
+	if err != nil {
+		return false, err
+	}
+	dupSort := flags&lmdb.DupSort != 0
+	err = readMap(func() error {
+		cur, err := txn.OpenCursor(v.dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		k, val, err := seekAfter(txn, cur, v.dbi, v.prevK, v.prevV, dupSort)
+		// hashing reads every byte of every value
+		h := crc32.NewIEEE()
+		for i := 0; ; i++ {
+			if lmdb.IsNotFound(err) {
+				done = true
+				return nil
+			}
+			if err != nil || i == n {
+				return err
+			}
+			switch {
+			case v.started && v.checkOrder(txn, k, val, dupSort):
+			case v.checksum && !validChecksum(k, val):
+				v.fail(k, "checksum mismatch")
+			}
+			h.Write(val)
+			v.report.Entries++
+			v.report.Bytes += uint64(len(k) + len(val))
+			v.prevK, v.prevV = append(v.prevK[:0], k...), append(v.prevV[:0], val...)
+			v.started = true
+			k, val, err = cur.Get(nil, nil, lmdb.Next)
+		}
+	})
+	return done, err
+}
+
+// checkOrder records an issue if k and val do not sort after the previous
+// entry and reports whether it did.
+func (v *dbVerifier) checkOrder(txn *lmdb.Txn, k, val []byte, dupSort bool) bool {
+	var problem string
+	switch c := txn.Cmp(v.dbi, v.prevK, k); {
+	case c > 0:
+		problem = "key out of order"
+	case c == 0 && !dupSort:
+		problem = "duplicate key"
+	case c == 0 && txn.DCmp(v.dbi, v.prevV, val) >= 0:
+		problem = "value out of order"
+	default:
+		return false
+	}
+	v.fail(k, problem)
+	return true
+}
+
+// fail records an issue of the entry at k.
+func (v *dbVerifier) fail(k []byte, problem string) {
+	v.report.Failed++
+	if len(v.report.Issues) < v.max {
+		v.report.Issues = append(v.report.Issues, VerifyIssue{Key: append([]byte{}, k...), Problem: problem})
+	}
+}
@@ -0,0 +1,115 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDB_Verify(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"users", "tags"}, WithDBOptions("tags", DBOptions{DupSort: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for i := 0; i < 25; i++ {
+		mustWrite(t, db, "users", fmt.Sprintf("k%02d", i))
+	}
+	for i := 0; i < 4; i++ {
+		for _, m := range []string{"a", "b", "c"} {
+			if err := db.AddValue("tags", []byte(fmt.Sprintf("t%d", i)), []byte(m)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// chunk boundaries fall inside the duplicates of a key
+	for _, chunk := range []int{0, 1, 2, 5} {
+		r, err := db.Verify(VerifyOpts{ChunkSize: chunk})
+		if err != nil {
+			t.Fatalf("chunk %d: %v", chunk, err)
+		}
+		if !r.OK() || len(r.DBs) != 2 {
+			t.Errorf("chunk %d: %+v", chunk, r)
+			continue
+		}
+		if d := r.DBs[0]; d.DB != "tags" || d.Entries != 12 || d.Bytes != 12*3 {
+			t.Errorf("chunk %d: tags: %+v", chunk, d)
+		}
+		if d := r.DBs[1]; d.DB != "users" || d.Entries != 25 || d.Bytes != 25*8 {
+			t.Errorf("chunk %d: users: %+v", chunk, d)
+		}
+	}
+	if _, err := db.Verify(VerifyOpts{DBNames: []string{"nope"}}); err == nil {
+		t.Errorf("unknown db verified")
+	}
+}
+
+func TestDB_Verify_outOfOrder(t *testing.T) {
+	dir := t.TempDir()
+	db, _, err := New(dir, []string{"users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		mustWrite(t, db, "users", fmt.Sprintf("key-%d", 1000+i))
+	}
+	db.Close()
+
+	// swap two keys in place, LMDB has no page checksums to notice
+	path := filepath.Join(dir, "data.mdb")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.ReplaceAll(data, []byte("key-1002"), []byte("key-tmp!"))
+	data = bytes.ReplaceAll(data, []byte("key-1007"), []byte("key-1002"))
+	data = bytes.ReplaceAll(data, []byte("key-tmp!"), []byte("key-1007"))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	db, _, err = New(dir, []string{"users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	r, err := db.Verify(VerifyOpts{MaxIssues: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := r.DBs[0]
+	if r.OK() || d.Entries != 10 || d.Failed != 2 || len(d.Issues) != 1 {
+		t.Fatalf("report: %+v", d)
+	}
+	if is := d.Issues[0]; string(is.Key) != "key-1003" || is.Problem != "key out of order" {
+		t.Errorf("issue: %q %s", is.Key, is.Problem)
+	}
+}
+
+func TestDB_Verify_truncated(t *testing.T) {
+	dir := t.TempDir()
+	db, _, err := New(dir, []string{"users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Write("users", []byte("big"), bytes.Repeat([]byte("x"), 100000)); err != nil {
+		t.Fatal(err)
+	}
+	// one page short: walking it would crash inside LMDB
+	data := filepath.Join(dir, "data.mdb")
+	fi, err := os.Stat(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(data, fi.Size()-int64(os.Getpagesize())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Verify(VerifyOpts{}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("truncated data file: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package wrap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+var (
+	// ErrVersionMismatch is matched by the VersionMismatchError returned when
+	// the expected version passed to WriteVersioned is not current.
+	ErrVersionMismatch = errors.New("version mismatch")
+
+	// ErrNotVersioned is returned by WriteVersioned and ReadVersioned when the
+	// database was opened without DBOptions.Versioned.
+	ErrNotVersioned = errors.New("database is not versioned")
+)
+
+// versionLen is the size of the header in front of versioned values.
+const versionLen = 8
+
+// VersionMismatchError reports the version a WriteVersioned call expected and
+// the version that was current, which is zero for a missing key.
+type VersionMismatchError struct {
+	DBName   string
+	Key      []byte
+	Expected uint64
+	Actual   uint64
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("%s: key %q: expected version %d, current version %d", e.DBName, e.Key, e.Expected, e.Actual)
+}
+
+// Unwrap returns ErrVersionMismatch.
+func (e *VersionMismatchError) Unwrap() error {
+	return ErrVersionMismatch
+}
+
+// WriteVersioned writes value under key only if the current version of key is
+// expectedVersion and returns the new version. A missing key has version zero
+// and its first write gets version 1. If the version does not match nothing
+// is written and the returned *VersionMismatchError holds the current version.
+func (db *DB) WriteVersioned(dbName string, key, value []byte, expectedVersion uint64) (newVersion uint64, err error) {
+	err = db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		if !db.versioned(dbName) {
+			return fmt.Errorf("%w: %q", ErrNotVersioned, dbName)
+		}
+		newVersion, err = db.writeVersioned(dbName, dbi, key, value, expectedVersion)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newVersion, nil
+}
+
+// ReadVersioned returns the value of key without its header and its version.
+func (db *DB) ReadVersioned(dbName string, key []byte) (value []byte, version uint64, err error) {
+	err = db.observeRead(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		if !db.versioned(dbName) {
+			return fmt.Errorf("%w: %q", ErrNotVersioned, dbName)
+		}
+		stored, err := db.get(dbName, dbi, key)
+		if err != nil {
+			return err
+		}
+		value, version, err = db.decodeVersioned(dbName, key, stored)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, version, nil
+}
+
+// writeVersioned writes value with the next version of key if the current
+// version is expected.
+func (db *DB) writeVersioned(dbName string, dbi lmdb.DBI, key, value []byte, expected uint64) (next uint64, err error) {
+	err = db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		txn.RawRead = true
+		cur, err := db.currentVersion(txn, dbi, dbName, key)
+		if err != nil {
+			return err
+		}
+		if cur != expected {
+			return &VersionMismatchError{DBName: dbName, Key: copyBytes(key), Expected: expected, Actual: cur}
+		}
+		next = cur + 1
+		stored := db.frame(dbName, key, value, next)
+		if err := txn.Put(dbi, key, stored, 0); err != nil {
+			return err
+		}
+		return db.recordWrite(txn, OpPut, dbName, key, stored)
+	}, db.event(OpPut, dbName, key, value))
+	return next, err
+}
+
+// splitVersion separates a stored versioned value into its value and version.
+func splitVersion(key, stored []byte) (value []byte, version uint64, err error) {
+	if len(stored) < versionLen {
+		return nil, 0, fmt.Errorf("wrap: key %q: versioned value too short: %d bytes", key, len(stored))
+	}
+	return stored[versionLen:], binary.BigEndian.Uint64(stored), nil
+}
+
+func (db *DB) versioned(dbName string) bool {
+	return db.opts.DBs[dbName].Versioned
+}
@@ -0,0 +1,191 @@
+package wrap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_WriteVersioned(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"docs", "plain"}, WithDBOptions("docs", DBOptions{Versioned: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	key := []byte("doc")
+
+	v, err := db.WriteVersioned("docs", key, []byte("one"), 0)
+	if err != nil || v != 1 {
+		t.Fatalf("create: %d %v", v, err)
+	}
+	if v, err = db.WriteVersioned("docs", key, []byte("two"), 1); err != nil || v != 2 {
+		t.Fatalf("update: %d %v", v, err)
+	}
+
+	_, err = db.WriteVersioned("docs", key, []byte("stale"), 1)
+	var mismatch *VersionMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("stale write: %v", err)
+	}
+	if mismatch.Expected != 1 || mismatch.Actual != 2 {
+		t.Errorf("mismatch: %+v", mismatch)
+	}
+	if _, err := db.WriteVersioned("docs", []byte("new"), []byte("x"), 3); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("write missing key with version: %v", err)
+	}
+
+	val, ver, err := db.ReadVersioned("docs", key)
+	if err != nil || string(val) != "two" || ver != 2 {
+		t.Errorf("read versioned: %q %d %v", val, ver, err)
+	}
+	if _, _, err := db.ReadVersioned("docs", []byte("new")); !lmdb.IsNotFound(err) {
+		t.Errorf("read missing: %v", err)
+	}
+
+	// plain reads strip the header and plain writes bump the version
+	if val, err := db.Read("docs", key); err != nil || string(val) != "two" {
+		t.Errorf("read: %q %v", val, err)
+	}
+	if err := db.Write("docs", key, []byte("three")); err != nil {
+		t.Fatal(err)
+	}
+	if val, ver, _ := db.ReadVersioned("docs", key); string(val) != "three" || ver != 3 {
+		t.Errorf("after write: %q %d", val, ver)
+	}
+
+	if _, err := db.WriteVersioned("plain", key, []byte("x"), 0); !errors.Is(err, ErrNotVersioned) {
+		t.Errorf("write plain: %v", err)
+	}
+	if _, _, err := db.ReadVersioned("plain", key); !errors.Is(err, ErrNotVersioned) {
+		t.Errorf("read plain: %v", err)
+	}
+}
+
+func TestDB_Versioned_mixedAPIs(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"docs"}, WithDBOptions("docs", DBOptions{Versioned: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	b, err := db.Bucket("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every write increments the version, whatever the method
+	if err := b.Write([]byte("k"), []byte("hello-world")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.Read("docs", []byte("k")); err != nil || string(v) != "hello-world" {
+		t.Errorf("Read after Bucket.Write: %q, %v", v, err)
+	}
+	if err := db.Write("docs", []byte("k"), []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := b.Read([]byte("k")); err != nil || string(v) != "two" {
+		t.Errorf("Bucket.Read after Write: %q, %v", v, err)
+	}
+	err = db.Upsert("docs", []byte("k"), func(existing []byte) ([]byte, error) {
+		return append(existing, '!'), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := db.CompareAndSwap("docs", []byte("k"), []byte("two!"), []byte("four")); err != nil || !ok {
+		t.Errorf("CompareAndSwap: %v, %v", ok, err)
+	}
+	err = db.UpdateNamed(func(tx *NamedTxn) error {
+		return tx.Put("docs", []byte("k"), []byte("five"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutWithTTL("docs", []byte("k"), []byte("six"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if v, ver, err := db.ReadVersioned("docs", []byte("k")); err != nil || string(v) != "six" || ver != 6 {
+		t.Errorf("ReadVersioned: %q, %d, %v", v, ver, err)
+	}
+
+	if err := db.Append("docs", []byte("m"), []byte("appended")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.BatchAppend("docs", []KV{{Key: []byte("n"), Value: []byte("batched")}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.IntIncrement("docs", []byte("o"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.WriteJSON("docs", []byte("p"), map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]int
+	if err := db.ReadJSONView("docs", []byte("p"), &doc); err != nil || doc["a"] != 1 {
+		t.Errorf("ReadJSONView: %v, %v", doc, err)
+	}
+	if n, err := db.IntGet("docs", []byte("o")); err != nil || n != 3 {
+		t.Errorf("IntGet: %d, %v", n, err)
+	}
+	if _, ver, err := db.ReadVersioned("docs", []byte("m")); err != nil || ver != 1 {
+		t.Errorf("ReadVersioned appended: %d, %v", ver, err)
+	}
+
+	want := map[string]string{"k": "six", "m": "appended", "n": "batched", "o": "\x00\x00\x00\x00\x00\x00\x00\x03", "p": `{"a":1}`}
+	check := func(what string, k, v []byte) {
+		t.Helper()
+		if w := want[string(k)]; string(v) != w {
+			t.Errorf("%s: %q = %q (!= %q)", what, k, v, w)
+		}
+	}
+	kvs, err := db.Scan("docs", nil, ScanOptions{})
+	if err != nil || len(kvs) != len(want) {
+		t.Fatalf("Scan: %q, %v", kvs, err)
+	}
+	for _, kv := range kvs {
+		check("Scan", kv.Key, kv.Value)
+	}
+	err = db.ForEach("docs", nil, ScanOptions{RawRead: true}, func(k, v []byte) error {
+		check("ForEach", k, v)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	it, err := db.NewIterator("docs", IterOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for it.Next() {
+		check("Iterator", it.Key(), it.Value())
+	}
+	if err := it.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := db.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Read("docs", []byte("n")); err != nil {
+		t.Error(err)
+	} else {
+		check("Snapshot.Read", []byte("n"), v)
+	}
+	s.Close()
+	err = db.ViewNamed(func(v *NamedView) error {
+		val, err := v.Get("docs", []byte("m"))
+		if err == nil {
+			check("NamedView.Get", []byte("m"), val)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = New(t.TempDir(), []string{"tags"}, WithDBOptions("tags", DBOptions{Versioned: true, DupSort: true}))
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Versioned with DupSort: %v", err)
+	}
+}
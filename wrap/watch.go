@@ -0,0 +1,130 @@
+package wrap
+
+import (
+	"sync"
+)
+
+// OverflowPolicy selects how a Watch channel handles an event arriving while
+// its buffer is full, see Options.WatchOverflow.
+type OverflowPolicy uint8
+
+const (
+	// DropOldest discards the oldest buffered event to make room, so the
+	// receiver always sees the most recent changes.
+	DropOldest OverflowPolicy = iota
+
+	// MarkMissed discards new events until the receiver catches up and
+	// queues a single event with Op OpMissed in their place, so the receiver
+	// knows to resynchronize, for example by rereading the prefix.
+	MarkMissed
+)
+
+// WithWatchBufferSize sets Options.WatchBufferSize.
+func WithWatchBufferSize(n int) Option {
+	return func(o *Options) { o.WatchBufferSize = n }
+}
+
+// WithWatchOverflow sets Options.WatchOverflow.
+func WithWatchOverflow(p OverflowPolicy) Option {
+	return func(o *Options) { o.WatchOverflow = p }
+}
+
+// watcher is a channel registered by Watch.
+type watcher struct {
+	prefix []byte
+	policy OverflowPolicy
+	size   int // events buffered before overflowing
+
+	mu     sync.Mutex // serializes sends from concurrent writers
+	ch     chan KeyEvent
+	missed bool // an OpMissed marker is queued and no event was sent since
+}
+
+// Watch returns a channel receiving a KeyEvent, including the written value,
+// for every key starting with prefix that is written or deleted in the named
+// database, and a function that stops the watch and closes the channel. The
+// cancel function may be called more than once.
+//
+// Events are sent after their transaction commits, in commit order, by the
+// same methods that notify Subscribe. Changes made through the *lmdb.Txn of
+// Update or UpdateDB are not seen, write with NamedTxn to have them reported.
+// A receiver that falls behind loses events as selected by
+// Options.WatchOverflow, the writers never block on it.
+func (db *DB) Watch(dbName string, prefix []byte) (<-chan KeyEvent, func(), error) {
+	if _, err := db.getDBI(dbName); err != nil {
+		return nil, nil, err
+	}
+	w := &watcher{
+		prefix: copyBytes(prefix),
+		policy: db.opts.WatchOverflow,
+		size:   db.opts.WatchBufferSize,
+	}
+	if w.policy == MarkMissed {
+		// room for the marker behind a full buffer
+		w.ch = make(chan KeyEvent, w.size+1)
+	} else {
+		w.ch = make(chan KeyEvent, w.size)
+	}
+
+	db.subMu.Lock()
+	if db.watchers == nil {
+		db.watchers = make(map[string][]*watcher)
+	}
+	db.watchers[dbName] = append(db.watchers[dbName], w)
+	db.subMu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			db.subMu.Lock()
+			defer db.subMu.Unlock()
+			ws := db.watchers[dbName]
+			for i, x := range ws {
+				if x == w {
+					// copy so a concurrent publish keeps a consistent slice
+					ws = append(append([]*watcher(nil), ws[:i]...), ws[i+1:]...)
+					break
+				}
+			}
+			if len(ws) == 0 {
+				delete(db.watchers, dbName)
+			} else {
+				db.watchers[dbName] = ws
+			}
+			// publish holds subMu while sending, so nothing sends on ch now
+			close(w.ch)
+		})
+	}
+	return w.ch, cancel, nil
+}
+
+// send delivers ev without blocking, applying the overflow policy when the
+// buffer is full. Only senders hold w.mu, the receiver only drains w.ch, so
+// the buffer cannot fill up between checking its length and sending.
+func (w *watcher) send(ev KeyEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch w.policy {
+	case MarkMissed:
+		if len(w.ch) < w.size {
+			w.ch <- ev
+			w.missed = false
+		} else if !w.missed {
+			w.ch <- KeyEvent{Op: OpMissed, DBName: ev.DBName}
+			w.missed = true
+		}
+	default:
+		for {
+			select {
+			case w.ch <- ev:
+				return
+			default:
+			}
+			// discard the oldest event, unless the receiver just took it
+			select {
+			case <-w.ch:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package wrap
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestDB_Watch(t *testing.T) {
+	db := newTestDB(t, "a")
+	events, cancel, err := db.Watch("a", []byte("user:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := db.Watch("nope", nil); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+
+	mustWrite(t, db, "a", "user:1", "other", "user:2")
+	if err := db.Delete("a", []byte("user:1")); err != nil {
+		t.Fatal(err)
+	}
+	db.Update(func(txn *lmdb.Txn) error {
+		dbi, _ := db.DBI("a")
+		return txn.Put(dbi, []byte("user:raw"), nil, 0)
+	})
+	err = db.UpdateNamed(func(tx *NamedTxn) error {
+		return tx.Put("a", []byte("user:3"), []byte("named"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"put user:1 v:user:1", "put user:2 v:user:2", "delete user:1 ", "put user:3 named"}
+	for i, w := range want {
+		if got := recvEvent(t, events); got != w {
+			t.Errorf("event %d: %q (!= %q)", i, got, w)
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event: %v %q", ev.Op, ev.Key)
+	default:
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-events; ok {
+		t.Errorf("channel open after cancel")
+	}
+	mustWrite(t, db, "a", "user:4")
+}
+
+func TestDB_Watch_overflow(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy OverflowPolicy
+		want   []string
+	}{
+		{"dropOldest", DropOldest, []string{"put k3 v:k3", "put k4 v:k4"}},
+		{"markMissed", MarkMissed, []string{"put k0 v:k0", "put k1 v:k1", "missed  "}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, _, err := New(t.TempDir(), []string{"a"}, WithWatchBufferSize(2), WithWatchOverflow(tc.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			events, cancel, err := db.Watch("a", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cancel()
+
+			for i := 0; i < 5; i++ {
+				mustWrite(t, db, "a", fmt.Sprintf("k%d", i))
+			}
+			for i, w := range tc.want {
+				if got := recvEvent(t, events); got != w {
+					t.Errorf("event %d: %q (!= %q)", i, got, w)
+				}
+			}
+
+			// delivery resumes once the receiver caught up
+			mustWrite(t, db, "a", "k5")
+			if got := recvEvent(t, events); got != "put k5 v:k5" {
+				t.Errorf("after catching up: %q", got)
+			}
+		})
+	}
+}
+
+func recvEvent(t *testing.T, events <-chan KeyEvent) string {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return fmt.Sprintf("%v %s %s", ev.Op, ev.Key, ev.Value)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not delivered")
+	}
+	return ""
+}
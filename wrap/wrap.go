@@ -2,11 +2,15 @@
 package wrap
 
 import (
+	"context"
 	"errors"
+	"hash/fnv"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
-	"sync/atomic"
+	"time"
 
 	"github.com/Data-Corruption/lmdb-go/lmdb"
 )
@@ -16,35 +20,96 @@ const (
 	MapSize     = 10 * 1 << 30 // 10 GB
 )
 
+// Database names starting with ReservedPrefix are reserved for the internal
+// databases backing features like sequences. They count towards MaxNamedDBs.
+const ReservedPrefix = "__"
+
+// internal database names
+const (
+	sequencesDbName = "__sequences__"
+	expiryDbName    = "__expiry__"
+	changelogDbName = "__changelog__"
+	metaDbName      = "__meta__"
+	mtimeDbName     = "__mtime__"
+)
+
+// permissions of the directories and files created for environments
+const (
+	dirMode  = 0755
+	fileMode = 0644
+)
+
 var (
 	ErrDuplicateDbName = errors.New("duplicate database name")
+	ErrReservedDbName  = errors.New("reserved database name")
 	ErrDbNameNotFound  = errors.New("database name not found")
 	ErrDBClosed        = errors.New("database is closed")
 	ErrEmptyKey        = errors.New("empty key")
+	ErrInvalidOption   = errors.New("invalid option")
+	ErrHandlesOpen     = errors.New("snapshots or iterators still open")
+	ErrCompacting      = errors.New("database is being compacted")
+	ErrDBsFull         = errors.New("too many databases")
 )
 
+// closePollInterval is how often Close checks for outstanding operations.
+const closePollInterval = 5 * time.Millisecond
+
 // updateOp is a struct used to pass LMDB write operations to an OS thread-locked goroutine.
 //
 // see https://pkg.go.dev/github.com/bmatsuo/lmdb-go/lmdb?utm_source=godoc#hdr-Caveats
 type updateOp struct {
-	op  lmdb.TxnOp
-	res chan<- error
+	op      lmdb.TxnOp // nil for a barrier that only reports it was reached, see Compact
+	res     chan<- error
+	started chan struct{} // closed when the writer dequeues op, nil without a WriteTimeout
+	events  *[]KeyEvent   // published if op commits, may be nil
+	dbName  string        // the database op is limited to, empty for any
+	touched *[]string     // databases written through a NamedTxn, may be nil
+	commit  func()        // called by the writer once op commits, may be nil
+	dropped []string      // databases unregistered by op, registered again if it fails, see drop
 }
 
 // DB represents a simple LMDB database wrapper.
 type DB struct {
-	env       *lmdb.Env
-	dbs       map[string]lmdb.DBI // handle is just a uint, safe to cache for the lifetime of the DB
-	uOps      chan *updateOp
-	wg        sync.WaitGroup // for closing the update goroutine cleanly
+	opts      Options
+	path      string
+	env       *lmdb.Env           // replaced by Compact while no operation is in progress
+	dbsMu     sync.RWMutex        // guards dbs and probe, see RenameDB, DropDB, and AddDB
+	dbs       map[string]lmdb.DBI // handle is just a uint, safe to cache until the database is dropped, renamed, or compacted
+	probe     string              // first registered database name, used by HealthCheck
+	seqs      lmdb.DBI            // internal database holding sequences
+	expiry    lmdb.DBI            // internal database indexing keys written with a TTL
+	changelog lmdb.DBI            // internal database recording changes, see DBOptions.Changelog
+	meta      lmdb.DBI            // internal database holding the schema version
+	mtimes    lmdb.DBI            // internal database holding modification times, see DBOptions.TrackModTime
+	uOps      []chan *updateOp    // one queue per write worker
+	wg        sync.WaitGroup      // for closing the update goroutine cleanly
 	closeOnce sync.Once
-	closed    uint32
+	compactMu sync.Mutex // serializes Compact calls
+
+	mu         sync.Mutex // guards the fields below
+	closed     bool
+	active     int        // in-flight View and Update calls
+	writes     int        // in-flight Update calls, also counted in active
+	handles    int        // open snapshots and iterators
+	compacting bool       // Compact is copying the environment, writes are paused
+	swapping   bool       // Compact is replacing the data file, everything is paused
+	resumed    *sync.Cond // broadcast when compacting or swapping is cleared, or on Close
+
+	subMu    sync.RWMutex // guards subs and watchers
+	subs     map[string][]chan<- KeyEvent
+	watchers map[string][]*watcher
+	dropped  uint64 // events dropped on full subscriber channels, accessed atomically
 }
 
 // New creates (or opens) an LMDB environment at the specified directory path and initializes the given databases.
 // If the directory does not exist, it will be created. Remember to call Close() on the returned DB
 // to cleanly shut down the environment. Returns the DB pointer, the number of stale readers cleared, and any error.
-func New(dirPath string, dbNames []string) (*DB, int, error) {
+// The optional opts adjust the default configuration, see Options.
+func New(dirPath string, dbNames []string, opts ...Option) (*DB, int, error) {
+	o, err := buildOptions(opts)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	// Ensure the database names are unique
 	seen := make(map[string]struct{})
@@ -52,29 +117,34 @@ func New(dirPath string, dbNames []string) (*DB, int, error) {
 		if _, ok := seen[n]; ok {
 			return nil, 0, ErrDuplicateDbName
 		}
+		if strings.HasPrefix(n, ReservedPrefix) {
+			return nil, 0, ErrReservedDbName
+		}
 		seen[n] = struct{}{}
 	}
-
-	// Ensure the directory exists
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		return nil, 0, err
+	for n := range o.DBs {
+		if _, ok := seen[n]; !ok {
+			return nil, 0, ErrDbNameNotFound
+		}
 	}
 
-	// Create DB struct and open the environment
-	newDB := &DB{dbs: make(map[string]lmdb.DBI), uOps: make(chan *updateOp, 1000)}
-
-	var err error
-	newDB.env, err = lmdb.NewEnv()
-	if err != nil {
+	// Ensure the directory exists, without the copy of an interrupted Compact
+	if err := os.MkdirAll(dirPath, dirMode); err != nil {
 		return nil, 0, err
 	}
-	if err = newDB.env.SetMaxDBs(MaxNamedDBs); err != nil {
+	if err := os.RemoveAll(filepath.Join(dirPath, compactDirName)); err != nil {
 		return nil, 0, err
 	}
-	if err = newDB.env.SetMapSize(MapSize); err != nil {
-		return nil, 0, err
+
+	// Create DB struct and open the environment
+	newDB := &DB{opts: o, path: dirPath, dbs: make(map[string]lmdb.DBI), uOps: make([]chan *updateOp, o.NumWriteWorkers)}
+	newDB.resumed = sync.NewCond(&newDB.mu)
+	for i := range newDB.uOps {
+		newDB.uOps[i] = make(chan *updateOp, o.UpdateBufferSize)
 	}
-	if err = newDB.env.Open(dirPath, 0, 0644); err != nil {
+
+	newDB.env, err = openEnv(dirPath)
+	if err != nil {
 		return nil, 0, err
 	}
 
@@ -88,41 +158,113 @@ func New(dirPath string, dbNames []string) (*DB, int, error) {
 	// Open each database handle
 	for _, name := range dbNames {
 		err = newDB.env.Update(func(txn *lmdb.Txn) (err error) {
-			newDB.dbs[name], err = txn.CreateDBI(name)
-			return err
+			newDB.dbs[name], err = txn.OpenDBI(name, lmdb.Create|newDB.opts.DBs[name].flags())
+			return newDB.dbsFull(err)
 		})
 		if err != nil {
 			newDB.env.Close()
 			return nil, staleReaders, err
 		}
 	}
+	if len(dbNames) > 0 {
+		newDB.probe = dbNames[0]
+	}
 
-	// Start issuing update operations in an OS thread-locked goroutine
-	newDB.wg.Add(1)
-	go func() {
-		runtime.LockOSThread()
-		defer func() {
-			runtime.UnlockOSThread()
-			newDB.wg.Done()
-		}()
-		for op := range newDB.uOps {
-			op.res <- newDB.env.UpdateLocked(op.op)
-		}
-	}()
+	// Open the internal databases
+	err = newDB.dbsFull(newDB.env.Update(newDB.openInternal))
+	if err != nil {
+		newDB.env.Close()
+		return nil, staleReaders, err
+	}
+
+	// Start issuing update operations in OS thread-locked goroutines
+	newDB.wg.Add(len(newDB.uOps))
+	for _, ops := range newDB.uOps {
+		go newDB.writer(ops)
+	}
 
 	return newDB, staleReaders, nil
 }
 
-// Read retrieves a value from the database.
-func (db *DB) Read(dbName string, key []byte) ([]byte, error) {
-	dbi, err := db.validateArgs(dbName, key)
+// openEnv opens the environment in dirPath with the settings used by New.
+func openEnv(dirPath string) (*lmdb.Env, error) {
+	env, err := lmdb.NewEnv()
 	if err != nil {
 		return nil, err
 	}
-	// read the value
-	var val []byte
-	err = db.env.View(func(txn *lmdb.Txn) (err error) {
-		val, err = txn.Get(dbi, key)
+	if err = env.SetMaxDBs(MaxNamedDBs); err == nil {
+		if err = env.SetMapSize(MapSize); err == nil {
+			err = env.Open(dirPath, 0, fileMode)
+		}
+	}
+	if err != nil {
+		env.Close()
+		return nil, err
+	}
+	return env, nil
+}
+
+// openInternal opens the internal databases, creating them if needed.
+func (db *DB) openInternal(txn *lmdb.Txn) (err error) {
+	if db.seqs, err = txn.CreateDBI(sequencesDbName); err != nil {
+		return err
+	}
+	if db.expiry, err = txn.CreateDBI(expiryDbName); err != nil {
+		return err
+	}
+	if db.changelog, err = txn.CreateDBI(changelogDbName); err != nil {
+		return err
+	}
+	if db.meta, err = txn.CreateDBI(metaDbName); err != nil {
+		return err
+	}
+	db.mtimes, err = txn.CreateDBI(mtimeDbName)
+	return err
+}
+
+// writer runs the update operations received on ops until ops is closed.
+func (db *DB) writer(ops <-chan *updateOp) {
+	runtime.LockOSThread()
+	defer func() {
+		runtime.UnlockOSThread()
+		db.wg.Done()
+	}()
+	for op := range ops {
+		if op.op == nil {
+			op.res <- nil
+			continue
+		}
+		if op.started != nil {
+			close(op.started)
+		}
+		start := time.Now()
+		err := db.env.UpdateLocked(db.withBeforeCommit(op))
+		if err == nil && op.commit != nil {
+			op.commit()
+		}
+		if err != nil && len(op.dropped) > 0 {
+			db.reregister(op.dropped)
+		}
+		if err == nil && op.events != nil {
+			db.publish(*op.events)
+		}
+		if err == nil {
+			db.afterCommit(op, time.Since(start))
+		}
+		op.res <- err
+	}
+}
+
+// Read retrieves a value from the database.
+func (db *DB) Read(dbName string, key []byte) (val []byte, err error) {
+	err = db.observeRead(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		if val, err = db.get(dbName, dbi, key); err == nil {
+			val, err = db.decodeValue(dbName, key, val)
+		}
 		return err
 	})
 	return val, err
@@ -130,34 +272,84 @@ func (db *DB) Read(dbName string, key []byte) ([]byte, error) {
 
 // Write inserts a key/value pair into the database.
 func (db *DB) Write(dbName string, key, value []byte) error {
-	dbi, err := db.validateArgs(dbName, key)
-	if err != nil {
-		return err
-	}
-	// write the key/value pair
-	return db.Update(func(txn *lmdb.Txn) error {
-		return txn.Put(dbi, key, value, 0)
+	return db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		return db.put(dbName, dbi, key, value)
 	})
 }
 
 // Delete removes a key/value pair from the database.
 func (db *DB) Delete(dbName string, key []byte) error {
-	dbi, err := db.validateArgs(dbName, key)
-	if err != nil {
+	return db.observeWrite(dbName, func() error {
+		dbi, err := db.validateArgs(dbName, key)
+		if err != nil {
+			return err
+		}
+		return db.del(dbName, dbi, key)
+	})
+}
+
+// get reads the value of key in dbi, the handle of dbName.
+func (db *DB) get(dbName string, dbi lmdb.DBI, key []byte) (val []byte, err error) {
+	err = db.view(func(txn *lmdb.Txn) (err error) {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		val, err = txn.Get(dbi, key)
 		return err
-	}
-	// delete the key/value pair
-	return db.Update(func(txn *lmdb.Txn) error {
-		return txn.Del(dbi, key, nil)
 	})
+	return val, err
 }
 
-// Update runs an LMDB transaction.
+// put writes a key/value pair into dbi, the handle of dbName.
+func (db *DB) put(dbName string, dbi lmdb.DBI, key, value []byte) error {
+	return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		stored, err := db.encodeValue(txn, dbi, dbName, key, value)
+		if err != nil {
+			return err
+		}
+		if err := txn.Put(dbi, key, stored, 0); err != nil {
+			return err
+		}
+		return db.recordWrite(txn, OpPut, dbName, key, stored)
+	}, db.event(OpPut, dbName, key, value))
+}
+
+// del deletes key from dbi, the handle of dbName.
+func (db *DB) del(dbName string, dbi lmdb.DBI, key []byte) error {
+	return db.updateEvents(dbName, func(txn *lmdb.Txn) error {
+		if err := db.refreshDBI(dbName, &dbi); err != nil {
+			return err
+		}
+		if err := txn.Del(dbi, key, nil); err != nil {
+			return err
+		}
+		return db.recordWrite(txn, OpDelete, dbName, key, nil)
+	}, db.event(OpDelete, dbName, key, nil))
+}
+
+// recordWrite updates the changelog and the modification times of dbName for
+// a write of key made in txn. Every helper writing user data calls it.
+func (db *DB) recordWrite(txn *lmdb.Txn, op Op, dbName string, key, val []byte) error {
+	if err := db.logChange(txn, op, dbName, key, val); err != nil {
+		return err
+	}
+	return db.trackModTime(txn, op, dbName, key, val)
+}
+
+// Update runs an LMDB transaction. UpdateNamed is usually more convenient for
+// transactions addressing databases by name.
 //
 // Usage:
 //
 //	err := db.Update(func(txn *lmdb.Txn) error {
-//		dbi := db.GetDBis()["users"]
+//		dbi, _ := db.DBI("users")
 //		data, err := txn.Get(dbi, []byte("user:123"))
 //		if err != nil {
 //			return err
@@ -168,20 +360,69 @@ func (db *DB) Delete(dbName string, key []byte) error {
 //		return txn.Put(dbi, []byte("user:123"), update(data), 0)
 //	})
 func (db *DB) Update(op lmdb.TxnOp) error {
-	if atomic.LoadUint32(&db.closed) != 0 {
-		return ErrDBClosed
+	return db.observeWrite("", func() error { return db.update(op) })
+}
+
+// UpdateDB runs an LMDB transaction like Update on the write worker assigned to
+// the named database, see Options.NumWriteWorkers. The transaction should
+// only modify the named database.
+func (db *DB) UpdateDB(dbName string, op lmdb.TxnOp) error {
+	return db.observeWrite(dbName, func() error {
+		if _, err := db.getDBI(dbName); err != nil {
+			return err
+		}
+		return db.updateEvents(dbName, op, nil)
+	})
+}
+
+// update queues op for the first writer goroutine and waits for its result.
+func (db *DB) update(op lmdb.TxnOp) error {
+	return db.updateEvents("", op, nil)
+}
+
+// updateEvents is like update but queues op for the writer assigned to
+// dbName, or the first writer if dbName is empty, and publishes events, which
+// op may append to while it runs, once the transaction commits.
+func (db *DB) updateEvents(dbName string, op lmdb.TxnOp, events *[]KeyEvent) error {
+	return db.submit(&updateOp{op: op, events: events, dbName: dbName})
+}
+
+// submit queues u for the writer assigned to u.dbName and waits for its
+// result.
+func (db *DB) submit(u *updateOp) error {
+	if err := db.acquireWrite(); err != nil {
+		return err
+	}
+	defer db.releaseWrite()
+	// buffered so the writer never blocks on a caller that timed out
+	res := make(chan error, 1)
+	u.res = res
+	ops := db.uOps[db.shard(u.dbName)]
+	timeout := db.opts.WriteTimeout
+	if timeout <= 0 {
+		ops <- u
+		return <-res
+	}
+	u.started = make(chan struct{})
+	ops <- u
+	<-u.started
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-res:
+		return err
+	case <-timer.C:
+		return context.DeadlineExceeded
 	}
-	res := make(chan error)
-	db.uOps <- &updateOp{op, res}
-	return <-res
 }
 
-// View runs a read-only LMDB transaction.
+// View runs a read-only LMDB transaction. ViewNamed is usually more convenient
+// for transactions addressing databases by name.
 //
 // Usage:
 //
 //	err := db.View(func(txn *lmdb.Txn) error {
-//		dbi := db.GetDBis()["users"]
+//		dbi, _ := db.DBI("users")
 //		data, err := txn.Get(dbi, []byte("user:123"))
 //		if err != nil {
 //			return err
@@ -190,14 +431,29 @@ func (db *DB) Update(op lmdb.TxnOp) error {
 //		return nil
 //	})
 func (db *DB) View(op lmdb.TxnOp) error {
-	if atomic.LoadUint32(&db.closed) != 0 {
-		return ErrDBClosed
+	return db.observeRead("", func() error { return db.view(op) })
+}
+
+func (db *DB) view(op lmdb.TxnOp) error {
+	if err := db.acquire(&db.active); err != nil {
+		return err
 	}
+	defer db.release(&db.active)
 	return db.env.View(op)
 }
 
-// GetDBis returns a copy of database names to DBI handle mappings.
+// DBI returns the handle of the named database and whether the name is known.
+// Unlike GetDBis it does not allocate, so it is suited to use inside
+// transactions.
+func (db *DB) DBI(dbName string) (lmdb.DBI, bool) {
+	return db.lookup(dbName)
+}
+
+// GetDBis returns a copy of database names to DBI handle mappings, for
+// introspection of every database at once.
 func (db *DB) GetDBis() map[string]lmdb.DBI {
+	db.dbsMu.RLock()
+	defer db.dbsMu.RUnlock()
 	dbis := make(map[string]lmdb.DBI, len(db.dbs))
 	for k, v := range db.dbs {
 		dbis[k] = v
@@ -205,14 +461,114 @@ func (db *DB) GetDBis() map[string]lmdb.DBI {
 	return dbis
 }
 
-// Close cleanly shuts down the LMDB environment.
-func (db *DB) Close() {
+// Close cleanly shuts down the LMDB environment. Once Close is called new
+// operations fail with ErrDBClosed, while operations already in progress are
+// allowed to finish.
+//
+// Snapshots and iterators keep the environment in use until they are closed.
+// Close waits up to Options.CloseTimeout for them and then gives up with
+// ErrHandlesOpen, leaving the environment open so the outstanding handles
+// remain valid. Close may be called again once they are closed. A Compact
+// replacing the data file is waited for.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	db.closed = true
+	db.resumed.Broadcast()
+	db.mu.Unlock()
+
+	deadline := time.Now().Add(db.opts.CloseTimeout)
+	for {
+		db.mu.Lock()
+		active, handles, swapping := db.active, db.handles, db.swapping
+		db.mu.Unlock()
+		if swapping {
+			time.Sleep(closePollInterval)
+			continue
+		}
+		if active == 0 && handles == 0 {
+			break
+		}
+		if active == 0 && !time.Now().Before(deadline) {
+			return ErrHandlesOpen
+		}
+		time.Sleep(closePollInterval)
+	}
+
 	db.closeOnce.Do(func() {
-		atomic.StoreUint32(&db.closed, 1)
-		close(db.uOps)
+		for _, ops := range db.uOps {
+			close(ops)
+		}
 		db.wg.Wait()
 		db.env.Close()
 	})
+	return nil
+}
+
+// shard returns the index of the write worker assigned to dbName, with the
+// empty name always assigned to the first worker.
+func (db *DB) shard(dbName string) int {
+	if dbName == "" || len(db.uOps) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(dbName))
+	return int(h.Sum32() % uint32(len(db.uOps)))
+}
+
+// isClosed reports whether Close has been called.
+func (db *DB) isClosed() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.closed
+}
+
+// acquire increments counter, one of db.active or db.handles, unless the DB is
+// closed. Each successful acquire must be paired with a release. While Compact
+// replaces the data file acquire fails with ErrCompacting, or waits for it to
+// finish if Options.CompactWait is set.
+func (db *DB) acquire(counter *int) error {
+	return db.acquireGated(counter, false)
+}
+
+func (db *DB) release(counter *int) {
+	db.mu.Lock()
+	*counter--
+	db.mu.Unlock()
+}
+
+// acquireWrite is like acquire for db.active but also counts a write, which
+// is paused for the whole Compact. It must be paired with releaseWrite.
+func (db *DB) acquireWrite() error {
+	return db.acquireGated(&db.active, true)
+}
+
+func (db *DB) releaseWrite() {
+	db.mu.Lock()
+	db.active--
+	db.writes--
+	db.mu.Unlock()
+}
+
+func (db *DB) acquireGated(counter *int, write bool) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for {
+		if db.closed {
+			return ErrDBClosed
+		}
+		if !db.swapping && !(write && db.compacting) {
+			break
+		}
+		if !db.opts.CompactWait {
+			return ErrCompacting
+		}
+		db.resumed.Wait()
+	}
+	*counter++
+	if write {
+		db.writes++
+	}
+	return nil
 }
 
 // validateArgs is a helper for Read, Write, and Delete argument parsing.
@@ -223,9 +579,37 @@ func (db *DB) validateArgs(dbName string, key []byte) (lmdb.DBI, error) {
 	if len(key) == 0 {
 		return 0, ErrEmptyKey
 	}
-	dbi, ok := db.dbs[dbName]
+	return db.getDBI(dbName)
+}
+
+// getDBI resolves a database name to its handle.
+func (db *DB) getDBI(dbName string) (lmdb.DBI, error) {
+	dbi, ok := db.lookup(dbName)
 	if !ok {
 		return 0, ErrDbNameNotFound
 	}
 	return dbi, nil
 }
+
+// refreshDBI sets *dbi to the current handle of dbName, or returns
+// ErrDbNameNotFound if the name is no longer registered. The methods looking
+// up a handle before their transaction begins call it in the transaction:
+// while they waited DropDB or RenameDB may have freed the handle for LMDB to
+// reuse, see DropDB, or Compact replaced it. From then on LMDB itself reports
+// a reuse.
+func (db *DB) refreshDBI(dbName string, dbi *lmdb.DBI) error {
+	cur, ok := db.lookup(dbName)
+	if !ok {
+		return ErrDbNameNotFound
+	}
+	*dbi = cur
+	return nil
+}
+
+// lookup is the single place mapping database names to handles.
+func (db *DB) lookup(dbName string) (lmdb.DBI, bool) {
+	db.dbsMu.RLock()
+	dbi, ok := db.dbs[dbName]
+	db.dbsMu.RUnlock()
+	return dbi, ok
+}
@@ -0,0 +1,290 @@
+package wrap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// newTestDB opens a DB in a temporary directory that is closed and removed
+// when the test completes.
+func newTestDB(t testing.TB, dbNames ...string) *DB {
+	t.Helper()
+	db, _, err := New(t.TempDir(), dbNames)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("close: %v", err)
+		}
+	})
+	return db
+}
+
+// mustWrite writes each key with value "v:"+key into dbName.
+func mustWrite(t testing.TB, db *DB, dbName string, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		if err := db.Write(dbName, []byte(k), []byte("v:"+k)); err != nil {
+			t.Fatalf("write %q: %v", k, err)
+		}
+	}
+}
+
+func TestDB_ReadWriteDelete(t *testing.T) {
+	db := newTestDB(t, "a")
+
+	if err := db.Write("a", []byte("k"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	val, err := db.Read("a", []byte("k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(val, []byte("v")) {
+		t.Errorf("read: %q (!= %q)", val, "v")
+	}
+	if err := db.Delete("a", []byte("k")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Read("a", []byte("k")); !lmdb.IsNotFound(err) {
+		t.Errorf("read after delete: %v", err)
+	}
+}
+
+func TestDB_validateArgs(t *testing.T) {
+	db := newTestDB(t, "a")
+
+	if _, err := db.Read("", []byte("k")); err != ErrDbNameNotFound {
+		t.Errorf("empty name: %v", err)
+	}
+	if _, err := db.Read("b", []byte("k")); err != ErrDbNameNotFound {
+		t.Errorf("unknown name: %v", err)
+	}
+	if _, err := db.Read("a", nil); err != ErrEmptyKey {
+		t.Errorf("empty key: %v", err)
+	}
+}
+
+func TestDB_closed(t *testing.T) {
+	db := newTestDB(t, "a")
+	db.Close()
+	db.Close()
+
+	if err := db.Write("a", []byte("k"), []byte("v")); err != ErrDBClosed {
+		t.Errorf("write: %v", err)
+	}
+	if _, err := db.Read("a", []byte("k")); err != ErrDBClosed {
+		t.Errorf("read: %v", err)
+	}
+}
+
+func TestNew_updateBufferSize(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"a"}, WithUpdateBufferSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if c := db.QueueCapacity(); c != 1 {
+		t.Errorf("buffer size: %d (!= 1)", c)
+	}
+
+	const writers = 16
+	done := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			done <- db.Write("a", []byte(fmt.Sprint(i)), []byte("v"))
+		}(i)
+	}
+	for i := 0; i < writers; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("concurrent writes deadlocked")
+		}
+	}
+}
+
+func TestNew_invalidOption(t *testing.T) {
+	_, _, err := New(t.TempDir(), []string{"a"}, WithUpdateBufferSize(-1))
+	if err != ErrInvalidOption {
+		t.Errorf("negative buffer size: %v", err)
+	}
+
+	db := newTestDB(t, "a")
+	if c := db.QueueCapacity(); c != DefaultUpdateBufferSize {
+		t.Errorf("default buffer size: %d", c)
+	}
+}
+
+func TestNew_dbFlags(t *testing.T) {
+	dir := t.TempDir()
+	db, _, err := New(dir, []string{"tags", "plain"}, WithDBOptions("tags", DBOptions{Flags: lmdb.DupSort}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"tags", "plain"} {
+		dbi, _ := db.DBI(name)
+		var flags uint
+		err = db.View(func(txn *lmdb.Txn) (err error) {
+			flags, err = txn.Flags(dbi)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := name == "tags"; (flags&lmdb.DupSort != 0) != want {
+			t.Errorf("%s: flags %#x", name, flags)
+		}
+	}
+	if err := db.Write("plain", []byte("k"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// flags of an existing database cannot be changed
+	db, _, err = New(dir, []string{"plain"}, WithDBOptions("plain", DBOptions{Flags: lmdb.DupSort}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dbi, _ := db.DBI("plain")
+	err = db.View(func(txn *lmdb.Txn) error {
+		flags, err := txn.Flags(dbi)
+		if err == nil && flags&lmdb.DupSort != 0 {
+			t.Errorf("reopened flags: %#x", flags)
+		}
+		return err
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDB_writeTimeout(t *testing.T) {
+	db, _, err := New(t.TempDir(), []string{"a"}, WithWriteTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	release := make(chan struct{})
+	slow := make(chan error, 1)
+	go func() {
+		slow <- db.Update(func(txn *lmdb.Txn) error {
+			<-release
+			return txn.Put(db.GetDBis()["a"], []byte("slow"), []byte("v"), 0)
+		})
+	}()
+	if err := <-slow; err != context.DeadlineExceeded {
+		t.Fatalf("slow update: %v", err)
+	}
+
+	// time spent queued behind the slow op does not count against the
+	// timeout of the ops after it
+	queued := make(chan error, 1)
+	go func() { queued <- db.Write("a", []byte("fast"), []byte("v")) }()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if err := <-queued; err != nil {
+		t.Fatalf("queued write: %v", err)
+	}
+
+	// the timed out transaction still committed
+	if _, err := db.Read("a", []byte("slow")); err != nil {
+		t.Errorf("slow write: %v", err)
+	}
+}
+
+func TestDB_UpdateDB(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e"}
+	db, _, err := New(t.TempDir(), names, WithNumWriteWorkers(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if c := db.QueueCapacity(); c != 3*DefaultUpdateBufferSize {
+		t.Errorf("capacity: %d", c)
+	}
+
+	done := make(chan error, len(names)*10)
+	for _, name := range names {
+		for i := 0; i < 10; i++ {
+			go func(name string, i int) {
+				dbi := db.GetDBis()[name]
+				done <- db.UpdateDB(name, func(txn *lmdb.Txn) error {
+					return txn.Put(dbi, []byte(fmt.Sprint(i)), nil, 0)
+				})
+			}(name, i)
+		}
+	}
+	for i := 0; i < cap(done); i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range names {
+		if n, _ := db.Count(name); n != 10 {
+			t.Errorf("%s: %d entries", name, n)
+		}
+	}
+	if err := db.UpdateDB("nope", func(*lmdb.Txn) error { return nil }); err != ErrDbNameNotFound {
+		t.Errorf("unknown db: %v", err)
+	}
+}
+
+// benchmarkWriteWorkers writes to several independent databases in parallel.
+// LMDB serializes write transactions, so extra workers are not expected to
+// increase throughput, only to isolate the queues of different databases.
+func benchmarkWriteWorkers(b *testing.B, workers int) {
+	names := []string{"a", "b", "c", "d"}
+	db, _, err := New(b.TempDir(), names, WithNumWriteWorkers(workers))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	var n uint32
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		name := names[int(atomic.AddUint32(&n, 1))%len(names)]
+		for i := 0; pb.Next(); i++ {
+			if err := db.Write(name, []byte(fmt.Sprint(i)), nil); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkDB_Write_workers1(b *testing.B) { benchmarkWriteWorkers(b, 1) }
+func BenchmarkDB_Write_workers4(b *testing.B) { benchmarkWriteWorkers(b, 4) }
+
+func TestDB_DBI(t *testing.T) {
+	db := newTestDB(t, "a", "b")
+	for name, want := range db.GetDBis() {
+		dbi, ok := db.DBI(name)
+		if !ok || dbi != want {
+			t.Errorf("%s: %d %v (!= %d)", name, dbi, ok, want)
+		}
+	}
+	if _, ok := db.DBI("nope"); ok {
+		t.Errorf("unknown name found")
+	}
+	if _, ok := db.DBI(sequencesDbName); ok {
+		t.Errorf("internal database exposed")
+	}
+	if n := testing.AllocsPerRun(100, func() { db.DBI("a") }); n != 0 {
+		t.Errorf("allocs: %v", n)
+	}
+}